@@ -8,12 +8,14 @@ import (
 	"os"
 	"os/signal"
 	"readmeow/internal/config"
+	"readmeow/internal/delivery/collab"
 	"readmeow/internal/delivery/handlers"
 	"readmeow/internal/delivery/oauth"
 	"readmeow/internal/delivery/routes"
 	"readmeow/internal/delivery/server"
 	"readmeow/internal/domain/repositories"
 	"readmeow/internal/domain/services"
+	"readmeow/internal/domain/services/utils"
 	"readmeow/internal/email"
 	"readmeow/internal/scheduler"
 	"readmeow/pkg/cache"
@@ -58,15 +60,6 @@ func Run() {
 	prometheus := monitoring.NewPrometheusSetup()
 	log.Info("prometheus setuped")
 
-	server := server.NewServer(cfg.Server, cfg.Auth, cfg.App, prometheus)
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.CloseTimeout)
-		defer cancel()
-		server.MustClose(ctx)
-		log.Info("server closed")
-	}()
-	log.Info("server created")
-
 	smtpAuth := smtp.PlainAuth("", cfg.Email.Address, cfg.Email.Password, cfg.Email.SmtpAddress)
 
 	cloudStorage := cloudstorage.MustConnect(cfg.CloudStorage)
@@ -77,23 +70,67 @@ func Run() {
 	readmeRepo := repositories.NewReadmeStorage(storage)
 	templateRepo := repositories.NewTemplateRepo(storage, cache, search)
 	verificationRepo := repositories.NewVerificationRepo(storage)
+	sessionRepo := repositories.NewSessionRepo(storage, cache)
+	passwordResetRepo := repositories.NewPasswordResetRepo(storage)
+	magicLinkRepo := repositories.NewMagicLinkRepo(storage)
+	emailChangeRepo := repositories.NewEmailChangeRepo(storage)
+	twoFactorRepo := repositories.NewTwoFactorRepo(storage, cache)
+	accessTokenRepo := repositories.NewAccessTokenRepo(storage)
+	identityRepo := repositories.NewIdentityRepo(storage)
+	erasureRepo := repositories.NewErasureRepo(storage)
+	followRepo := repositories.NewFollowRepo(storage)
+	handleHistoryRepo := repositories.NewHandleHistoryRepo(storage)
+	loginAttemptRepo := repositories.NewLoginAttemptRepo(cache)
+	loginEventRepo := repositories.NewLoginEventRepo(storage)
+	notificationRepo := repositories.NewNotificationRepo(storage, cache)
+	organizationRepo := repositories.NewOrganizationRepo(storage)
+	readmeCollaboratorRepo := repositories.NewReadmeCollaboratorRepo(storage)
+	commentRepo := repositories.NewCommentRepo(storage)
+	templateRatingRepo := repositories.NewTemplateRatingRepo(storage)
+	collectionRepo := repositories.NewCollectionRepo(storage, search)
+	reactionRepo := repositories.NewReactionRepo(storage, cache)
 	transactor := stor.NewTransactor(storage)
 	emailSendler := email.NewEmailSender(smtpAuth, cfg.Email)
 	oauthConf := oauth.NewOAuthConfig(cfg.OAuth)
+	keySet := utils.MustNewKeySet(cfg.Auth)
+	oidcRegistry := oauth.MustNewRegistry(cfg.OAuth, cache, &http.Client{Timeout: cfg.Server.RequestTimeout})
 
-	authServ := services.NewAuthServ(userRepo, verificationRepo, cloudStorage, transactor, emailSendler, log, cfg.Auth)
-	readmeServ := services.NewReadmeServ(readmeRepo, userRepo, templateRepo, widgetRepo, transactor, cloudStorage, log)
+	authServ := services.NewAuthServ(userRepo, verificationRepo, sessionRepo, passwordResetRepo, magicLinkRepo, emailChangeRepo, twoFactorRepo, accessTokenRepo, identityRepo, loginAttemptRepo, loginEventRepo, erasureRepo, cloudStorage, transactor, emailSendler, prometheus, log, cfg.Auth, keySet)
+	notificationServ := services.NewNotificationServ(notificationRepo, log)
+	readmeServ := services.NewReadmeServ(readmeRepo, userRepo, templateRepo, widgetRepo, organizationRepo, readmeCollaboratorRepo, notificationServ, transactor, cloudStorage, log)
 	widgetServ := services.NewWidgetServ(widgetRepo, userRepo, transactor, log)
-	templateServ := services.NewTemplateServ(templateRepo, readmeRepo, userRepo, widgetRepo, transactor, cloudStorage, log)
-	userServ := services.NewUserServ(userRepo, templateRepo, cloudStorage, transactor, log)
+	templateServ := services.NewTemplateServ(templateRepo, templateRatingRepo, readmeRepo, userRepo, widgetRepo, organizationRepo, commentRepo, notificationServ, transactor, cloudStorage, log)
+	commentServ := services.NewCommentServ(commentRepo, readmeRepo, templateRepo, userRepo, organizationRepo, readmeCollaboratorRepo, notificationServ, transactor, log)
+	collectionServ := services.NewCollectionServ(collectionRepo, templateRepo, widgetRepo, organizationRepo, notificationServ, transactor, log)
+	reactionServ := services.NewReactionServ(reactionRepo, templateRepo, widgetRepo, organizationRepo, notificationServ, transactor, log)
+	organizationServ := services.NewOrganizationServ(organizationRepo, userRepo, templateRepo, readmeRepo, transactor, log)
+	userServ := services.NewUserServ(userRepo, templateRepo, readmeRepo, widgetRepo, erasureRepo, followRepo, handleHistoryRepo, collectionRepo, accessTokenRepo, authServ, notificationServ, cloudStorage, transactor, emailSendler, log)
+
+	server := server.NewServer(cfg.Server, cfg.Auth, cfg.OAuth, cfg.App, cfg.Notifications, prometheus, authServ, keySet)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.CloseTimeout)
+		defer cancel()
+		server.MustClose(ctx)
+		log.Info("server closed")
+	}()
+	log.Info("server created")
 
-	authHandl := handlers.NewAuthHandle(authServ, userServ, oauthConf, validator)
+	authHandl := handlers.NewAuthHandle(authServ, userServ, oauthConf, oidcRegistry, validator)
 	readmeHandl := handlers.NewReadmeHandl(readmeServ, authServ, validator)
 	widgetHandl := handlers.NewWidgetHandl(widgetServ, authServ, validator)
 	templateHandl := handlers.NewTemplateHandl(templateServ, authServ, validator)
-	userHandl := handlers.NewUserHandl(userServ, authServ, validator)
-
-	sheduler := scheduler.NewScheduler(widgetRepo, templateRepo, verificationRepo, cfg.Sheduler, cfg.Search, log)
+	userHandl := handlers.NewUserHandl(userServ, authServ, readmeServ, validator)
+	adminHandl := handlers.NewAdminHandl(authServ, validator)
+	notificationHandl := handlers.NewNotificationHandl(notificationServ, validator, cfg.Notifications)
+	defer notificationHandl.Close()
+	organizationHandl := handlers.NewOrganizationHandl(organizationServ, validator)
+	collabHandl := handlers.NewCollabHandl(readmeServ, collab.NewHub(readmeServ, validator, cfg.Collab, log), cfg.Collab)
+	defer collabHandl.Close()
+	commentHandl := handlers.NewCommentHandl(commentServ, validator)
+	collectionHandl := handlers.NewCollectionHandl(collectionServ, validator)
+	reactionHandl := handlers.NewReactionHandl(reactionServ, validator)
+
+	sheduler := scheduler.NewScheduler(widgetRepo, templateRepo, collectionRepo, verificationRepo, sessionRepo, passwordResetRepo, magicLinkRepo, emailChangeRepo, accessTokenRepo, userServ, cfg.Sheduler, cfg.Search, log)
 	sheduler.Start()
 	defer func() {
 		sheduler.Stop()
@@ -101,7 +138,7 @@ func Run() {
 	}()
 	log.Info("sheduler started")
 
-	routConfig := routes.NewRoutConfig(server.App, userHandl, authHandl, templateHandl, readmeHandl, widgetHandl)
+	routConfig := routes.NewRoutConfig(server.App, userHandl, authHandl, templateHandl, readmeHandl, widgetHandl, adminHandl, notificationHandl, organizationHandl, collabHandl, commentHandl, collectionHandl, reactionHandl)
 	routConfig.SetupRoutes()
 
 	go func() {
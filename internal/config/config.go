@@ -11,17 +11,19 @@ import (
 )
 
 type Config struct {
-	App          AppConfig          `mapstructure:"app"`
-	Server       ServerConfig       `mapstructure:"server"`
-	Auth         AuthConfig         `mapstructure:"auth"`
-	Storage      StorageConfig      `mapstructure:"storage"`
-	Cache        CacheConfig        `mapstructure:"cache"`
-	Search       SearchConfig       `mapstructure:"search"`
-	Email        EmailConfig        `mapstructure:"email"`
-	Sheduler     ShedulerConfig     `mapstructure:"sheduler"`
-	CloudStorage CloudStorageConfig `mapstructure:"cloudstorage"`
-	OAuth        OAuthConfig        `mapstructure:"oauth"`
-	Monitoring   MonitoringConfig   `mapstructure:"monitoring"`
+	App           AppConfig           `mapstructure:"app"`
+	Server        ServerConfig        `mapstructure:"server"`
+	Auth          AuthConfig          `mapstructure:"auth"`
+	Storage       StorageConfig       `mapstructure:"storage"`
+	Cache         CacheConfig         `mapstructure:"cache"`
+	Search        SearchConfig        `mapstructure:"search"`
+	Email         EmailConfig         `mapstructure:"email"`
+	Sheduler      ShedulerConfig      `mapstructure:"sheduler"`
+	CloudStorage  CloudStorageConfig  `mapstructure:"cloudstorage"`
+	OAuth         OAuthConfig         `mapstructure:"oauth"`
+	Monitoring    MonitoringConfig    `mapstructure:"monitoring"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	Collab        CollabConfig        `mapstructure:"collab"`
 }
 
 type AppConfig struct {
@@ -45,20 +47,63 @@ type ServerConfig struct {
 }
 
 type AuthConfig struct {
-	Secret       string        `mapstructure:"secret"`
-	CodeTTL      time.Duration `mapstructure:"codeTTL"`
-	CodeAttempts int           `mapstructure:"codeAttempts"`
-	TokenTTL     time.Duration `mapstructure:"tokenTTL"`
+	Secret          string             `mapstructure:"secret"`
+	CodeTTL         time.Duration      `mapstructure:"codeTTL"`
+	CodeAttempts    int                `mapstructure:"codeAttempts"`
+	TokenTTL        time.Duration      `mapstructure:"tokenTTL"`
+	RefreshTokenTTL time.Duration      `mapstructure:"refreshTokenTTL"`
+	ResetTokenTTL   time.Duration      `mapstructure:"resetTokenTTL"`
+	ResetURL        string             `mapstructure:"resetURL"`
+	TwoFactorTTL    time.Duration      `mapstructure:"twoFactorTTL"`
+	MagicLinkTTL    time.Duration      `mapstructure:"magicLinkTTL"`
+	MagicLinkURL    string             `mapstructure:"magicLinkURL"`
+	SecurityURL     string             `mapstructure:"securityURL"`
+	Lockout         LockoutConfig      `mapstructure:"lockout"`
+	SigningKeys     []SigningKeyConfig `mapstructure:"signingKeys"`
+}
+
+// SigningKeyConfig describes one JWT signing key. The first key signs new
+// tokens, the rest only verify until VerifyUntil (RFC 3339, empty means forever).
+type SigningKeyConfig struct {
+	Kid         string `mapstructure:"kid"`
+	Algorithm   string `mapstructure:"algorithm"`
+	KeyPath     string `mapstructure:"keyPath"`
+	VerifyUntil string `mapstructure:"verifyUntil"`
+}
+
+type LockoutConfig struct {
+	FreeAttempts  int           `mapstructure:"freeAttempts"`
+	MaxAttempts   int           `mapstructure:"maxAttempts"`
+	IPMaxAttempts int           `mapstructure:"ipMaxAttempts"`
+	Window        time.Duration `mapstructure:"window"`
+	BaseDelay     time.Duration `mapstructure:"baseDelay"`
+	MaxDelay      time.Duration `mapstructure:"maxDelay"`
+	TTL           time.Duration `mapstructure:"ttl"`
 }
 
 type OAuthConfig struct {
-	GoogleClientId     string        `mapstructure:"googleClientId"`
-	GoogleClientSecret string        `mapstructure:"googleClientSecret"`
-	GoogleRedirectURL  string        `mapstructure:"googleRedirectURL"`
-	GithubClientId     string        `mapstructure:"githubClientId"`
-	GithubClientSecret string        `mapstructure:"githubClientSecret"`
-	GithubRedirectURL  string        `mapstructure:"githubRedirectURL"`
-	StateTTL           time.Duration `mapstructure:"stateTTL"`
+	GoogleClientId        string               `mapstructure:"googleClientId"`
+	GoogleClientSecret    string               `mapstructure:"googleClientSecret"`
+	GoogleRedirectURL     string               `mapstructure:"googleRedirectURL"`
+	GithubClientId        string               `mapstructure:"githubClientId"`
+	GithubClientSecret    string               `mapstructure:"githubClientSecret"`
+	GithubRedirectURL     string               `mapstructure:"githubRedirectURL"`
+	StateTTL              time.Duration        `mapstructure:"stateTTL"`
+	GoogleLinkRedirectURL string               `mapstructure:"googleLinkRedirectURL"`
+	GithubLinkRedirectURL string               `mapstructure:"githubLinkRedirectURL"`
+	Providers             []OIDCProviderConfig `mapstructure:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientId     string   `mapstructure:"clientId"`
+	ClientSecret string   `mapstructure:"clientSecret"`
+	RedirectURL  string   `mapstructure:"redirectURL"`
+	Scopes       []string `mapstructure:"scopes"`
+	// TrustEmail lets a verified email of the provider sign in to the account
+	// that already has it. Only for issuers that really verify emails.
+	TrustEmail bool `mapstructure:"trustEmail"`
 }
 
 type StorageConfig struct {
@@ -98,12 +143,18 @@ type EmailConfig struct {
 }
 
 type ShedulerConfig struct {
-	WidgetBulkTime      time.Duration `mapstructure:"widgetBulkTime"`
-	WidgetBulkTimeout   time.Duration `mapstructure:"widgetBulkTimeout"`
-	TemplateBulkTime    time.Duration `mapstructure:"templateBulkTime"`
-	TemplateBulkTimeout time.Duration `mapstructure:"templateBulkTimeout"`
-	CleanCodesTime      time.Duration `mapstructure:"cleanCodesTime"`
-	CleanCodesTimeout   time.Duration `mapstructure:"cleanCodesTimeout"`
+	WidgetBulkTime        time.Duration `mapstructure:"widgetBulkTime"`
+	WidgetBulkTimeout     time.Duration `mapstructure:"widgetBulkTimeout"`
+	TemplateBulkTime      time.Duration `mapstructure:"templateBulkTime"`
+	TemplateBulkTimeout   time.Duration `mapstructure:"templateBulkTimeout"`
+	CollectionBulkTime    time.Duration `mapstructure:"collectionBulkTime"`
+	CollectionBulkTimeout time.Duration `mapstructure:"collectionBulkTimeout"`
+	CleanCodesTime        time.Duration `mapstructure:"cleanCodesTime"`
+	CleanCodesTimeout     time.Duration `mapstructure:"cleanCodesTimeout"`
+	CleanSessionsTime     time.Duration `mapstructure:"cleanSessionsTime"`
+	CleanSessionsTimeout  time.Duration `mapstructure:"cleanSessionsTimeout"`
+	EraseUsersTime        time.Duration `mapstructure:"eraseUsersTime"`
+	EraseUsersTimeout     time.Duration `mapstructure:"eraseUsersTimeout"`
 }
 
 type CloudStorageConfig struct {
@@ -111,6 +162,27 @@ type CloudStorageConfig struct {
 	Timeout  time.Duration `mapstructure:"timeout"`
 }
 
+// NotificationsConfig controls the realtime notification stream. StreamTimeout
+// replaces the server write timeout for it; clients reconnect after it ends.
+type NotificationsConfig struct {
+	StreamTimeout time.Duration `mapstructure:"streamTimeout"`
+	Heartbeat     time.Duration `mapstructure:"heartbeat"`
+}
+
+// CollabConfig controls live readme editing sessions. Snapshots of a session
+// are saved every SnapshotInterval and when its last editor leaves; History is
+// how many applied edits are kept to rebase late ones. Origins limits which
+// sites may open a session, since browsers authenticate it with the cookie.
+type CollabConfig struct {
+	SnapshotInterval time.Duration `mapstructure:"snapshotInterval"`
+	SnapshotTimeout  time.Duration `mapstructure:"snapshotTimeout"`
+	WriteTimeout     time.Duration `mapstructure:"writeTimeout"`
+	PingInterval     time.Duration `mapstructure:"pingInterval"`
+	History          int           `mapstructure:"history"`
+	MaxMessageSize   int64         `mapstructure:"maxMessageSize"`
+	Origins          []string      `mapstructure:"origins"`
+}
+
 type MonitoringConfig struct {
 	Namespace string `mapstructure:"namespace"`
 }
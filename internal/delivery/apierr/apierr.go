@@ -21,6 +21,7 @@ var (
 	ErrToManyRequests    = errors.New("to many requests")
 	ErrForbidden         = errors.New("forbidden")
 	ErrUnauthorized      = errors.New("unauthorized")
+	ErrInvalidCSRFToken  = errors.New("invalid csrf token")
 )
 
 type ApiErr struct {
@@ -64,6 +65,18 @@ func ToApiError(err error) ApiErr {
 		return CodeIsExpired()
 	case errors.Is(err, errs.ErrIncorrectOldPasswordBase):
 		return IncorrectOldPassword()
+	case errors.Is(err, errs.ErrInvalidTokenBase):
+		return Unauthorized()
+	case errors.Is(err, errs.ErrInvalidCredentialsBase):
+		return Unauthorized()
+	case errors.Is(err, errs.ErrTooManyAttemptsBase):
+		return TooManyRequests()
+	case errors.Is(err, errs.ErrBreachedPasswordBase):
+		return BreachedPassword()
+	case errors.Is(err, errs.ErrReservedHandleBase):
+		return ReservedHandle()
+	case errors.Is(err, errs.ErrForbiddenBase):
+		return Forbidden()
 	default:
 		return InternalServerError()
 	}
@@ -120,3 +133,15 @@ func Unauthorized() ApiErr {
 func IncorrectOldPassword() ApiErr {
 	return NewApiError(fiber.StatusBadRequest, errs.ErrIncorrectOldPasswordBase)
 }
+
+func BreachedPassword() ApiErr {
+	return NewApiError(fiber.StatusBadRequest, errs.ErrBreachedPasswordBase)
+}
+
+func ReservedHandle() ApiErr {
+	return NewApiError(fiber.StatusBadRequest, errs.ErrReservedHandleBase)
+}
+
+func InvalidCSRFToken() ApiErr {
+	return NewApiError(fiber.StatusForbidden, ErrInvalidCSRFToken)
+}
@@ -0,0 +1,547 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"readmeow/internal/config"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/services"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"readmeow/pkg/validator"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	// Client messages.
+	MessageOps    = "ops"
+	MessageCursor = "cursor"
+
+	// Server messages besides ops and cursor.
+	MessageInit     = "init"
+	MessageAck      = "ack"
+	MessagePresence = "presence"
+	MessageError    = "error"
+)
+
+const sendBuffer = 64
+
+// Cursor is where a client's caret is: Offset characters into the block at
+// Index of Field, with Length characters selected.
+type Cursor struct {
+	Field  string `json:"field" validate:"required,oneof=title text links widgets render_order"`
+	Index  int    `json:"index" validate:"min=0"`
+	Offset int    `json:"offset" validate:"min=0"`
+	Length int    `json:"length" validate:"min=0"`
+}
+
+// Peer is one open editor of the readme. A user may have several.
+type Peer struct {
+	ClientId string  `json:"client_id"`
+	UserId   string  `json:"user_id"`
+	Cursor   *Cursor `json:"cursor,omitempty"`
+}
+
+// ClientMessage is sent by editors. Ops are made against Version and a client
+// sends its next ops only after the ack of the previous ones.
+type ClientMessage struct {
+	Type    string  `json:"type" validate:"required,oneof=ops cursor"`
+	Version int     `json:"version" validate:"min=0"`
+	Ops     []Op    `json:"ops" validate:"required_if=Type ops,max=100,dive"`
+	Cursor  *Cursor `json:"cursor" validate:"required_if=Type cursor,omitempty"`
+}
+
+// ServerMessage is sent to editors. Init carries the whole readme and is sent
+// again when a client's ops can't be rebased any more.
+type ServerMessage struct {
+	Type     string         `json:"type"`
+	Version  int            `json:"version"`
+	ClientId string         `json:"client_id,omitempty"`
+	UserId   string         `json:"user_id,omitempty"`
+	Readme   *models.Readme `json:"readme,omitempty"`
+	Ops      []Op           `json:"ops,omitempty"`
+	Cursor   *Cursor        `json:"cursor,omitempty"`
+	Peers    []Peer         `json:"peers,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Hub keeps one editing session per open readme. Sessions live in memory, so
+// editors of the same readme must reach the same instance.
+type Hub struct {
+	ReadmeServ services.ReadmeServ
+	Validator  *validator.Validator
+	Config     config.CollabConfig
+	Logger     *logger.Logger
+	mu         sync.Mutex
+	rooms      map[string]*room
+	closed     bool
+}
+
+func NewHub(rs services.ReadmeServ, v *validator.Validator, cfg config.CollabConfig, l *logger.Logger) *Hub {
+	return &Hub{
+		ReadmeServ: rs,
+		Validator:  v,
+		Config:     cfg,
+		Logger:     l,
+		rooms:      make(map[string]*room),
+	}
+}
+
+// Serve runs an editing connection of uid until it is closed. readme is the
+// stored version, used when nobody else has the readme open.
+func (h *Hub) Serve(conn *websocket.Conn, readme *models.Readme, uid string) {
+	c := &client{
+		id:      uuid.NewString(),
+		uid:     uid,
+		conn:    conn,
+		send:    make(chan []byte, sendBuffer),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	r := h.join(readme, c)
+	if r == nil {
+		conn.Close()
+		return
+	}
+	go c.writeLoop(h.Config)
+	// The connection is reused once Serve returns, so wait for the writer.
+	defer func() { <-c.stopped }()
+	defer h.leave(r, c)
+
+	conn.SetReadLimit(h.Config.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(2 * h.Config.PingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.Config.PingInterval))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * h.Config.PingInterval))
+		msg := ClientMessage{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.push(ServerMessage{Type: MessageError, Error: "invalid message"})
+			continue
+		}
+		if errs := h.Validator.ValidateStruct(msg); len(errs) > 0 {
+			c.push(ServerMessage{Type: MessageError, Error: "invalid message"})
+			continue
+		}
+		switch msg.Type {
+		case MessageOps:
+			r.edit(c, msg.Version, msg.Ops)
+		case MessageCursor:
+			r.moveCursor(c, msg.Cursor)
+		}
+	}
+}
+
+// Close saves every open session and disconnects its editors.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	rooms := make([]*room, 0, len(h.rooms))
+	for _, r := range h.rooms {
+		rooms = append(rooms, r)
+	}
+	h.rooms = make(map[string]*room)
+	h.mu.Unlock()
+	for _, r := range rooms {
+		r.stop()
+		r.save()
+		r.disconnect()
+	}
+}
+
+func (h *Hub) join(readme *models.Readme, c *client) *room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	id := readme.Id.String()
+	r, ok := h.rooms[id]
+	if !ok {
+		r = newRoom(h, readme)
+		h.rooms[id] = r
+		go r.run()
+	}
+	r.add(c)
+	return r
+}
+
+// leave saves the session once its last editor is gone and drops it unless
+// someone joined while it was being saved.
+func (h *Hub) leave(r *room, c *client) {
+	if !r.remove(c) {
+		return
+	}
+	r.save()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[r.id] != r || !r.empty() {
+		return
+	}
+	delete(h.rooms, r.id)
+	r.stop()
+}
+
+// drop closes a session whose document can't be saved. Its editors have to
+// reconnect and start over from the stored readme, rather than keep editing
+// a document that is retried and refused again on every snapshot.
+func (h *Hub) drop(r *room, reason string) {
+	h.mu.Lock()
+	if h.rooms[r.id] == r {
+		delete(h.rooms, r.id)
+	}
+	h.mu.Unlock()
+	r.stop()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		c.push(ServerMessage{Type: MessageError, Version: r.version, Error: reason})
+		c.close()
+	}
+}
+
+type client struct {
+	id        string
+	uid       string
+	conn      *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	cursor    *Cursor
+}
+
+// push queues msg for the client. A client too slow to keep up is dropped
+// rather than holding up the session.
+func (c *client) push(msg ServerMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case <-c.done:
+	case c.send <- data:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *client) writeLoop(cfg config.CollabConfig) {
+	ping := time.NewTicker(cfg.PingInterval)
+	defer ping.Stop()
+	defer close(c.stopped)
+	defer c.conn.Close()
+	for {
+		select {
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// room is the editing session of one readme. history keeps the ops of the
+// last applied messages, the newest of them took the document to version.
+type room struct {
+	id       string
+	hub      *Hub
+	mu       sync.Mutex
+	readme   *models.Readme
+	version  int
+	history  [][]Op
+	clients  map[*client]struct{}
+	dirty    bool
+	editor   string
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newRoom(h *Hub, readme *models.Readme) *room {
+	return &room{
+		id:      readme.Id.String(),
+		hub:     h,
+		readme:  readme,
+		clients: make(map[*client]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// run saves the session periodically while it is open, first disconnecting
+// editors who lost access to the readme in the meantime.
+func (r *room) run() {
+	ticker := time.NewTicker(r.hub.Config.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			for _, uid := range r.users() {
+				if lostAccess(r.checkAccess(uid)) {
+					r.evict(uid)
+				}
+			}
+			r.save()
+		}
+	}
+}
+
+func (r *room) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *room) add(c *client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[c] = struct{}{}
+	c.push(ServerMessage{
+		Type:     MessageInit,
+		Version:  r.version,
+		ClientId: c.id,
+		Readme:   r.readme,
+		Peers:    r.peers(),
+	})
+	r.broadcast(c, ServerMessage{Type: MessagePresence, Version: r.version, Peers: r.peers()})
+}
+
+// remove reports whether c was the last editor.
+func (r *room) remove(c *client) bool {
+	c.close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, c)
+	r.broadcast(nil, ServerMessage{Type: MessagePresence, Version: r.version, Peers: r.peers()})
+	return len(r.clients) == 0
+}
+
+func (r *room) empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients) == 0
+}
+
+func (r *room) disconnect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		c.close()
+	}
+}
+
+// edit rebases ops made against version onto the current document, applies
+// them, acknowledges them to the sender and relays them to everyone else.
+func (r *room) edit(c *client, version int, ops []Op) {
+	if err := r.checkAccess(c.uid); err != nil {
+		if lostAccess(err) {
+			r.evict(c.uid)
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.resync(c, "failed to check access")
+		return
+	}
+	if err := r.checkWidgets(ops); err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.resync(c, "unknown widget")
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oldest := r.version - len(r.history)
+	if version > r.version || version < oldest {
+		r.resync(c, "version is out of range")
+		return
+	}
+	for _, applied := range r.history[version-oldest:] {
+		ops = transformOps(ops, applied)
+	}
+	next := *r.readme
+	next.Text = append([]string{}, r.readme.Text...)
+	next.Links = append([]string{}, r.readme.Links...)
+	next.Widgets = append([]map[string]string{}, r.readme.Widgets...)
+	next.RenderOrder = append([]string{}, r.readme.RenderOrder...)
+	for _, op := range ops {
+		if err := apply(&next, op); err != nil {
+			r.resync(c, err.Error())
+			return
+		}
+	}
+	r.readme = &next
+	r.version++
+	r.history = append(r.history, ops)
+	if len(r.history) > r.hub.Config.History {
+		r.history = r.history[len(r.history)-r.hub.Config.History:]
+	}
+	r.dirty = true
+	r.editor = c.uid
+	c.push(ServerMessage{Type: MessageAck, Version: r.version})
+	r.broadcast(c, ServerMessage{Type: MessageOps, Version: r.version, ClientId: c.id, UserId: c.uid, Ops: ops})
+}
+
+// checkAccess makes sure the user may still edit the readme, access can be
+// taken away while the session is open.
+func (r *room) checkAccess(uid string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.hub.Config.SnapshotTimeout)
+	defer cancel()
+	_, err := r.hub.ReadmeServ.GetForEdit(ctx, r.id, uid)
+	return err
+}
+
+// lostAccess tells access errors apart from failures to check it.
+func lostAccess(err error) bool {
+	return errors.Is(err, errs.ErrForbiddenBase) || errors.Is(err, errs.ErrNotFoundBase)
+}
+
+// evict disconnects every client of the user.
+func (r *room) evict(uid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		if c.uid == uid {
+			c.push(ServerMessage{Type: MessageError, Version: r.version, Error: "access denied"})
+			c.close()
+		}
+	}
+}
+
+// users lists the users with a client in the session.
+func (r *room) users() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]struct{}, len(r.clients))
+	uids := make([]string, 0, len(r.clients))
+	for c := range r.clients {
+		if _, ok := seen[c.uid]; !ok {
+			seen[c.uid] = struct{}{}
+			uids = append(uids, c.uid)
+		}
+	}
+	return uids
+}
+
+// checkWidgets makes sure the widgets the ops insert exist before they reach
+// the document, a snapshot with an unknown widget could never be saved.
+func (r *room) checkWidgets(ops []Op) error {
+	ids := make([]string, 0)
+	for _, op := range ops {
+		if op.Field != FieldWidgets || op.Type == OpDelete {
+			continue
+		}
+		for id := range op.Widget {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.hub.Config.SnapshotTimeout)
+	defer cancel()
+	return r.hub.ReadmeServ.CheckWidgets(ctx, ids)
+}
+
+// resync drops the client's ops and hands it the current document instead.
+func (r *room) resync(c *client, reason string) {
+	c.push(ServerMessage{Type: MessageError, Version: r.version, Error: reason})
+	c.push(ServerMessage{Type: MessageInit, Version: r.version, ClientId: c.id, Readme: r.readme, Peers: r.peers()})
+}
+
+func (r *room) moveCursor(c *client, cursor *Cursor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.cursor = cursor
+	r.broadcast(c, ServerMessage{Type: MessageCursor, Version: r.version, ClientId: c.id, UserId: c.uid, Cursor: cursor})
+}
+
+func (r *room) peers() []Peer {
+	peers := make([]Peer, 0, len(r.clients))
+	for c := range r.clients {
+		peers = append(peers, Peer{ClientId: c.id, UserId: c.uid, Cursor: c.cursor})
+	}
+	return peers
+}
+
+func (r *room) broadcast(from *client, msg ServerMessage) {
+	for c := range r.clients {
+		if c != from {
+			c.push(msg)
+		}
+	}
+}
+
+// save stores the document through the readme service as the last user who
+// edited it, so their access is checked again and widget usage kept right.
+// When that user lost access, the save goes through another editor still in
+// the session.
+func (r *room) save() {
+	op := "room.save"
+	log := r.hub.Logger.AddOp(op)
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return
+	}
+	readme, editor := r.readme, r.editor
+	r.dirty = false
+	r.mu.Unlock()
+	editors := []string{editor}
+	for _, uid := range r.users() {
+		if uid != editor {
+			editors = append(editors, uid)
+		}
+	}
+
+	updates := map[string]any{
+		"title":        readme.Title,
+		"text":         readme.Text,
+		"links":        readme.Links,
+		"widgets":      readme.Widgets,
+		"render_order": readme.RenderOrder,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.hub.Config.SnapshotTimeout)
+	defer cancel()
+	for _, uid := range editors {
+		err := r.hub.ReadmeServ.Update(ctx, updates, r.id, uid)
+		if err == nil {
+			log.Info("readme snapshot saved")
+			return
+		}
+		log.Error("failed to save readme snapshot", logger.Err(err))
+		if !lostAccess(err) {
+			break
+		}
+	}
+	r.hub.drop(r, "failed to save readme")
+}
@@ -0,0 +1,132 @@
+package collab
+
+import (
+	"fmt"
+	"readmeow/internal/domain/models"
+)
+
+const (
+	OpInsert = "insert"
+	OpDelete = "delete"
+	OpUpdate = "update"
+)
+
+const (
+	FieldTitle       = "title"
+	FieldText        = "text"
+	FieldLinks       = "links"
+	FieldWidgets     = "widgets"
+	FieldRenderOrder = "render_order"
+)
+
+// Op is a single block edit: it inserts, deletes or replaces the block at
+// Index of one of the readme lists. The title is a single block, so only
+// updates apply to it. Widgets carry their value in Widget, the rest in Value.
+type Op struct {
+	Type   string            `json:"type" validate:"required,oneof=insert delete update"`
+	Field  string            `json:"field" validate:"required,oneof=title text links widgets render_order"`
+	Index  int               `json:"index" validate:"min=0"`
+	Value  string            `json:"value,omitempty" validate:"max=20000"`
+	Widget map[string]string `json:"widget,omitempty" validate:"max=10"`
+}
+
+// transform rebases op, made against the same document as against, onto the
+// document after against. later tells whether op is applied after against on
+// the server: the later one wins concurrent updates of a block and goes
+// second when both insert at the same index. Clients must transform with the
+// same rules, treating incoming ops as the earlier ones. The second result is
+// false when op has nothing left to do.
+func transform(op, against Op, later bool) (Op, bool) {
+	if op.Field != against.Field {
+		return op, true
+	}
+	if op.Field == FieldTitle {
+		return op, later
+	}
+	switch against.Type {
+	case OpInsert:
+		if op.Index > against.Index || op.Index == against.Index && (op.Type != OpInsert || later) {
+			op.Index++
+		}
+	case OpDelete:
+		switch {
+		case op.Index > against.Index:
+			op.Index--
+		case op.Index == against.Index && op.Type != OpInsert:
+			return op, false
+		}
+	case OpUpdate:
+		if op.Type == OpUpdate && op.Index == against.Index {
+			return op, later
+		}
+	}
+	return op, true
+}
+
+// transformOps rebases ops, a sequence made against the same document as the
+// applied sequence, onto the document after applied.
+func transformOps(ops, applied []Op) []Op {
+	for _, a := range applied {
+		rebased := make([]Op, 0, len(ops))
+		live := true
+		for _, op := range ops {
+			if !live {
+				rebased = append(rebased, op)
+				continue
+			}
+			next, ok := transform(op, a, true)
+			a, live = transform(a, op, false)
+			if ok {
+				rebased = append(rebased, next)
+			}
+		}
+		ops = rebased
+	}
+	return ops
+}
+
+// apply performs op on readme, refusing ops that point outside the lists.
+func apply(readme *models.Readme, op Op) error {
+	switch op.Field {
+	case FieldTitle:
+		if op.Type != OpUpdate {
+			return fmt.Errorf("title can only be updated")
+		}
+		readme.Title = op.Value
+		return nil
+	case FieldText:
+		return applyList(&readme.Text, op, op.Value)
+	case FieldLinks:
+		return applyList(&readme.Links, op, op.Value)
+	case FieldRenderOrder:
+		return applyList(&readme.RenderOrder, op, op.Value)
+	case FieldWidgets:
+		return applyList(&readme.Widgets, op, op.Widget)
+	}
+	return fmt.Errorf("unknown field %q", op.Field)
+}
+
+func applyList[T any](list *[]T, op Op, value T) error {
+	l := *list
+	limit := len(l)
+	if op.Type == OpInsert {
+		limit++
+	}
+	if op.Index >= limit {
+		return fmt.Errorf("%s index %d out of range", op.Field, op.Index)
+	}
+	switch op.Type {
+	case OpInsert:
+		l = append(l, value)
+		copy(l[op.Index+1:], l[op.Index:])
+		l[op.Index] = value
+	case OpDelete:
+		l = append(l[:op.Index], l[op.Index+1:]...)
+	case OpUpdate:
+		l[op.Index] = value
+	default:
+		return fmt.Errorf("unknown op %q", op.Type)
+	}
+	*list = l
+	return nil
+}
@@ -0,0 +1,346 @@
+package collab
+
+import (
+	"fmt"
+	"readmeow/internal/domain/models"
+	"reflect"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      Op
+		against Op
+		later   bool
+		want    Op
+		wantOk  bool
+	}{
+		{
+			name:    "insert after later insert at same index",
+			op:      Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "a"},
+			later:   true,
+			want:    Op{Type: OpInsert, Field: FieldText, Index: 2, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "insert before earlier insert at same index",
+			op:      Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "a"},
+			against: Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "b"},
+			later:   false,
+			want:    Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "a"},
+			wantOk:  true,
+		},
+		{
+			name:    "insert before insert at higher index",
+			op:      Op{Type: OpInsert, Field: FieldText, Index: 0, Value: "b"},
+			against: Op{Type: OpInsert, Field: FieldText, Index: 2, Value: "a"},
+			later:   true,
+			want:    Op{Type: OpInsert, Field: FieldText, Index: 0, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "update shifted by insert at same index",
+			op:      Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "a"},
+			later:   false,
+			want:    Op{Type: OpUpdate, Field: FieldText, Index: 2, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "update of deleted block",
+			op:      Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpDelete, Field: FieldText, Index: 1},
+			later:   true,
+			wantOk:  false,
+		},
+		{
+			name:    "delete of updated block",
+			op:      Op{Type: OpDelete, Field: FieldText, Index: 1},
+			against: Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			later:   true,
+			want:    Op{Type: OpDelete, Field: FieldText, Index: 1},
+			wantOk:  true,
+		},
+		{
+			name:    "delete of deleted block",
+			op:      Op{Type: OpDelete, Field: FieldText, Index: 1},
+			against: Op{Type: OpDelete, Field: FieldText, Index: 1},
+			later:   true,
+			wantOk:  false,
+		},
+		{
+			name:    "insert at index of deleted block",
+			op:      Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpDelete, Field: FieldText, Index: 1},
+			later:   true,
+			want:    Op{Type: OpInsert, Field: FieldText, Index: 1, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "op after deleted block",
+			op:      Op{Type: OpUpdate, Field: FieldText, Index: 3, Value: "b"},
+			against: Op{Type: OpDelete, Field: FieldText, Index: 1},
+			later:   true,
+			want:    Op{Type: OpUpdate, Field: FieldText, Index: 2, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "later update wins",
+			op:      Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "a"},
+			later:   true,
+			want:    Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			wantOk:  true,
+		},
+		{
+			name:    "earlier update loses",
+			op:      Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "b"},
+			against: Op{Type: OpUpdate, Field: FieldText, Index: 1, Value: "a"},
+			later:   false,
+			wantOk:  false,
+		},
+		{
+			name:    "earlier title update loses",
+			op:      Op{Type: OpUpdate, Field: FieldTitle, Value: "b"},
+			against: Op{Type: OpUpdate, Field: FieldTitle, Value: "a"},
+			later:   false,
+			wantOk:  false,
+		},
+		{
+			name:    "other field",
+			op:      Op{Type: OpDelete, Field: FieldLinks, Index: 1},
+			against: Op{Type: OpDelete, Field: FieldText, Index: 0},
+			later:   true,
+			want:    Op{Type: OpDelete, Field: FieldLinks, Index: 1},
+			wantOk:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := transform(tt.op, tt.against, tt.later)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("op = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTransformOps rebases ops the way room.edit does: against every message
+// of the history applied since the version the ops were made against.
+func TestTransformOps(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    []string
+		history [][]Op
+		ops     []Op
+		want    []Op
+		doc     []string
+	}{
+		{
+			name: "history of two messages",
+			base: []string{"a", "b", "c"},
+			history: [][]Op{
+				{{Type: OpInsert, Field: FieldText, Index: 0, Value: "x"}},
+				{{Type: OpDelete, Field: FieldText, Index: 2}},
+			},
+			ops: []Op{
+				{Type: OpUpdate, Field: FieldText, Index: 1, Value: "B"},
+				{Type: OpInsert, Field: FieldText, Index: 3, Value: "d"},
+			},
+			want: []Op{
+				{Type: OpInsert, Field: FieldText, Index: 3, Value: "d"},
+			},
+			doc: []string{"x", "a", "c", "d"},
+		},
+		{
+			name: "ops depending on each other",
+			base: []string{"a", "b", "c"},
+			history: [][]Op{
+				{{Type: OpDelete, Field: FieldText, Index: 0}},
+			},
+			ops: []Op{
+				{Type: OpInsert, Field: FieldText, Index: 0, Value: "p"},
+				{Type: OpUpdate, Field: FieldText, Index: 1, Value: "A"},
+				{Type: OpUpdate, Field: FieldText, Index: 2, Value: "B"},
+			},
+			want: []Op{
+				{Type: OpInsert, Field: FieldText, Index: 0, Value: "p"},
+				{Type: OpUpdate, Field: FieldText, Index: 1, Value: "B"},
+			},
+			doc: []string{"p", "B", "c"},
+		},
+		{
+			name: "multi-op message in history",
+			base: []string{"a", "b"},
+			history: [][]Op{
+				{
+					{Type: OpInsert, Field: FieldText, Index: 2, Value: "c"},
+					{Type: OpInsert, Field: FieldText, Index: 0, Value: "x"},
+				},
+				{{Type: OpUpdate, Field: FieldText, Index: 3, Value: "C"}},
+			},
+			ops: []Op{
+				{Type: OpDelete, Field: FieldText, Index: 1},
+				{Type: OpInsert, Field: FieldText, Index: 1, Value: "y"},
+			},
+			want: []Op{
+				{Type: OpDelete, Field: FieldText, Index: 2},
+				{Type: OpInsert, Field: FieldText, Index: 3, Value: "y"},
+			},
+			doc: []string{"x", "a", "C", "y"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readme := &models.Readme{Text: append([]string{}, tt.base...)}
+			ops := tt.ops
+			for _, applied := range tt.history {
+				for _, op := range applied {
+					if err := apply(readme, op); err != nil {
+						t.Fatalf("apply history: %v", err)
+					}
+				}
+				ops = transformOps(ops, applied)
+			}
+			if !reflect.DeepEqual(ops, tt.want) {
+				t.Fatalf("ops = %+v, want %+v", ops, tt.want)
+			}
+			for _, op := range ops {
+				if err := apply(readme, op); err != nil {
+					t.Fatalf("apply ops: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(readme.Text, tt.doc) {
+				t.Errorf("text = %q, want %q", readme.Text, tt.doc)
+			}
+		})
+	}
+}
+
+// TestTransformConverges applies every pair of concurrent ops in both orders:
+// the server applies a and then b rebased as the later op, a client that made
+// b applies it first and then a rebased as the earlier one. Both must end up
+// with the same document.
+func TestTransformConverges(t *testing.T) {
+	base := []string{"a", "b", "c"}
+	ops := func(value string) []Op {
+		list := []Op{
+			{Type: OpUpdate, Field: FieldTitle, Value: value},
+			{Type: OpInsert, Field: FieldLinks, Index: 0, Value: value},
+		}
+		for i := 0; i <= len(base); i++ {
+			list = append(list, Op{Type: OpInsert, Field: FieldText, Index: i, Value: value})
+		}
+		for i := range base {
+			list = append(list,
+				Op{Type: OpDelete, Field: FieldText, Index: i},
+				Op{Type: OpUpdate, Field: FieldText, Index: i, Value: value},
+			)
+		}
+		return list
+	}
+
+	for _, a := range ops("A") {
+		for _, b := range ops("B") {
+			t.Run(fmt.Sprintf("%+v/%+v", a, b), func(t *testing.T) {
+				server := models.Readme{Title: "t", Text: append([]string{}, base...)}
+				mustApply(t, &server, a)
+				if next, ok := transform(b, a, true); ok {
+					mustApply(t, &server, next)
+				}
+
+				client := models.Readme{Title: "t", Text: append([]string{}, base...)}
+				mustApply(t, &client, b)
+				if next, ok := transform(a, b, false); ok {
+					mustApply(t, &client, next)
+				}
+
+				if !reflect.DeepEqual(server, client) {
+					t.Errorf("server %+v, client %+v", server, client)
+				}
+			})
+		}
+	}
+}
+
+// TestTransformOpsConverges does the same for every pair of two-op messages.
+// The client rebases the applied message against its pending one with the
+// same rules, treating the applied ops as the earlier ones.
+func TestTransformOpsConverges(t *testing.T) {
+	base := []string{"a", "b"}
+	ops := func(value string) []Op {
+		list := make([]Op, 0)
+		for i := 0; i <= len(base); i++ {
+			list = append(list,
+				Op{Type: OpInsert, Field: FieldText, Index: i, Value: value + fmt.Sprint(i)},
+				Op{Type: OpDelete, Field: FieldText, Index: i},
+				Op{Type: OpUpdate, Field: FieldText, Index: i, Value: value + fmt.Sprint(i)},
+			)
+		}
+		return list
+	}
+	messages := func(value string) [][]Op {
+		list := make([][]Op, 0)
+		for _, first := range ops(value) {
+			for _, second := range ops(value) {
+				readme := models.Readme{Text: append([]string{}, base...)}
+				if apply(&readme, first) == nil && apply(&readme, second) == nil {
+					list = append(list, []Op{first, second})
+				}
+			}
+		}
+		return list
+	}
+	clientRebase := func(applied, pending []Op) []Op {
+		rebased := make([]Op, 0, len(applied))
+		for _, a := range applied {
+			next := make([]Op, 0, len(pending))
+			live := true
+			for _, op := range pending {
+				if !live {
+					next = append(next, op)
+					continue
+				}
+				if p, ok := transform(op, a, true); ok {
+					next = append(next, p)
+				}
+				a, live = transform(a, op, false)
+			}
+			if live {
+				rebased = append(rebased, a)
+			}
+			pending = next
+		}
+		return rebased
+	}
+
+	for _, a := range messages("A") {
+		for _, b := range messages("B") {
+			server := models.Readme{Text: append([]string{}, base...)}
+			for _, op := range append(append([]Op{}, a...), transformOps(b, a)...) {
+				mustApply(t, &server, op)
+			}
+			client := models.Readme{Text: append([]string{}, base...)}
+			for _, op := range append(append([]Op{}, b...), clientRebase(a, b)...) {
+				mustApply(t, &client, op)
+			}
+			if !reflect.DeepEqual(server.Text, client.Text) {
+				t.Errorf("%+v then %+v: server %q, client %q", a, b, server.Text, client.Text)
+			}
+		}
+	}
+}
+
+func mustApply(t *testing.T, readme *models.Readme, op Op) {
+	t.Helper()
+	if err := apply(readme, op); err != nil {
+		t.Fatalf("apply %+v: %v", op, err)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandl struct {
+	AuthServ  services.AuthServ
+	Validator *validator.Validator
+}
+
+func NewAdminHandl(as services.AuthServ, v *validator.Validator) *AdminHandl {
+	return &AdminHandl{
+		AuthServ:  as,
+		Validator: v,
+	}
+}
+
+// ChangeUserRole godoc
+// @Summary      Change User Role
+// @Description  Changing role of a user and revoking the user's sessions. Available only for admins
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Param        body body dto.ChangeRoleRequest true "Change role request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/admin/users/{user}/role [patch]
+func (ah *AdminHandl) ChangeUserRole(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("user")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.ChangeRoleRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	adminId := c.Locals("userId").(string)
+	if err := ah.AuthServ.ChangeRole(ctx, id, req.Role, adminId); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
@@ -20,14 +20,16 @@ type AuthHandl struct {
 	AuthServ    services.AuthServ
 	UserServ    services.UserServ
 	OAuthConfig oauth.OAuthConfig
+	OIDC        *oauth.Registry
 	Validator   *validator.Validator
 }
 
-func NewAuthHandle(as services.AuthServ, us services.UserServ, oc oauth.OAuthConfig, v *validator.Validator) *AuthHandl {
+func NewAuthHandle(as services.AuthServ, us services.UserServ, oc oauth.OAuthConfig, r *oauth.Registry, v *validator.Validator) *AuthHandl {
 	return &AuthHandl{
 		AuthServ:    as,
 		UserServ:    us,
 		OAuthConfig: oc,
+		OIDC:        r,
 		Validator:   v,
 	}
 }
@@ -52,19 +54,11 @@ func (ah *AuthHandl) Register(c *fiber.Ctx) error {
 	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
 		return err
 	}
-	loginData, err := ah.AuthServ.Register(ctx, req.Email, req.Code)
+	loginData, err := ah.AuthServ.Register(ctx, req.Email, req.Code, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	cookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    loginData.JWT,
-		HTTPOnly: true,
-		Expires:  loginData.TTL,
-		MaxAge:   int(time.Until(loginData.TTL).Seconds()),
-		SameSite: "Lax",
-	}
-	c.Cookie(cookie)
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
 	response := dto.LoginResponse{
 		Id:       loginData.Id,
 		Nickname: loginData.Nickname,
@@ -107,10 +101,13 @@ func (ah *AuthHandl) VerifyEmail(c *fiber.Ctx) error {
 // @Produce json
 // @Param body body dto.LoginRequest true "Login Request"
 // @Success 200 {object} dto.LoginResponse "Login response"
+// @Success 202 {object} dto.TwoFactorChallengeResponse "Two factor code required"
 // @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Invalid credentials"
 // @Failure 404 {object} apierr.ApiErr "Not found"
 // @Failure 409 {object} apierr.ApiErr "Already exists"
 // @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 429 {object} apierr.ApiErr "Too many failed attempts"
 // @Failure 500 {object} apierr.ApiErr "Internal server error"
 // @Router /api/auth/login [post]
 func (ah *AuthHandl) Login(c *fiber.Ctx) error {
@@ -119,19 +116,18 @@ func (ah *AuthHandl) Login(c *fiber.Ctx) error {
 	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
 		return err
 	}
-	loginData, err := ah.AuthServ.Login(ctx, req.Login, req.Password)
+	loginData, err := ah.AuthServ.Login(ctx, req.Login, req.Password, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	cookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    loginData.JWT,
-		HTTPOnly: true,
-		Expires:  loginData.TTL,
-		MaxAge:   int(time.Until(loginData.TTL).Seconds()),
-		SameSite: "Lax",
+	if loginData.TwoFactorToken != "" {
+		response := dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Token:             loginData.TwoFactorToken,
+		}
+		return c.Status(fiber.StatusAccepted).JSON(response)
 	}
-	c.Cookie(cookie)
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
 	response := dto.LoginResponse{
 		Id:       loginData.Id,
 		Nickname: loginData.Nickname,
@@ -142,22 +138,193 @@ func (ah *AuthHandl) Login(c *fiber.Ctx) error {
 
 // Logout godoc
 // @Summary Logout
-// @Description Logout a user
+// @Description Logout a user and revoke the current session
 // @Tags Auth
 // @Produce json
 // @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
 // @Security ApiKeyAuth
-// @Router /api/auth/logout [get]
+// @Router /api/auth/logout [post]
 func (ah *AuthHandl) Logout(c *fiber.Ctx) error {
-	cookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    "",
-		HTTPOnly: true,
-		Expires:  time.Now().Add(-time.Hour),
-		MaxAge:   -1,
-		SameSite: "Lax",
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	jti := c.Locals("jti").(string)
+	helpers.ClearAuthCookies(c)
+	if err := ah.AuthServ.Logout(ctx, uid, jti); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// Refresh godoc
+// @Summary Refresh
+// @Description Rotate the refresh token cookie and issue a new access token
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.LoginResponse "Login response"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/refresh [post]
+func (ah *AuthHandl) Refresh(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	token := c.Cookies(helpers.RefreshCookie)
+	if token == "" {
+		return apierr.Unauthorized()
+	}
+	loginData, err := ah.AuthServ.Refresh(ctx, token, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		helpers.ClearAuthCookies(c)
+		return apierr.ToApiError(err)
+	}
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
+	response := dto.LoginResponse{
+		Id:       loginData.Id,
+		Nickname: loginData.Nickname,
+		Avatar:   loginData.Avatar,
+	}
+	return c.JSON(response)
+}
+
+// CreateAccessToken godoc
+// @Summary Create Access Token
+// @Description Creating a personal access token with scopes for CLI and CI usage. The token is shown only once and is sent as Authorization: Bearer
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.CreateAccessTokenRequest true "Create Access Token Request"
+// @Success 200 {object} dto.CreateAccessTokenResponse "Access token response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 403 {object} apierr.ApiErr "Forbidden"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/tokens [post]
+func (ah *AuthHandl) CreateAccessToken(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.CreateAccessTokenRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, err := ah.AuthServ.CreateAccessToken(ctx, uid, req.Name, req.Scopes, ttl)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(token)
+}
+
+// FetchAccessTokens godoc
+// @Summary Fetch Access Tokens
+// @Description Fetching personal access tokens of the logged-in user with scopes, expiry and last usage
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.AccessTokenResponse "Access tokens response"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 403 {object} apierr.ApiErr "Forbidden"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/tokens [get]
+func (ah *AuthHandl) FetchAccessTokens(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	tokens, err := ah.AuthServ.FetchAccessTokens(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeAccessToken godoc
+// @Summary Revoke Access Token
+// @Description Revoking one personal access token of the logged-in user by its id
+// @Tags Auth
+// @Produce json
+// @Param token path string true "Access Token ID"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 403 {object} apierr.ApiErr "Forbidden"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/tokens/{token} [delete]
+func (ah *AuthHandl) RevokeAccessToken(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("token")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.RevokeAccessToken(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
 	}
-	c.Cookie(cookie)
+	return helpers.SuccessResponse(c)
+}
+
+// FetchSessions godoc
+// @Summary Fetch Sessions
+// @Description Fetching active sessions of the logged-in user with device and IP
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.SessionResponse "Sessions response"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/sessions [get]
+func (ah *AuthHandl) FetchSessions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	jti := c.Locals("jti").(string)
+	sessions, err := ah.AuthServ.FetchSessions(ctx, uid, jti)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke Session
+// @Description Revoking one session of the logged-in user by its id
+// @Tags Auth
+// @Produce json
+// @Param session path string true "Session ID"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/sessions/{session} [delete]
+func (ah *AuthHandl) RevokeSession(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("session")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.RevokeSession(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// RevokeAllSessions godoc
+// @Summary Revoke All Sessions
+// @Description Revoking every session of the logged-in user, including the current one
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/sessions [delete]
+func (ah *AuthHandl) RevokeAllSessions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.RevokeAllSessions(ctx, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	helpers.ClearAuthCookies(c)
 	return helpers.SuccessResponse(c)
 }
 
@@ -181,6 +348,34 @@ func (ah *AuthHandl) Profile(c *fiber.Ctx) error {
 	return c.JSON(user)
 }
 
+// CSRFToken godoc
+// @Summary CSRF Token
+// @Description Issuing a CSRF token. It is set as a cookie and must be sent back in the X-CSRF-Token header of every POST, PATCH and DELETE request authenticated by cookies. Sign-in, registration, verification, password reset, magic link and 2FA login endpoints are exempt
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.CSRFTokenResponse "CSRF token response"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/csrf [get]
+func (ah *AuthHandl) CSRFToken(c *fiber.Ctx) error {
+	token, err := helpers.SetCSRFToken(c)
+	if err != nil {
+		return apierr.InternalServerError()
+	}
+	return c.JSON(dto.CSRFTokenResponse{Token: token})
+}
+
+// JWKS godoc
+// @Summary JWKS
+// @Description Public keys that verify access tokens issued by readmeow, in JSON Web Key Set format
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.JWKSResponse "Key set response"
+// @Router /.well-known/jwks.json [get]
+func (ah *AuthHandl) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(ah.AuthServ.JWKS())
+}
+
 // SendNewCode godoc
 // @Summary Send New Code
 // @Description Sending a new code for user verification
@@ -207,6 +402,261 @@ func (ah *AuthHandl) SendNewCode(c *fiber.Ctx) error {
 	return helpers.SuccessResponse(c)
 }
 
+// ForgotPassword godoc
+// @Summary Forgot Password
+// @Description Sending a one-time password reset link. The response is the same whether the email is registered or not
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.ForgotPasswordRequest true "Forgot Password Request"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/password/forgot [post]
+func (ah *AuthHandl) ForgotPassword(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.ForgotPasswordRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	if err := ah.AuthServ.ForgotPassword(ctx, req.Email); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// ResetPassword godoc
+// @Summary Reset Password
+// @Description Setting a new password with a reset token and revoking all sessions
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.ResetPasswordRequest true "Reset Password Request"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Invalid or expired token"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/password/reset [post]
+func (ah *AuthHandl) ResetPassword(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.ResetPasswordRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	if err := ah.AuthServ.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		return apierr.ToApiError(err)
+	}
+	helpers.ClearAuthCookies(c)
+	return helpers.SuccessResponse(c)
+}
+
+// RequestMagicLink godoc
+// @Summary Request Magic Link
+// @Description Sending a one-time sign-in link. The response is the same whether the email is registered or not
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.MagicLinkRequest true "Magic Link Request"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/magic [post]
+func (ah *AuthHandl) RequestMagicLink(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.MagicLinkRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	if err := ah.AuthServ.RequestMagicLink(ctx, req.Email); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// LoginMagicLink godoc
+// @Summary Login Magic Link
+// @Description Logging in with the token from a sign-in link. The token can be used only once
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.MagicLinkLoginRequest true "Magic Link Login Request"
+// @Success 200 {object} dto.LoginResponse "Login response"
+// @Success 202 {object} dto.TwoFactorChallengeResponse "Two factor code required"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Invalid or expired token"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/magic/verify [post]
+func (ah *AuthHandl) LoginMagicLink(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.MagicLinkLoginRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	loginData, err := ah.AuthServ.LoginMagicLink(ctx, req.Token, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	if loginData.TwoFactorToken != "" {
+		response := dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Token:             loginData.TwoFactorToken,
+		}
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
+	response := dto.LoginResponse{
+		Id:       loginData.Id,
+		Nickname: loginData.Nickname,
+		Avatar:   loginData.Avatar,
+	}
+	return c.JSON(response)
+}
+
+// LoginTwoFactor godoc
+// @Summary Login Two Factor
+// @Description Finishing login with a TOTP or recovery code after the password check
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.TwoFactorLoginRequest true "Two Factor Login Request"
+// @Success 200 {object} dto.LoginResponse "Login response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Invalid or expired token"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/2fa/login [post]
+func (ah *AuthHandl) LoginTwoFactor(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.TwoFactorLoginRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	loginData, err := ah.AuthServ.LoginTwoFactor(ctx, req.Token, req.Code, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
+	response := dto.LoginResponse{
+		Id:       loginData.Id,
+		Nickname: loginData.Nickname,
+		Avatar:   loginData.Avatar,
+	}
+	return c.JSON(response)
+}
+
+// EnrollTwoFactor godoc
+// @Summary Enroll Two Factor
+// @Description Generating a TOTP secret and an otpauth URI for the authenticator app
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.TwoFactorEnrollResponse "Two factor enroll response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 409 {object} apierr.ApiErr "Already enabled"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/2fa/enroll [post]
+func (ah *AuthHandl) EnrollTwoFactor(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	response, err := ah.AuthServ.EnrollTwoFactor(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(response)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm Two Factor
+// @Description Enabling two factor with the first TOTP code. Recovery codes are shown only once
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.TwoFactorCodeRequest true "Two Factor Code Request"
+// @Success 200 {object} dto.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 409 {object} apierr.ApiErr "Already enabled"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/2fa/confirm [post]
+func (ah *AuthHandl) ConfirmTwoFactor(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.TwoFactorCodeRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	codes, err := ah.AuthServ.ConfirmTwoFactor(ctx, uid, req.Code)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(dto.RecoveryCodesResponse{Codes: codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable Two Factor
+// @Description Disabling two factor and deleting recovery codes after the password check
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.TwoFactorPasswordRequest true "Two Factor Password Request"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/2fa [delete]
+func (ah *AuthHandl) DisableTwoFactor(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.TwoFactorPasswordRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.DisableTwoFactor(ctx, uid, req.Password); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate Recovery Codes
+// @Description Replacing all recovery codes after the password check. New codes are shown only once
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.TwoFactorPasswordRequest true "Two Factor Password Request"
+// @Success 200 {object} dto.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/2fa/recovery [post]
+func (ah *AuthHandl) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.TwoFactorPasswordRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ah.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	codes, err := ah.AuthServ.RegenerateRecoveryCodes(ctx, uid, req.Password)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(dto.RecoveryCodesResponse{Codes: codes})
+}
+
 // GoogleOAuth godoc
 // @Summary Login via Google
 // @Description Start Google OAuth login flow. User will be redirected to Google for authentication, then back to your app. After successful login, the client will receive dto.LoginResponse.
@@ -217,17 +667,7 @@ func (ah *AuthHandl) SendNewCode(c *fiber.Ctx) error {
 // @Failure 500 {object} apierr.ApiErr "Internal server error"
 // @Router /api/auth/google [get]
 func (ah *AuthHandl) GoogleOAuth(c *fiber.Ctx) error {
-	state := uuid.New().String()
-	exp := time.Now().Add(ah.OAuthConfig.StateExpire)
-	stateCookie := &fiber.Cookie{
-		Name:     "oauth_state",
-		Value:    state,
-		HTTPOnly: true,
-		Expires:  exp,
-		MaxAge:   int(time.Until(exp).Seconds()),
-		SameSite: "Lax",
-	}
-	c.Cookie(stateCookie)
+	state := setOAuthState(c, ah.OAuthConfig.StateExpire)
 	url := ah.OAuthConfig.GoogleOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline)
 	return c.Redirect(url, fiber.StatusTemporaryRedirect)
 }
@@ -242,17 +682,7 @@ func (ah *AuthHandl) GoogleOAuth(c *fiber.Ctx) error {
 // @Failure 500 {object} apierr.ApiErr "Internal server error"
 // @Router /api/auth/github [get]
 func (ah *AuthHandl) GitHubOAuth(c *fiber.Ctx) error {
-	state := uuid.New().String()
-	exp := time.Now().Add(ah.OAuthConfig.StateExpire)
-	stateCookie := &fiber.Cookie{
-		Name:     "oauth_state",
-		Value:    state,
-		HTTPOnly: true,
-		Expires:  exp,
-		MaxAge:   int(time.Until(exp).Seconds()),
-		SameSite: "Lax",
-	}
-	c.Cookie(stateCookie)
+	state := setOAuthState(c, ah.OAuthConfig.StateExpire)
 	url := ah.OAuthConfig.GithubOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline)
 	return c.Redirect(url, fiber.StatusTemporaryRedirect)
 }
@@ -264,50 +694,199 @@ const (
 
 func (ah *AuthHandl) GoogleOAthCallback(c *fiber.Ctx) error {
 	ctx := c.UserContext()
-	state := c.Query("state")
-	defer func() {
-		s := &fiber.Cookie{
-			Name:     "oauth_state",
-			Value:    "",
-			HTTPOnly: true,
-			Expires:  time.Now().Add(-time.Hour),
-			MaxAge:   -1,
-			SameSite: "Lax",
+	if err := checkOAuthState(c); err != nil {
+		return err
+	}
+	profile, err := ah.googleProfile(c, ah.OAuthConfig.GoogleRedirectURL)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	loginData, err := ah.AuthServ.OAuthLogin(ctx, *profile, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	if loginData.TwoFactorToken != "" {
+		response := dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Token:             loginData.TwoFactorToken,
 		}
-		c.Cookie(s)
-	}()
-	if c.Cookies("oauth_state") != state {
-		return apierr.InvalidRequest()
+		return c.Status(fiber.StatusAccepted).JSON(response)
 	}
-	code := c.Query("code")
-	token, err := ah.OAuthConfig.GoogleOAuthConfig.Exchange(ctx, code)
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
+	response := dto.LoginResponse{
+		Id:       loginData.Id,
+		Nickname: loginData.Nickname,
+		Avatar:   loginData.Avatar,
+	}
+	return c.JSON(response)
+}
+
+func (ah *AuthHandl) GitHubOAuthCallback(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if err := checkOAuthState(c); err != nil {
+		return err
+	}
+	profile, err := ah.githubProfile(c, ah.OAuthConfig.GithubRedirectURL)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	client := ah.OAuthConfig.GoogleOAuthConfig.Client(ctx, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	loginData, err := ah.AuthServ.OAuthLogin(ctx, *profile, c.Get(fiber.HeaderUserAgent), c.IP())
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	defer resp.Body.Close()
-	oauthReq := dto.GoogleOAuthRequest{}
-	if err := json.NewDecoder(resp.Body).Decode(&oauthReq); err != nil {
+	if loginData.TwoFactorToken != "" {
+		response := dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Token:             loginData.TwoFactorToken,
+		}
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
+	response := dto.LoginResponse{
+		Id:       loginData.Id,
+		Nickname: loginData.Nickname,
+		Avatar:   loginData.Avatar,
+	}
+	return c.JSON(response)
+}
+
+// GoogleLink godoc
+// @Summary Link Google
+// @Description Start linking a Google account to the logged-in user. User will be redirected to Google and back to the link callback
+// @Tags Auth
+// @Produce json
+// @Success 307 "Redirect to Google OAuth"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/google/link [get]
+func (ah *AuthHandl) GoogleLink(c *fiber.Ctx) error {
+	state := setOAuthState(c, ah.OAuthConfig.StateExpire)
+	url := ah.OAuthConfig.GoogleOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.SetAuthURLParam("redirect_uri", ah.OAuthConfig.GoogleLinkRedirectURL))
+	return c.Redirect(url, fiber.StatusTemporaryRedirect)
+}
+
+// GitHubLink godoc
+// @Summary Link GitHub
+// @Description Start linking a GitHub account to the logged-in user. User will be redirected to GitHub and back to the link callback
+// @Tags Auth
+// @Produce json
+// @Success 307 "Redirect to GitHub OAuth"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/github/link [get]
+func (ah *AuthHandl) GitHubLink(c *fiber.Ctx) error {
+	state := setOAuthState(c, ah.OAuthConfig.StateExpire)
+	url := ah.OAuthConfig.GithubOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.SetAuthURLParam("redirect_uri", ah.OAuthConfig.GithubLinkRedirectURL))
+	return c.Redirect(url, fiber.StatusTemporaryRedirect)
+}
+
+func (ah *AuthHandl) GoogleLinkCallback(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if err := checkOAuthState(c); err != nil {
+		return err
+	}
+	profile, err := ah.googleProfile(c, ah.OAuthConfig.GoogleLinkRedirectURL)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.LinkIdentity(ctx, uid, *profile); err != nil {
 		return apierr.ToApiError(err)
 	}
+	return helpers.SuccessResponse(c)
+}
 
-	loginData, err := ah.AuthServ.OAuthLogin(ctx, oauthReq.Name, oauthReq.Picture, oauthReq.Email, oauthReq.Id, google)
+func (ah *AuthHandl) GitHubLinkCallback(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if err := checkOAuthState(c); err != nil {
+		return err
+	}
+	profile, err := ah.githubProfile(c, ah.OAuthConfig.GithubLinkRedirectURL)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	cookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    loginData.JWT,
-		HTTPOnly: true,
-		Expires:  loginData.TTL,
-		MaxAge:   int(time.Until(loginData.TTL).Seconds()),
-		SameSite: "Lax",
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.LinkIdentity(ctx, uid, *profile); err != nil {
+		return apierr.ToApiError(err)
 	}
-	c.Cookie(cookie)
+	return helpers.SuccessResponse(c)
+}
+
+// OIDCLogin godoc
+// @Summary Login via OpenID Connect
+// @Description Start login with a configured OpenID Connect provider. User will be redirected to the issuer with PKCE, then back to the provider callback
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 307 "Redirect to OpenID Connect provider"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/oidc/{provider} [get]
+func (ah *AuthHandl) OIDCLogin(c *fiber.Ctx) error {
+	return ah.oidcRedirect(c, "")
+}
+
+// OIDCLink godoc
+// @Summary Link OpenID Connect
+// @Description Start linking a configured OpenID Connect provider to the logged-in user
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 307 "Redirect to OpenID Connect provider"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/oidc/{provider}/link [get]
+func (ah *AuthHandl) OIDCLink(c *fiber.Ctx) error {
+	return ah.oidcRedirect(c, c.Locals("userId").(string))
+}
+
+// OIDCCallback godoc
+// @Summary OpenID Connect Callback
+// @Description Finishing login or account linking with a configured OpenID Connect provider
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param state query string true "State"
+// @Param code query string true "Authorization code"
+// @Success 200 {object} dto.LoginResponse "Login response"
+// @Success 202 {object} dto.TwoFactorChallengeResponse "Two factor code required"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Invalid or expired state"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 409 {object} apierr.ApiErr "Already linked"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Router /api/auth/oidc/{provider}/callback [get]
+func (ah *AuthHandl) OIDCCallback(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if err := checkOAuthState(c); err != nil {
+		return err
+	}
+	profile, linkUserId, err := ah.OIDC.Exchange(ctx, c.Params("provider"), c.Query("state"), c.Query("code"))
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	if linkUserId != "" {
+		if err := ah.AuthServ.LinkIdentity(ctx, linkUserId, *profile); err != nil {
+			return apierr.ToApiError(err)
+		}
+		return helpers.SuccessResponse(c)
+	}
+	loginData, err := ah.AuthServ.OAuthLogin(ctx, *profile, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	if loginData.TwoFactorToken != "" {
+		response := dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Token:             loginData.TwoFactorToken,
+		}
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+	helpers.SetAuthCookies(c, loginData.JWT, loginData.TTL, loginData.RefreshToken, loginData.RefreshTTL)
 	response := dto.LoginResponse{
 		Id:       loginData.Id,
 		Nickname: loginData.Nickname,
@@ -316,41 +895,145 @@ func (ah *AuthHandl) GoogleOAthCallback(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-func (ah *AuthHandl) GitHubOAuthCallback(c *fiber.Ctx) error {
+// FetchIdentities godoc
+// @Summary Fetch Identities
+// @Description Fetching OAuth providers linked to the logged-in user
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.IdentityResponse "Identities response"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/identities [get]
+func (ah *AuthHandl) FetchIdentities(c *fiber.Ctx) error {
 	ctx := c.UserContext()
-	state := c.Query("state")
-	defer func() {
-		s := &fiber.Cookie{
-			Name:     "oauth_state",
-			Value:    "",
-			HTTPOnly: true,
-			Expires:  time.Now().Add(-time.Hour),
-			MaxAge:   -1,
-			SameSite: "Lax",
-		}
-		c.Cookie(s)
-	}()
-	if c.Cookies("oauth_state") != state {
+	uid := c.Locals("userId").(string)
+	identities, err := ah.AuthServ.FetchIdentities(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(identities)
+}
+
+// UnlinkIdentity godoc
+// @Summary Unlink Identity
+// @Description Unlinking an OAuth provider from the logged-in user. The last sign-in method of an account without a password can not be unlinked
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider: google, github or a configured OpenID Connect provider"
+// @Success 200 {object} dto.SuccessResponse "Success response"
+// @Failure 400 {object} apierr.ApiErr "Bad request"
+// @Failure 401 {object} apierr.ApiErr "Unauthorized"
+// @Failure 404 {object} apierr.ApiErr "Not found"
+// @Failure 500 {object} apierr.ApiErr "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/auth/identities/{provider} [delete]
+func (ah *AuthHandl) UnlinkIdentity(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	provider := c.Params("provider")
+	if provider != google && provider != github && !ah.OIDC.Has(provider) {
 		return apierr.InvalidRequest()
 	}
-	code := c.Query("code")
-	token, err := ah.OAuthConfig.GithubOAuthConfig.Exchange(ctx, code)
+	uid := c.Locals("userId").(string)
+	if err := ah.AuthServ.UnlinkIdentity(ctx, uid, provider); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+func (ah *AuthHandl) oidcRedirect(c *fiber.Ctx, linkUserId string) error {
+	ctx := c.UserContext()
+	url, state, err := ah.OIDC.AuthCodeURL(ctx, c.Params("provider"), linkUserId)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
+	setOAuthStateCookie(c, state, ah.OIDC.StateTTL)
+	return c.Redirect(url, fiber.StatusTemporaryRedirect)
+}
+
+func setOAuthState(c *fiber.Ctx, ttl time.Duration) string {
+	state := uuid.New().String()
+	setOAuthStateCookie(c, state, ttl)
+	return state
+}
+
+func setOAuthStateCookie(c *fiber.Ctx, state string, ttl time.Duration) {
+	exp := time.Now().Add(ttl)
+	stateCookie := &fiber.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		HTTPOnly: true,
+		Expires:  exp,
+		MaxAge:   int(time.Until(exp).Seconds()),
+		SameSite: "Lax",
+	}
+	c.Cookie(stateCookie)
+}
+
+func checkOAuthState(c *fiber.Ctx) error {
+	state := c.Cookies("oauth_state")
+	s := &fiber.Cookie{
+		Name:     "oauth_state",
+		Value:    "",
+		HTTPOnly: true,
+		Expires:  time.Now().Add(-time.Hour),
+		MaxAge:   -1,
+		SameSite: "Lax",
+	}
+	c.Cookie(s)
+	if state == "" || state != c.Query("state") {
+		return apierr.InvalidRequest()
+	}
+	return nil
+}
+
+func (ah *AuthHandl) googleProfile(c *fiber.Ctx, redirectURL string) (*dto.OAuthProfile, error) {
+	ctx := c.UserContext()
+	code := c.Query("code")
+	token, err := ah.OAuthConfig.GoogleOAuthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("redirect_uri", redirectURL))
+	if err != nil {
+		return nil, err
+	}
+	client := ah.OAuthConfig.GoogleOAuthConfig.Client(ctx, token)
+	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	oauthReq := dto.GoogleOAuthRequest{}
+	if err := json.NewDecoder(resp.Body).Decode(&oauthReq); err != nil {
+		return nil, err
+	}
+	return &dto.OAuthProfile{
+		Id:            oauthReq.Id,
+		Provider:      google,
+		Nickname:      oauthReq.Name,
+		Avatar:        oauthReq.Picture,
+		Email:         oauthReq.Email,
+		VerifiedEmail: oauthReq.VerifiedEmail,
+	}, nil
+}
+
+func (ah *AuthHandl) githubProfile(c *fiber.Ctx, redirectURL string) (*dto.OAuthProfile, error) {
+	ctx := c.UserContext()
+	code := c.Query("code")
+	token, err := ah.OAuthConfig.GithubOAuthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("redirect_uri", redirectURL))
+	if err != nil {
+		return nil, err
+	}
 	client := ah.OAuthConfig.GithubOAuthConfig.Client(ctx, token)
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
-		return apierr.ToApiError(err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 	oauthReq := dto.GitHubOAuthRequest{}
 	if err := json.NewDecoder(resp.Body).Decode(&oauthReq); err != nil {
-		return apierr.ToApiError(err)
+		return nil, err
 	}
 	emailResp, err := client.Get("https://api.github.com/user/emails")
 	if err != nil {
-		return apierr.ToApiError(err)
+		return nil, err
 	}
 	defer emailResp.Body.Close()
 	var emails []struct {
@@ -359,32 +1042,22 @@ func (ah *AuthHandl) GitHubOAuthCallback(c *fiber.Ctx) error {
 		Verified bool   `json:"verified"`
 	}
 	if err := json.NewDecoder(emailResp.Body).Decode(&emails); err != nil {
-		return apierr.ToApiError(err)
+		return nil, err
 	}
+	verified := false
 	for _, e := range emails {
 		if e.Verified && e.Primary {
 			oauthReq.Email = e.Email
+			verified = true
 			break
 		}
 	}
-	pid := strconv.FormatInt(oauthReq.Id, 10)
-	loginData, err := ah.AuthServ.OAuthLogin(ctx, oauthReq.Login, oauthReq.Avatar, oauthReq.Email, pid, github)
-	if err != nil {
-		return apierr.ToApiError(err)
-	}
-	cookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    loginData.JWT,
-		HTTPOnly: true,
-		Expires:  loginData.TTL,
-		MaxAge:   int(time.Until(loginData.TTL).Seconds()),
-		SameSite: "Lax",
-	}
-	c.Cookie(cookie)
-	response := dto.LoginResponse{
-		Id:       loginData.Id,
-		Nickname: loginData.Nickname,
-		Avatar:   loginData.Avatar,
-	}
-	return c.JSON(response)
+	return &dto.OAuthProfile{
+		Id:            strconv.FormatInt(oauthReq.Id, 10),
+		Provider:      github,
+		Nickname:      oauthReq.Login,
+		Avatar:        oauthReq.Avatar,
+		Email:         oauthReq.Email,
+		VerifiedEmail: verified,
+	}, nil
 }
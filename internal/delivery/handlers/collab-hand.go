@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"readmeow/internal/config"
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/collab"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/services"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CollabHandl struct {
+	ReadmeServ services.ReadmeServ
+	Hub        *collab.Hub
+	upgrade    fiber.Handler
+}
+
+func NewCollabHandl(rs services.ReadmeServ, h *collab.Hub, cfg config.CollabConfig) *CollabHandl {
+	ch := &CollabHandl{
+		ReadmeServ: rs,
+		Hub:        h,
+	}
+	ch.upgrade = websocket.New(func(conn *websocket.Conn) {
+		readme := conn.Locals("readme").(*models.Readme)
+		uid := conn.Locals("userId").(string)
+		ch.Hub.Serve(conn, readme, uid)
+	}, websocket.Config{
+		Origins: cfg.Origins,
+	})
+	return ch
+}
+
+// Close saves open editing sessions and disconnects their editors.
+func (ch *CollabHandl) Close() {
+	ch.Hub.Close()
+}
+
+// EditReadme godoc
+// @Summary      Edit Readme Live
+// @Description  Opens a WebSocket editing session of the readme shared by all its open editors. Requires owner or editor access and a signed-in session, personal access tokens are refused. Editors who lose access are disconnected. The server sends init with the readme and its version, then ack for own ops, ops of others, presence and cursor messages. Clients send ops made against the last known version, one message at a time, and cursor moves
+// @Tags         Readmes
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Success      101 "Switching protocols"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      426 {object} apierr.ApiErr "Upgrade required"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/live [get]
+func (ch *CollabHandl) EditReadme(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	id := c.Params("readme")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	readme, err := ch.ReadmeServ.GetForEdit(ctx, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	c.Locals("readme", readme)
+	return ch.upgrade(c)
+}
@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CollectionHandl struct {
+	CollectionServ services.CollectionServ
+	Validator      *validator.Validator
+}
+
+func NewCollectionHandl(cs services.CollectionServ, v *validator.Validator) *CollectionHandl {
+	return &CollectionHandl{
+		CollectionServ: cs,
+		Validator:      v,
+	}
+}
+
+// CreateCollection godoc
+// @Summary      Create Collection
+// @Description  Creating a new collection of templates and widgets owned by current user
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.CreateCollectionRequest true "Collection creation request"
+// @Success      200 {object} dto.CollectionResponse "Created collection"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections [post]
+func (ch *CollectionHandl) CreateCollection(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	// The search shares this path and lets anonymous requests through.
+	uid, ok := c.Locals("userId").(string)
+	if !ok {
+		return apierr.Unauthorized()
+	}
+	req := dto.CreateCollectionRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	collection, err := ch.CollectionServ.Create(ctx, uid, req.Title, req.Description, req.IsPublic)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collection)
+}
+
+// SearchCollections godoc
+// @Summary      Search Collections
+// @Description  Search public collections with sorting
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.SearchCollectionRequestDoc true "Search collections request"
+// @Success      200 {array} dto.CollectionResponse "List of collections"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections [get]
+func (ch *CollectionHandl) SearchCollections(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.SearchCollectionRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	uid, _ := c.Locals("userId").(string)
+	collections, err := ch.CollectionServ.Search(ctx, uid, req.Amount, req.Page, req.Query, req.Sort)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collections)
+}
+
+// GetCollection godoc
+// @Summary      Get Collection
+// @Description  Get collection with its items in order. Private collections are only shown to their owner
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.CollectionPageResponse "Collection"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection} [get]
+func (ch *CollectionHandl) GetCollection(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	collection, err := ch.CollectionServ.Get(ctx, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collection)
+}
+
+// GetSharedCollection godoc
+// @Summary      Get Shared Collection
+// @Description  Get collection with its items through its share link, private or not
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        token path string true "Share token"
+// @Success      200 {object} dto.CollectionPageResponse "Collection"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/shared/{token} [get]
+func (ch *CollectionHandl) GetSharedCollection(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	token := c.Params("token")
+	if token == "" || len(token) > 64 {
+		return apierr.InvalidRequest()
+	}
+	uid := c.Locals("userId").(string)
+	collection, err := ch.CollectionServ.GetShared(ctx, token, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collection)
+}
+
+// FetchUserCollections godoc
+// @Summary      Fetch User Collections
+// @Description  Fetch collections of the user, recently updated first. Private ones are only listed to their owner
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.CollectionResponse "List of collections"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/user/{user} [get]
+func (ch *CollectionHandl) FetchUserCollections(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("user")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	collections, err := ch.CollectionServ.FetchByUser(ctx, id, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collections)
+}
+
+// FetchFollowedCollections godoc
+// @Summary      Fetch Followed Collections
+// @Description  Fetch public collections current user follows, recently followed first
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.CollectionResponse "List of collections"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/followed [get]
+func (ch *CollectionHandl) FetchFollowedCollections(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	collections, err := ch.CollectionServ.FetchFollowed(ctx, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collections)
+}
+
+// UpdateCollection godoc
+// @Summary      Update Collection
+// @Description  Updating collection title, description or visibility. Only the owner can update it
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Param        body body dto.UpdateCollectionRequest true "Collection update request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection} [patch]
+func (ch *CollectionHandl) UpdateCollection(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.UpdateCollectionRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.Update(ctx, id, uid, req.Title, req.Description, req.IsPublic); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// DeleteCollection godoc
+// @Summary      Delete Collection
+// @Description  Deleting collection. Only the owner can delete it. Its templates and widgets stay untouched
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection} [delete]
+func (ch *CollectionHandl) DeleteCollection(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.Delete(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// AddCollectionItem godoc
+// @Summary      Add Collection Item
+// @Description  Adding a template or a widget to the end of the collection. Only the owner can add items
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Param        body body dto.AddCollectionItemRequest true "Add item request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      409 {object} apierr.ApiErr "Already in the collection"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/items [post]
+func (ch *CollectionHandl) AddItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.AddCollectionItemRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.AddItem(ctx, id, uid, req.Type, req.ItemId); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// ReorderCollectionItems godoc
+// @Summary      Reorder Collection Items
+// @Description  Placing items of the collection in the given order. Every item has to be listed exactly once
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Param        body body dto.ReorderCollectionItemsRequest true "Reorder items request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/items [patch]
+func (ch *CollectionHandl) ReorderItems(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.ReorderCollectionItemsRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.ReorderItems(ctx, id, uid, req.ItemIds); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// RemoveCollectionItem godoc
+// @Summary      Remove Collection Item
+// @Description  Removing an item from the collection. Only the owner can remove items
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Param        item path string true "Item ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/items/{item} [delete]
+func (ch *CollectionHandl) RemoveItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	itemId := c.Params("item")
+	if err := helpers.ValidateId(c, itemId); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.RemoveItem(ctx, id, uid, itemId); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// ShareCollection godoc
+// @Summary      Share Collection
+// @Description  Turning on the share link of the collection, replacing the previous one. Anyone signed in with the link can see the collection, private or not
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.CollectionShareResponse "Share token"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/share [post]
+func (ch *CollectionHandl) Share(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	share, err := ch.CollectionServ.Share(ctx, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(share)
+}
+
+// UnshareCollection godoc
+// @Summary      Unshare Collection
+// @Description  Turning off the share link of the collection
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/share [delete]
+func (ch *CollectionHandl) Unshare(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.Unshare(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// FollowCollection godoc
+// @Summary      Follow Collection
+// @Description  Follow a public collection of another user
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/follow [post]
+func (ch *CollectionHandl) Follow(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.Follow(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// UnfollowCollection godoc
+// @Summary      Unfollow Collection
+// @Description  Stop following the collection
+// @Tags         Collections
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        collection path string true "Collection ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/collections/{collection}/follow [delete]
+func (ch *CollectionHandl) Unfollow(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("collection")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := ch.CollectionServ.Unfollow(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CommentHandl struct {
+	CommentServ services.CommentServ
+	Validator   *validator.Validator
+}
+
+func NewCommentHandl(cs services.CommentServ, v *validator.Validator) *CommentHandl {
+	return &CommentHandl{
+		CommentServ: cs,
+		Validator:   v,
+	}
+}
+
+// FetchReadmeComments godoc
+// @Summary      Fetch Readme Comments
+// @Description  Returns review threads anchored to blocks of the readme with their replies, oldest first. Requires access to the readme
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        body query dto.FetchCommentsRequest true "Fetch comments request"
+// @Success      200 {array} dto.CommentThreadResponse "Comment threads"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/comments [get]
+func (ch *CommentHandl) FetchReadmeComments(c *fiber.Ctx) error {
+	return ch.fetch(c, models.CommentTargetReadme, "readme")
+}
+
+// CreateReadmeComment godoc
+// @Summary      Create Readme Comment
+// @Description  Starts a thread anchored to a block of the readme, or replies to a thread when parent_id is set. Mentioned @handles who can see the thread are notified. Requires access to the readme
+// @Tags         Readmes
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        body body dto.CreateCommentRequest true "Create comment request"
+// @Success      200 {object} dto.CommentResponse "Created comment"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/comments [post]
+func (ch *CommentHandl) CreateReadmeComment(c *fiber.Ctx) error {
+	return ch.create(c, models.CommentTargetReadme, "readme")
+}
+
+// ResolveReadmeComment godoc
+// @Summary      Resolve Readme Comment Thread
+// @Description  Marks a review thread of the readme as resolved. Allowed to editors and the thread author
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/comments/{comment}/resolve [patch]
+func (ch *CommentHandl) ResolveReadmeComment(c *fiber.Ctx) error {
+	return ch.setResolved(c, models.CommentTargetReadme, "readme", true)
+}
+
+// UnresolveReadmeComment godoc
+// @Summary      Unresolve Readme Comment Thread
+// @Description  Reopens a resolved review thread of the readme. Allowed to editors and the thread author
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/comments/{comment}/unresolve [patch]
+func (ch *CommentHandl) UnresolveReadmeComment(c *fiber.Ctx) error {
+	return ch.setResolved(c, models.CommentTargetReadme, "readme", false)
+}
+
+// DeleteReadmeComment godoc
+// @Summary      Delete Readme Comment
+// @Description  Deletes a comment of the readme with its replies. Authors delete their own comments, those who manage the readme any of its comments, moderators and admins anything
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/comments/{comment} [delete]
+func (ch *CommentHandl) DeleteReadmeComment(c *fiber.Ctx) error {
+	return ch.delete(c, models.CommentTargetReadme, "readme")
+}
+
+// FetchTemplateComments godoc
+// @Summary      Fetch Template Comments
+// @Description  Returns review threads anchored to blocks of the template with their replies, oldest first. Set discussion to list general discussion threads instead of review ones. Review threads require owning the template or membership in its organization, discussion threads a public template
+// @Tags         Templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        body query dto.FetchCommentsRequest true "Fetch comments request"
+// @Success      200 {array} dto.CommentThreadResponse "Comment threads"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/comments [get]
+func (ch *CommentHandl) FetchTemplateComments(c *fiber.Ctx) error {
+	return ch.fetch(c, models.CommentTargetTemplate, "template")
+}
+
+// CreateTemplateComment godoc
+// @Summary      Create Template Comment
+// @Description  Starts a thread anchored to a block of the template, or replies to a thread when parent_id is set. Leaving the anchor out starts a discussion thread on a public template. Mentioned @handles who can see the thread are notified. Review threads require owning the template or membership in its organization, discussion threads a public template
+// @Tags         Templates
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        body body dto.CreateCommentRequest true "Create comment request"
+// @Success      200 {object} dto.CommentResponse "Created comment"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/comments [post]
+func (ch *CommentHandl) CreateTemplateComment(c *fiber.Ctx) error {
+	return ch.create(c, models.CommentTargetTemplate, "template")
+}
+
+// ResolveTemplateComment godoc
+// @Summary      Resolve Template Comment Thread
+// @Description  Marks a review thread of the template as resolved. Allowed to the owner, organization editors and the thread author
+// @Tags         Templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/comments/{comment}/resolve [patch]
+func (ch *CommentHandl) ResolveTemplateComment(c *fiber.Ctx) error {
+	return ch.setResolved(c, models.CommentTargetTemplate, "template", true)
+}
+
+// UnresolveTemplateComment godoc
+// @Summary      Unresolve Template Comment Thread
+// @Description  Reopens a resolved review thread of the template. Allowed to the owner, organization editors and the thread author
+// @Tags         Templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/comments/{comment}/unresolve [patch]
+func (ch *CommentHandl) UnresolveTemplateComment(c *fiber.Ctx) error {
+	return ch.setResolved(c, models.CommentTargetTemplate, "template", false)
+}
+
+// DeleteTemplateComment godoc
+// @Summary      Delete Template Comment
+// @Description  Deletes a comment of the template with its replies. Authors delete their own comments, those who manage the template any of its comments, moderators and admins anything
+// @Tags         Templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        comment path string true "Comment ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/comments/{comment} [delete]
+func (ch *CommentHandl) DeleteTemplateComment(c *fiber.Ctx) error {
+	return ch.delete(c, models.CommentTargetTemplate, "template")
+}
+
+func (ch *CommentHandl) fetch(c *fiber.Ctx, target, param string) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	req := dto.FetchCommentsRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	threads, err := ch.CommentServ.Fetch(ctx, target, targetId, uid, req.Discussion, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(threads)
+}
+
+func (ch *CommentHandl) create(c *fiber.Ctx, target, param string) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	req := dto.CreateCommentRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, ch.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	comment, err := ch.CommentServ.Create(ctx, target, targetId, uid, req.ParentId, req.AnchorField, req.AnchorIndex, req.Body)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(comment)
+}
+
+func (ch *CommentHandl) setResolved(c *fiber.Ctx, target, param string, resolved bool) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	id := c.Params("comment")
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	var err error
+	if resolved {
+		err = ch.CommentServ.Resolve(ctx, target, targetId, id, uid)
+	} else {
+		err = ch.CommentServ.Unresolve(ctx, target, targetId, id, uid)
+	}
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+func (ch *CommentHandl) delete(c *fiber.Ctx, target, param string) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	id := c.Params("comment")
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	role := c.Locals("role").(string)
+	if err := ch.CommentServ.Delete(ctx, target, targetId, id, uid, role); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
@@ -1,9 +1,12 @@
 package helpers
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"readmeow/internal/delivery/apierr"
 	"readmeow/internal/dto"
 	"readmeow/pkg/validator"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -49,3 +52,71 @@ func SuccessResponse(c *fiber.Ctx) error {
 		Message: "success",
 	})
 }
+
+const (
+	AccessCookie  = "jwt"
+	RefreshCookie = "refresh_token"
+	CSRFCookie    = "csrf_token"
+	CSRFHeader    = "X-CSRF-Token"
+	refreshPath   = "/api/auth"
+)
+
+func SetAuthCookies(c *fiber.Ctx, jwt string, ttl time.Time, refresh string, refreshTTL time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     AccessCookie,
+		Value:    jwt,
+		HTTPOnly: true,
+		Expires:  ttl,
+		MaxAge:   int(time.Until(ttl).Seconds()),
+		Path:     "/",
+		SameSite: "Lax",
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     RefreshCookie,
+		Value:    refresh,
+		HTTPOnly: true,
+		Expires:  refreshTTL,
+		MaxAge:   int(time.Until(refreshTTL).Seconds()),
+		Path:     refreshPath,
+		SameSite: "Lax",
+	})
+}
+
+func ClearAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     AccessCookie,
+		Value:    "",
+		HTTPOnly: true,
+		Expires:  time.Now().Add(-time.Hour),
+		MaxAge:   -1,
+		Path:     "/",
+		SameSite: "Lax",
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     RefreshCookie,
+		Value:    "",
+		HTTPOnly: true,
+		Expires:  time.Now().Add(-time.Hour),
+		MaxAge:   -1,
+		Path:     refreshPath,
+		SameSite: "Lax",
+	})
+}
+
+// SetCSRFToken issues a new double-submit token: the browser keeps it in a
+// cookie and the client echoes it back in the CSRFHeader on every mutation.
+func SetCSRFToken(c *fiber.Ctx) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	c.Cookie(&fiber.Cookie{
+		Name:     CSRFCookie,
+		Value:    token,
+		HTTPOnly: true,
+		Path:     "/",
+		SameSite: "Lax",
+	})
+	return token, nil
+}
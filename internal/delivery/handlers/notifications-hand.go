@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"readmeow/internal/config"
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type NotificationHandl struct {
+	NotificationServ services.NotificationServ
+	Validator        *validator.Validator
+	Config           config.NotificationsConfig
+	done             chan struct{}
+	closeOnce        sync.Once
+}
+
+func NewNotificationHandl(ns services.NotificationServ, v *validator.Validator, cfg config.NotificationsConfig) *NotificationHandl {
+	return &NotificationHandl{
+		NotificationServ: ns,
+		Validator:        v,
+		Config:           cfg,
+		done:             make(chan struct{}),
+	}
+}
+
+// Close ends every open notification stream so the server can shut down
+// without waiting for them to time out.
+func (nh *NotificationHandl) Close() {
+	nh.closeOnce.Do(func() {
+		close(nh.done)
+	})
+}
+
+// FetchNotifications godoc
+// @Summary      Fetch Notifications
+// @Description  Fetch notifications of current user, newest first
+// @Tags         Notifications
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.NotificationResponse "Notifications"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications [get]
+func (nh *NotificationHandl) FetchNotifications(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, nh.Validator); err != nil {
+		return err
+	}
+	notifications, err := nh.NotificationServ.Fetch(ctx, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(notifications)
+}
+
+// UnreadCount godoc
+// @Summary      Unread Notifications Count
+// @Description  Get number of unread notifications of current user
+// @Tags         Notifications
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {object} dto.UnreadCountResponse "Unread count"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/unread [get]
+func (nh *NotificationHandl) UnreadCount(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	res, err := nh.NotificationServ.UnreadCount(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(res)
+}
+
+// MarkRead godoc
+// @Summary      Mark Notification Read
+// @Description  Mark notification of current user as read
+// @Tags         Notifications
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        notification path string true "Notification ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/{notification}/read [patch]
+func (nh *NotificationHandl) MarkRead(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("notification")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := nh.NotificationServ.MarkRead(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// MarkAllRead godoc
+// @Summary      Mark All Notifications Read
+// @Description  Mark every notification of current user as read
+// @Tags         Notifications
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/read [patch]
+func (nh *NotificationHandl) MarkAllRead(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	if err := nh.NotificationServ.MarkAllRead(ctx, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// FetchPreferences godoc
+// @Summary      Fetch Notification Preferences
+// @Description  Get which notification types current user receives
+// @Tags         Notifications
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {object} dto.NotificationPreferencesResponse "Notification preferences"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/preferences [get]
+func (nh *NotificationHandl) FetchPreferences(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	res, err := nh.NotificationServ.FetchPreferences(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(res)
+}
+
+// UpdatePreferences godoc
+// @Summary      Update Notification Preferences
+// @Description  Turn notification types on or off for current user
+// @Tags         Notifications
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.UpdateNotificationPreferencesRequest true "Notification preferences"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/preferences [patch]
+func (nh *NotificationHandl) UpdatePreferences(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.UpdateNotificationPreferencesRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, nh.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := nh.NotificationServ.UpdatePreferences(ctx, uid, req.Preferences); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// Stream godoc
+// @Summary      Notifications Stream
+// @Description  Server-sent events stream of new notifications of current user. On reconnect the Last-Event-ID header replays the missed ones
+// @Tags         Notifications
+// @Produce      text/event-stream
+// @Security     ApiKeyAuth
+// @Param        Last-Event-ID header string false "ID of the last received notification"
+// @Success      200 {object} dto.NotificationResponse "Stream of notification events"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/notifications/stream [get]
+func (nh *NotificationHandl) Stream(c *fiber.Ctx) error {
+	uid := c.Locals("userId").(string)
+	// The request context is cancelled once the handler returns, long before
+	// the stream ends, so the subscription gets its own one.
+	ctx, cancel := context.WithTimeout(context.Background(), nh.Config.StreamTimeout)
+	messages, unsubscribe := nh.NotificationServ.Subscribe(ctx, uid)
+	missed := []dto.NotificationResponse{}
+	if lastId := c.Get("Last-Event-ID"); lastId != "" {
+		if _, err := uuid.Parse(lastId); err == nil {
+			res, err := nh.NotificationServ.FetchMissed(c.UserContext(), uid, lastId)
+			if err != nil {
+				unsubscribe()
+				cancel()
+				return apierr.ToApiError(err)
+			}
+			missed = res
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer unsubscribe()
+		for _, n := range missed {
+			data, err := json.Marshal(n)
+			if err != nil {
+				return
+			}
+			writeNotificationEvent(w, n.Id, data)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+		heartbeat := time.NewTicker(nh.Config.Heartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-nh.done:
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				n := dto.NotificationResponse{}
+				if err := json.Unmarshal([]byte(msg), &n); err != nil {
+					continue
+				}
+				writeNotificationEvent(w, n.Id, []byte(msg))
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeNotificationEvent(w *bufio.Writer, id string, data []byte) {
+	fmt.Fprintf(w, "id: %s\nevent: notification\ndata: %s\n\n", id, data)
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrganizationHandl struct {
+	OrganizationServ services.OrganizationServ
+	Validator        *validator.Validator
+}
+
+func NewOrganizationHandl(ors services.OrganizationServ, v *validator.Validator) *OrganizationHandl {
+	return &OrganizationHandl{
+		OrganizationServ: ors,
+		Validator:        v,
+	}
+}
+
+// CreateOrganization godoc
+// @Summary      Create Organization
+// @Description  Creating a new organization owned by current user
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.CreateOrganizationRequest true "Organization creation request"
+// @Success      200 {object} dto.OrganizationResponse "Created organization"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs [post]
+func (oh *OrganizationHandl) CreateOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	req := dto.CreateOrganizationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, oh.Validator); err != nil {
+		return err
+	}
+	org, err := oh.OrganizationServ.Create(ctx, uid, req.Name, req.Description)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(org)
+}
+
+// FetchOrganizations godoc
+// @Summary      Fetch Organizations
+// @Description  Fetch organizations current user is a member of
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200 {array} dto.OrganizationResponse "Organizations"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs [get]
+func (oh *OrganizationHandl) FetchOrganizations(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	orgs, err := oh.OrganizationServ.FetchByUser(ctx, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(orgs)
+}
+
+// GetOrganization godoc
+// @Summary      Get Organization
+// @Description  Get organization by ID. Only members can see it
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Success      200 {object} dto.OrganizationResponse "Organization"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org} [get]
+func (oh *OrganizationHandl) GetOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	org, err := oh.OrganizationServ.Get(ctx, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(org)
+}
+
+// UpdateOrganization godoc
+// @Summary      Update Organization
+// @Description  Updating organization name or description. Requires the admin role
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        body body dto.UpdateOrganizationRequest true "Organization update request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org} [patch]
+func (oh *OrganizationHandl) UpdateOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.UpdateOrganizationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, oh.Validator); err != nil {
+		return err
+	}
+	if err := oh.OrganizationServ.Update(ctx, id, uid, req.Name, req.Description); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// DeleteOrganization godoc
+// @Summary      Delete Organization
+// @Description  Deleting organization. Requires the owner role. Its templates and readmes stay with their creators
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org} [delete]
+func (oh *OrganizationHandl) DeleteOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := oh.OrganizationServ.Delete(ctx, id, uid); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// FetchMembers godoc
+// @Summary      Fetch Organization Members
+// @Description  Fetch members of the organization in order of joining
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.OrganizationMemberResponse "Members"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/members [get]
+func (oh *OrganizationHandl) FetchMembers(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, oh.Validator); err != nil {
+		return err
+	}
+	members, err := oh.OrganizationServ.FetchMembers(ctx, id, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(members)
+}
+
+// AddMember godoc
+// @Summary      Add Organization Member
+// @Description  Adding a user to the organization. Requires the admin role; the new role can not exceed the caller's one
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        body body dto.AddOrganizationMemberRequest true "Add member request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      409 {object} apierr.ApiErr "Already a member"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/members [post]
+func (oh *OrganizationHandl) AddMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.AddOrganizationMemberRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, oh.Validator); err != nil {
+		return err
+	}
+	if err := oh.OrganizationServ.AddMember(ctx, id, uid, req.UserId, req.Role); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// UpdateMember godoc
+// @Summary      Update Organization Member
+// @Description  Changing role of a member. Requires the admin role; the last owner can not be demoted
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        user path string true "User ID"
+// @Param        body body dto.UpdateOrganizationMemberRequest true "Update member request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/members/{user} [patch]
+func (oh *OrganizationHandl) UpdateMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	memberId := c.Params("user")
+	if err := helpers.ValidateId(c, memberId); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.UpdateOrganizationMemberRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, oh.Validator); err != nil {
+		return err
+	}
+	if err := oh.OrganizationServ.UpdateMember(ctx, id, uid, memberId, req.Role); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// RemoveMember godoc
+// @Summary      Remove Organization Member
+// @Description  Removing a member from the organization. Members can leave on their own, others require the admin role
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        user path string true "User ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/members/{user} [delete]
+func (oh *OrganizationHandl) RemoveMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	memberId := c.Params("user")
+	if err := helpers.ValidateId(c, memberId); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := oh.OrganizationServ.RemoveMember(ctx, id, uid, memberId); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// FetchTemplates godoc
+// @Summary      Fetch Organization Templates
+// @Description  Fetch public and private templates of the organization, recently updated first
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.TemplateResponse "Templates"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/templates [get]
+func (oh *OrganizationHandl) FetchTemplates(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, oh.Validator); err != nil {
+		return err
+	}
+	templates, err := oh.OrganizationServ.FetchTemplates(ctx, id, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(templates)
+}
+
+// FetchReadmes godoc
+// @Summary      Fetch Organization Readmes
+// @Description  Fetch readmes of the organization, recently updated first
+// @Tags         Organizations
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        org path string true "Organization ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.ReadmeResponse "Readmes"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/orgs/{org}/readmes [get]
+func (oh *OrganizationHandl) FetchReadmes(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("org")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, oh.Validator); err != nil {
+		return err
+	}
+	readmes, err := oh.OrganizationServ.FetchReadmes(ctx, id, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(readmes)
+}
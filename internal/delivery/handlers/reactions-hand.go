@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/dto"
+	"readmeow/pkg/validator"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReactionHandl struct {
+	ReactionServ services.ReactionServ
+	Validator    *validator.Validator
+}
+
+func NewReactionHandl(rs services.ReactionServ, v *validator.Validator) *ReactionHandl {
+	return &ReactionHandl{
+		ReactionServ: rs,
+		Validator:    v,
+	}
+}
+
+// ReactTemplate godoc
+// @Summary      React To Template
+// @Description  Leaves a reaction on the template. A user may leave several different reactions, repeating one is not an error. The owner is notified about new likes
+// @Tags         Templates
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        body body dto.ReactRequest true "React request"
+// @Success      200 {object} dto.ReactionsResponse "Reaction counts"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/reactions [post]
+func (rh *ReactionHandl) ReactTemplate(c *fiber.Ctx) error {
+	return rh.react(c, models.ReactionTargetTemplate, "template")
+}
+
+// UnreactTemplate godoc
+// @Summary      Remove Template Reaction
+// @Description  Removes a reaction the user left on the template
+// @Tags         Templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
+// @Param        reaction path string true "Reaction" Enums(like, love, laugh, hooray, rocket, eyes)
+// @Success      200 {object} dto.ReactionsResponse "Reaction counts"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/{template}/reactions/{reaction} [delete]
+func (rh *ReactionHandl) UnreactTemplate(c *fiber.Ctx) error {
+	return rh.unreact(c, models.ReactionTargetTemplate, "template")
+}
+
+// ReactWidget godoc
+// @Summary      React To Widget
+// @Description  Leaves a reaction on the widget. A user may leave several different reactions, repeating one is not an error
+// @Tags         Widgets
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        widget path string true "Widget ID"
+// @Param        body body dto.ReactRequest true "React request"
+// @Success      200 {object} dto.ReactionsResponse "Reaction counts"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/widgets/{widget}/reactions [post]
+func (rh *ReactionHandl) ReactWidget(c *fiber.Ctx) error {
+	return rh.react(c, models.ReactionTargetWidget, "widget")
+}
+
+// UnreactWidget godoc
+// @Summary      Remove Widget Reaction
+// @Description  Removes a reaction the user left on the widget
+// @Tags         Widgets
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        widget path string true "Widget ID"
+// @Param        reaction path string true "Reaction" Enums(like, love, laugh, hooray, rocket, eyes)
+// @Success      200 {object} dto.ReactionsResponse "Reaction counts"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/widgets/{widget}/reactions/{reaction} [delete]
+func (rh *ReactionHandl) UnreactWidget(c *fiber.Ctx) error {
+	return rh.unreact(c, models.ReactionTargetWidget, "widget")
+}
+
+func (rh *ReactionHandl) react(c *fiber.Ctx, target, param string) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	req := dto.ReactRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, rh.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	reactions, err := rh.ReactionServ.React(ctx, target, targetId, uid, req.Reaction)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(reactions)
+}
+
+func (rh *ReactionHandl) unreact(c *fiber.Ctx, target, param string) error {
+	ctx := c.UserContext()
+	targetId := c.Params(param)
+	if err := helpers.ValidateId(c, targetId); err != nil {
+		return err
+	}
+	reaction := c.Params("reaction")
+	if !slices.Contains(models.Reactions, reaction) {
+		return apierr.InvalidRequest()
+	}
+	uid := c.Locals("userId").(string)
+	reactions, err := rh.ReactionServ.Unreact(ctx, target, targetId, uid, reaction)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(reactions)
+}
@@ -47,6 +47,7 @@ func (rh *ReadmeHandl) CreateReadme(c *fiber.Ctx) error {
 	uid := c.Locals("userId").(string)
 
 	req.TemplateId = c.FormValue("template_id")
+	req.OrgId = c.FormValue("org_id")
 
 	req.Title = c.FormValue("title")
 	form, err := c.MultipartForm()
@@ -81,7 +82,7 @@ func (rh *ReadmeHandl) CreateReadme(c *fiber.Ctx) error {
 		return apierr.ValidationError(errs)
 	}
 
-	if err := rh.ReadmeServ.Create(ctx, req.TemplateId, uid, req.Title, req.Image, req.Text, req.Links, req.RenderOrder, req.Widgets); err != nil {
+	if err := rh.ReadmeServ.Create(ctx, req.TemplateId, uid, req.OrgId, req.Title, req.Image, req.Text, req.Links, req.RenderOrder, req.Widgets); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
@@ -89,13 +90,14 @@ func (rh *ReadmeHandl) CreateReadme(c *fiber.Ctx) error {
 
 // DeleteReadme godoc
 // @Summary      Delete Readme
-// @Description  Deleting a user readme by its id
+// @Description  Deleting a user readme by its id. Collaborators can not delete readmes
 // @Tags         Readmes
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        readme path string true "Readme ID"
 // @Success      200 {object} dto.SuccessResponse "Success response"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
 // @Failure      404 {object} apierr.ApiErr "Not found"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
 // @Router       /api/readmes/{readme} [delete]
@@ -115,7 +117,7 @@ func (rh *ReadmeHandl) DeleteReadme(c *fiber.Ctx) error {
 
 // UpdateReadme godoc
 // @Summary      Update Readme
-// @Description  Updating existing readme by id
+// @Description  Updating existing readme by id. Requires owner or editor access
 // @Tags         Readmes
 // @Accept       multipart/form-data
 // @Produce      json
@@ -123,6 +125,7 @@ func (rh *ReadmeHandl) DeleteReadme(c *fiber.Ctx) error {
 // @Param        data formData dto.UpdateReadmeRequestDoc true "Readme update request"
 // @Success      200 {object} dto.SuccessResponse "Success response"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
 // @Failure      404 {object} apierr.ApiErr "Not found"
 // @Failure      422 {object} apierr.ApiErr "Invalid JSON"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
@@ -167,7 +170,8 @@ func (rh *ReadmeHandl) UpdateReadme(c *fiber.Ctx) error {
 	if errs := rh.Validator.ValidateStruct(req); len(errs) > 0 {
 		return apierr.ValidationError(errs)
 	}
-	if err := rh.ReadmeServ.Update(ctx, req.Updates, req.Id); err != nil {
+	uid := c.Locals("userId").(string)
+	if err := rh.ReadmeServ.Update(ctx, req.Updates, req.Id, uid); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
@@ -175,7 +179,7 @@ func (rh *ReadmeHandl) UpdateReadme(c *fiber.Ctx) error {
 
 // GetReadmeById godoc
 // @Summary      Get Readme by ID
-// @Description  Returns single readme by id
+// @Description  Returns single readme by id if current user has access to it
 // @Tags         Readmes
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -191,7 +195,8 @@ func (rh *ReadmeHandl) GetReadmeById(c *fiber.Ctx) error {
 	if err := helpers.ValidateId(c, id); err != nil {
 		return err
 	}
-	readme, err := rh.ReadmeServ.Get(ctx, id)
+	uid := c.Locals("userId").(string)
+	readme, err := rh.ReadmeServ.Get(ctx, id, uid)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
@@ -222,3 +227,157 @@ func (rh *ReadmeHandl) FetchReadmesByUser(c *fiber.Ctx) error {
 	}
 	return c.JSON(readmes)
 }
+
+// FetchSharedReadmes godoc
+// @Summary      Fetch Shared Readmes
+// @Description  Returns list of readmes other users shared with the authorized user, most recently shared first
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.SharedReadmeResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/shared [get]
+func (rh *ReadmeHandl) FetchSharedReadmes(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, rh.Validator); err != nil {
+		return err
+	}
+	readmes, err := rh.ReadmeServ.FetchShared(ctx, req.Amount, req.Page, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(readmes)
+}
+
+// FetchCollaborators godoc
+// @Summary      Fetch Readme Collaborators
+// @Description  Returns users the readme is shared with
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Success      200 {array} dto.CollaboratorResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/collaborators [get]
+func (rh *ReadmeHandl) FetchCollaborators(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("readme")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	collaborators, err := rh.ReadmeServ.FetchCollaborators(ctx, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(collaborators)
+}
+
+// AddCollaborator godoc
+// @Summary      Add Readme Collaborator
+// @Description  Sharing the readme with a user found by email or handle. Only the readme owner can share it
+// @Tags         Readmes
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        body body dto.AddCollaboratorRequest true "Add collaborator request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      409 {object} apierr.ApiErr "Already shared"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/collaborators [post]
+func (rh *ReadmeHandl) AddCollaborator(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("readme")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.AddCollaboratorRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, rh.Validator); err != nil {
+		return err
+	}
+	if err := rh.ReadmeServ.AddCollaborator(ctx, id, uid, req.Email, req.Handle, req.Access); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// UpdateCollaborator godoc
+// @Summary      Update Readme Collaborator
+// @Description  Changing access level of a readme collaborator. Only the readme owner can change it
+// @Tags         Readmes
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        user path string true "User ID"
+// @Param        body body dto.UpdateCollaboratorRequest true "Update collaborator request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/collaborators/{user} [patch]
+func (rh *ReadmeHandl) UpdateCollaborator(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("readme")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	collaboratorId := c.Params("user")
+	if err := helpers.ValidateId(c, collaboratorId); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	req := dto.UpdateCollaboratorRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, rh.Validator); err != nil {
+		return err
+	}
+	if err := rh.ReadmeServ.UpdateCollaborator(ctx, id, uid, collaboratorId, req.Access); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// RemoveCollaborator godoc
+// @Summary      Remove Readme Collaborator
+// @Description  Stop sharing the readme with a user. Collaborators can remove themselves
+// @Tags         Readmes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        readme path string true "Readme ID"
+// @Param        user path string true "User ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/readmes/{readme}/collaborators/{user} [delete]
+func (rh *ReadmeHandl) RemoveCollaborator(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("readme")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	collaboratorId := c.Params("user")
+	if err := helpers.ValidateId(c, collaboratorId); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := rh.ReadmeServ.RemoveCollaborator(ctx, id, uid, collaboratorId); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
@@ -55,6 +55,7 @@ func (th *TemplateHandl) CreateTemplate(c *fiber.Ctx) error {
 
 	req.Title = c.FormValue("title")
 	req.Description = c.FormValue("description")
+	req.OrgId = c.FormValue("org_id")
 	form, err := c.MultipartForm()
 	if err != nil {
 		return apierr.InvalidRequest()
@@ -87,7 +88,7 @@ func (th *TemplateHandl) CreateTemplate(c *fiber.Ctx) error {
 	if errs := th.Validator.ValidateStruct(req); len(errs) > 0 {
 		return apierr.ValidationError(errs)
 	}
-	if err := th.TemplateServ.Create(ctx, oid, req.Title, req.Description, req.Image, req.Links, req.RenderOrder, req.Text, req.Widgets, req.IsPublic); err != nil {
+	if err := th.TemplateServ.Create(ctx, oid, req.OrgId, req.Title, req.Description, req.Image, req.Links, req.RenderOrder, req.Text, req.Widgets, req.IsPublic); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
@@ -95,13 +96,14 @@ func (th *TemplateHandl) CreateTemplate(c *fiber.Ctx) error {
 
 // UpdateTemplate godoc
 // @Summary      Update Template
-// @Description  Updating a user template
+// @Description  Updating a user template. Organization templates can be updated by their authors among the editors and by admins of the organization
 // @Tags         Templates
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        data formData dto.UpdateTemplateRequestDoc true "Update template request"
 // @Success      200 {object} dto.SuccessResponse "Success response"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
 // @Failure      404 {object} apierr.ApiErr "Not found"
 // @Failure      422 {object} apierr.ApiErr "Invalid JSON"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
@@ -161,7 +163,8 @@ func (th *TemplateHandl) UpdateTemplate(c *fiber.Ctx) error {
 		return apierr.ValidationError(errs)
 	}
 
-	if err := th.TemplateServ.Update(ctx, req.Updates, req.Id); err != nil {
+	uid := c.Locals("userId").(string)
+	if err := th.TemplateServ.Update(ctx, req.Updates, req.Id, uid); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
@@ -169,7 +172,7 @@ func (th *TemplateHandl) UpdateTemplate(c *fiber.Ctx) error {
 
 // DeleteTemplate godoc
 // @Summary      Delete Template
-// @Description  Deleting a user template by its id
+// @Description  Deleting a user template by its id. Moderators and admins can delete any template
 // @Tags         Templates
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -183,10 +186,11 @@ func (th *TemplateHandl) DeleteTemplate(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 	id := c.Params("template")
 	uid := c.Locals("userId").(string)
+	role := c.Locals("role").(string)
 	if err := helpers.ValidateId(c, id); err != nil {
 		return err
 	}
-	if err := th.TemplateServ.Delete(ctx, id, uid); err != nil {
+	if err := th.TemplateServ.Delete(ctx, id, uid, role); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
@@ -194,12 +198,12 @@ func (th *TemplateHandl) DeleteTemplate(c *fiber.Ctx) error {
 
 // GetTemplate godoc
 // @Summary      Get Template
-// @Description  Get template by ID
+// @Description  Get template by ID. Public templates come with the first threads of their general discussion
 // @Tags         Templates
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        template path string true "Template ID"
-// @Success      200 {object} models.TemplateWithOwner "Template data"
+// @Success      200 {object} dto.TemplatePageResponse "Template data"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
 // @Failure      404 {object} apierr.ApiErr "Not found"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
@@ -207,10 +211,11 @@ func (th *TemplateHandl) DeleteTemplate(c *fiber.Ctx) error {
 func (th *TemplateHandl) GetTemplate(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 	id := c.Params("template")
+	uid := c.Locals("userId").(string)
 	if err := helpers.ValidateId(c, id); err != nil {
 		return err
 	}
-	template, err := th.TemplateServ.Get(ctx, id)
+	template, err := th.TemplateServ.Get(ctx, id, uid)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
@@ -219,7 +224,7 @@ func (th *TemplateHandl) GetTemplate(c *fiber.Ctx) error {
 
 // SearchTemplates godoc
 // @Summary      Search Templates
-// @Description  Search templates with filters and sorting
+// @Description  Search templates with filters and sorting, also by rating. Signed-in users also find private templates of their organizations
 // @Tags         Templates
 // @Accept       json
 // @Produce      json
@@ -236,42 +241,78 @@ func (th *TemplateHandl) SearchTemplate(c *fiber.Ctx) error {
 	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, th.Validator); err != nil {
 		return err
 	}
-	templates, err := th.TemplateServ.Search(ctx, req.Amount, req.Page, req.Query, req.Filter, req.Sort)
+	uid, _ := c.Locals("userId").(string)
+	templates, err := th.TemplateServ.Search(ctx, uid, req.Amount, req.Page, req.Query, req.OrgId, req.Filter, req.MinRating, req.Sort)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(templates)
+}
+
+// FetchFavoriteTemplates godoc
+// @Summary      Fetch Favorite Templates
+// @Description  Fetch favorite templates of current user
+// @Tags         Templates
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.TemplateResponse "List of favorite templates"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/templates/favorite [get]
+func (th *TemplateHandl) FetchFavoriteTemplates(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, th.Validator); err != nil {
+		return err
+	}
+	id := c.Locals("userId").(string)
+	templates, err := th.TemplateServ.FetchFavorite(ctx, id, req.Amount, req.Page)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
 	return c.JSON(templates)
 }
 
-// LikeTemplate godoc
-// @Summary      Like Template
-// @Description  Like template by ID
+// RateTemplate godoc
+// @Summary      Rate Template
+// @Description  Rate a public template from 1 to 5 stars with an optional review. Rating again replaces the previous rating. Owners cannot rate their own templates
 // @Tags         Templates
+// @Accept       json
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        template path string true "Template ID"
+// @Param        body body dto.RateTemplateRequest true "Rate template request"
 // @Success      200 {object} dto.SuccessResponse "Success response"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
 // @Failure      404 {object} apierr.ApiErr "Not found"
-// @Failure      409 {object} apierr.ApiErr "Already liked"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
-// @Router       /api/templates/like/{template} [patch]
-func (th *TemplateHandl) Like(c *fiber.Ctx) error {
+// @Router       /api/templates/{template}/ratings [post]
+func (th *TemplateHandl) Rate(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 	id := c.Params("template")
 	if err := helpers.ValidateId(c, id); err != nil {
 		return err
 	}
+	req := dto.RateTemplateRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, th.Validator); err != nil {
+		return err
+	}
 	uid := c.Locals("userId").(string)
-	if err := th.TemplateServ.Like(ctx, id, uid); err != nil {
+	if err := th.TemplateServ.Rate(ctx, id, uid, req.Stars, req.Review); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
 }
 
-// DislikeTemplate godoc
-// @Summary      Dislike Template
-// @Description  Dislike template by ID
+// DeleteTemplateRating godoc
+// @Summary      Delete Template Rating
+// @Description  Delete the rating of the current user from the template
 // @Tags         Templates
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -279,46 +320,50 @@ func (th *TemplateHandl) Like(c *fiber.Ctx) error {
 // @Success      200 {object} dto.SuccessResponse "Success response"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
 // @Failure      404 {object} apierr.ApiErr "Not found"
-// @Failure      409 {object} apierr.ApiErr "Already disliked"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
-// @Router       /api/templates/dislike/{template} [patch]
-func (th *TemplateHandl) Dislike(c *fiber.Ctx) error {
+// @Router       /api/templates/{template}/ratings [delete]
+func (th *TemplateHandl) DeleteRating(c *fiber.Ctx) error {
 	ctx := c.UserContext()
 	id := c.Params("template")
 	if err := helpers.ValidateId(c, id); err != nil {
 		return err
 	}
 	uid := c.Locals("userId").(string)
-	if err := th.TemplateServ.Dislike(ctx, id, uid); err != nil {
+	if err := th.TemplateServ.DeleteRating(ctx, id, uid); err != nil {
 		return apierr.ToApiError(err)
 	}
 	return helpers.SuccessResponse(c)
 }
 
-// FetchFavoriteTemplates godoc
-// @Summary      Fetch Favorite Templates
-// @Description  Fetch favorite templates of current user
+// FetchTemplateRatings godoc
+// @Summary      Fetch Template Ratings
+// @Description  Fetch ratings and reviews of the template, recently updated first
 // @Tags         Templates
 // @Accept       json
 // @Produce      json
 // @Security     ApiKeyAuth
+// @Param        template path string true "Template ID"
 // @Param        body query dto.PaginationRequest true "Pagination request"
-// @Success      200 {array} dto.TemplateResponse "List of favorite templates"
+// @Success      200 {array} dto.TemplateRatingResponse "List of ratings"
 // @Failure      400 {object} apierr.ApiErr "Bad request"
 // @Failure      404 {object} apierr.ApiErr "Not found"
 // @Failure      422 {object} apierr.ApiErr "Invalid JSON"
 // @Failure      500 {object} apierr.ApiErr "Internal server error"
-// @Router       /api/templates/favorite [get]
-func (th *TemplateHandl) FetchFavoriteTemplates(c *fiber.Ctx) error {
+// @Router       /api/templates/{template}/ratings [get]
+func (th *TemplateHandl) FetchRatings(c *fiber.Ctx) error {
 	ctx := c.UserContext()
+	id := c.Params("template")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
 	req := dto.PaginationRequest{}
 	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, th.Validator); err != nil {
 		return err
 	}
-	id := c.Locals("userId").(string)
-	templates, err := th.TemplateServ.FetchFavorite(ctx, id, req.Amount, req.Page)
+	uid := c.Locals("userId").(string)
+	ratings, err := th.TemplateServ.FetchRatings(ctx, id, uid, req.Amount, req.Page)
 	if err != nil {
 		return apierr.ToApiError(err)
 	}
-	return c.JSON(templates)
+	return c.JSON(ratings)
 }
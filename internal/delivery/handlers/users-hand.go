@@ -6,22 +6,24 @@ import (
 	"readmeow/internal/domain/services"
 	"readmeow/internal/dto"
 	"readmeow/pkg/validator"
-	"time"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserHandl struct {
-	UserServ  services.UserServ
-	AuthServ  services.AuthServ
-	Validator *validator.Validator
+	UserServ   services.UserServ
+	AuthServ   services.AuthServ
+	ReadmeServ services.ReadmeServ
+	Validator  *validator.Validator
 }
 
-func NewUserHandl(us services.UserServ, as services.AuthServ, v *validator.Validator) *UserHandl {
+func NewUserHandl(us services.UserServ, as services.AuthServ, rs services.ReadmeServ, v *validator.Validator) *UserHandl {
 	return &UserHandl{
-		UserServ:  us,
-		AuthServ:  as,
-		Validator: v,
+		UserServ:   us,
+		AuthServ:   as,
+		ReadmeServ: rs,
+		Validator:  v,
 	}
 }
 
@@ -50,6 +52,150 @@ func (uh *UserHandl) GetUser(c *fiber.Ctx) error {
 	return c.JSON(user)
 }
 
+// GetUserByHandle godoc
+// @Summary      Get User by Handle
+// @Description  Get user by handle. Old handles redirect to the current one
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        handle path string true "User handle"
+// @Success      200 {object} dto.UserResponse "User data"
+// @Success      301 "Redirect to the current handle"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/@{handle} [get]
+func (uh *UserHandl) GetUserByHandle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id, redirect, err := uh.resolveHandle(c)
+	if err != nil {
+		return err
+	}
+	if redirect != "" {
+		return c.Redirect(redirect, fiber.StatusMovedPermanently)
+	}
+	uid := c.Locals("userId").(string)
+	user, err := uh.UserServ.Get(ctx, id, id == uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(user)
+}
+
+// FetchReadmesByHandle godoc
+// @Summary      Fetch Readmes by Handle
+// @Description  Fetch personal readmes of the user with the handle. Other users only get the readmes shared with them. Old handles redirect to the current one
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        handle path string true "User handle"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.ReadmeResponse "Readmes"
+// @Success      301 "Redirect to the current handle"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/@{handle}/readmes [get]
+func (uh *UserHandl) FetchReadmesByHandle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id, redirect, err := uh.resolveHandle(c)
+	if err != nil {
+		return err
+	}
+	if redirect != "" {
+		return c.Redirect(redirect, fiber.StatusMovedPermanently)
+	}
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, uh.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	readmes, err := uh.ReadmeServ.FetchByOwner(ctx, req.Amount, req.Page, id, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(readmes)
+}
+
+// GetReadmeByHandle godoc
+// @Summary      Get Readme by Handle
+// @Description  Get readme of the user with the handle if current user has access to it. Old handles redirect to the current one
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        handle path string true "User handle"
+// @Param        readme path string true "Readme ID"
+// @Success      200 {object} models.Readme "Readme data"
+// @Success      301 "Redirect to the current handle"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/@{handle}/readmes/{readme} [get]
+func (uh *UserHandl) GetReadmeByHandle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	readmeId := c.Params("readme")
+	if err := helpers.ValidateId(c, readmeId); err != nil {
+		return err
+	}
+	id, redirect, err := uh.resolveHandle(c)
+	if err != nil {
+		return err
+	}
+	if redirect != "" {
+		return c.Redirect(redirect, fiber.StatusMovedPermanently)
+	}
+	uid := c.Locals("userId").(string)
+	readme, err := uh.ReadmeServ.Get(ctx, readmeId, uid)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	if readme.OwnerId.String() != id {
+		return apierr.NotFound()
+	}
+	return c.JSON(readme)
+}
+
+// resolveHandle returns the id of the user with the handle from the path, or
+// the URL to redirect to when the handle is an old one.
+func (uh *UserHandl) resolveHandle(c *fiber.Ctx) (string, string, error) {
+	handle := c.Params("handle")
+	id, current, err := uh.UserServ.ResolveHandle(c.UserContext(), handle)
+	if err != nil {
+		return "", "", apierr.ToApiError(err)
+	}
+	if !strings.EqualFold(handle, current) {
+		return "", strings.Replace(c.OriginalURL(), "/@"+handle, "/@"+current, 1), nil
+	}
+	return id, "", nil
+}
+
+// ChangeHandle godoc
+// @Summary      Change Handle
+// @Description  Change handle of current user. The old handle keeps redirecting to the new one and can not be taken by others
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.ChangeHandleRequest true "Change handle request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      409 {object} apierr.ApiErr "Handle is taken"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/handle [patch]
+func (uh *UserHandl) ChangeHandle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.ChangeHandleRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, uh.Validator); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := uh.UserServ.ChangeHandle(ctx, uid, req.Handle); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
 // UpdateUser godoc
 // @Summary      Update User
 // @Description  Update user's profile (nickname, avatar, etc.)
@@ -91,7 +237,7 @@ func (uh *UserHandl) Update(c *fiber.Ctx) error {
 
 // DeleteUser godoc
 // @Summary      Delete User
-// @Description  Delete current user account
+// @Description  Delete current user account. Sessions are revoked at once, and the account with its readmes, templates, favorites and images is erased by a background job that emails a confirmation
 // @Tags         Users
 // @Accept       json
 // @Produce      json
@@ -113,19 +259,64 @@ func (uh *UserHandl) Delete(c *fiber.Ctx) error {
 	if err := uh.UserServ.Delete(ctx, id, req.Password); err != nil {
 		return apierr.ToApiError(err)
 	}
-	newCookie := &fiber.Cookie{
-		Name:     "jwt",
-		Value:    "",
-		HTTPOnly: true,
-		Expires:  time.Now().Add(-time.Hour),
-		MaxAge:   -1,
-		Path:     "/",
-		SameSite: "Lax",
+	if err := uh.AuthServ.RevokeAllSessions(ctx, id); err != nil {
+		return apierr.ToApiError(err)
 	}
-	c.Cookie(newCookie)
+	helpers.ClearAuthCookies(c)
 	return helpers.SuccessResponse(c)
 }
 
+// ExportUser godoc
+// @Summary      Export User Data
+// @Description  Download a ZIP archive with the profile, readmes, templates, favorites and images of the current user
+// @Tags         Users
+// @Produce      application/zip
+// @Security     ApiKeyAuth
+// @Success      200 {file} file "ZIP archive"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/export [get]
+func (uh *UserHandl) Export(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Locals("userId").(string)
+	data, err := uh.UserServ.Export(ctx, id)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="readmeow-export.zip"`)
+	return c.Send(data)
+}
+
+// FetchLoginEvents godoc
+// @Summary      Fetch Login Events
+// @Description  Fetch login history of current user with method, device and IP, newest first
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.LoginEventResponse "Login events response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      403 {object} apierr.ApiErr "Forbidden"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/security/events [get]
+func (uh *UserHandl) FetchLoginEvents(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, uh.Validator); err != nil {
+		return err
+	}
+	events, err := uh.AuthServ.FetchLoginEvents(ctx, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(events)
+}
+
 // ChangeUserPassword godoc
 // @Summary      Change User Password
 // @Description  Change password for current user
@@ -153,3 +344,225 @@ func (uh *UserHandl) ChangeUserPassword(c *fiber.Ctx) error {
 	}
 	return helpers.SuccessResponse(c)
 }
+
+// SetUserPassword godoc
+// @Summary      Set User Password
+// @Description  Set a password for current user who signed up through an OAuth or OpenID Connect provider. Login is required when the account has none
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.SetPasswordRequest true "Set password request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      409 {object} apierr.ApiErr "Password or login already exists"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/password [post]
+func (uh *UserHandl) SetUserPassword(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.SetPasswordRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, uh.Validator); err != nil {
+		return err
+	}
+	id := c.Locals("userId").(string)
+	if err := uh.UserServ.SetPassword(ctx, id, req.Login, req.Password); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// ChangeEmail godoc
+// @Summary      Change User Email
+// @Description  Send confirmation code to the new email and notice to the current one
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.ChangeEmailRequest true "Change email request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      409 {object} apierr.ApiErr "Email already exists"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/email [post]
+func (uh *UserHandl) ChangeEmail(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.ChangeEmailRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, uh.Validator); err != nil {
+		return err
+	}
+	id := c.Locals("userId").(string)
+	if err := uh.AuthServ.RequestEmailChange(ctx, id, req.Email); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// ConfirmEmailChange godoc
+// @Summary      Confirm User Email Change
+// @Description  Swap user's email after confirming the code sent to the new address
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body body dto.ConfirmEmailChangeRequest true "Confirm email change request"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      401 {object} apierr.ApiErr "Unauthorized"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      409 {object} apierr.ApiErr "Email already exists"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/email/confirm [post]
+func (uh *UserHandl) ConfirmEmailChange(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	req := dto.ConfirmEmailChangeRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Body{}, uh.Validator); err != nil {
+		return err
+	}
+	id := c.Locals("userId").(string)
+	if err := uh.AuthServ.ConfirmEmailChange(ctx, id, req.Code); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// Follow godoc
+// @Summary      Follow User
+// @Description  Follow user to see their new and updated public templates in the feed
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/{user}/follow [post]
+func (uh *UserHandl) Follow(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	target := c.Params("user")
+	if err := helpers.ValidateId(c, target); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := uh.UserServ.Follow(ctx, uid, target); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// Unfollow godoc
+// @Summary      Unfollow User
+// @Description  Stop following user
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Success      200 {object} dto.SuccessResponse "Success response"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      404 {object} apierr.ApiErr "Not found"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/{user}/follow [delete]
+func (uh *UserHandl) Unfollow(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	target := c.Params("user")
+	if err := helpers.ValidateId(c, target); err != nil {
+		return err
+	}
+	uid := c.Locals("userId").(string)
+	if err := uh.UserServ.Unfollow(ctx, uid, target); err != nil {
+		return apierr.ToApiError(err)
+	}
+	return helpers.SuccessResponse(c)
+}
+
+// FetchFollowers godoc
+// @Summary      Fetch Followers
+// @Description  Fetch users following the user, newest first
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.FollowUserResponse "Followers"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/{user}/followers [get]
+func (uh *UserHandl) FetchFollowers(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("user")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, uh.Validator); err != nil {
+		return err
+	}
+	users, err := uh.UserServ.FetchFollowers(ctx, id, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(users)
+}
+
+// FetchFollowing godoc
+// @Summary      Fetch Following
+// @Description  Fetch users followed by the user, newest first
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        user path string true "User ID"
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.FollowUserResponse "Followed users"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/{user}/following [get]
+func (uh *UserHandl) FetchFollowing(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	id := c.Params("user")
+	if err := helpers.ValidateId(c, id); err != nil {
+		return err
+	}
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, uh.Validator); err != nil {
+		return err
+	}
+	users, err := uh.UserServ.FetchFollowing(ctx, id, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(users)
+}
+
+// FetchFeed godoc
+// @Summary      Fetch Feed
+// @Description  Fetch new and updated public templates of followed users, most recent first
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body query dto.PaginationRequest true "Pagination request"
+// @Success      200 {array} dto.FeedItemResponse "Feed"
+// @Failure      400 {object} apierr.ApiErr "Bad request"
+// @Failure      422 {object} apierr.ApiErr "Invalid JSON"
+// @Failure      500 {object} apierr.ApiErr "Internal server error"
+// @Router       /api/users/feed [get]
+func (uh *UserHandl) FetchFeed(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	uid := c.Locals("userId").(string)
+	req := dto.PaginationRequest{}
+	if err := helpers.ParseAndValidateRequest(c, &req, helpers.Query{}, uh.Validator); err != nil {
+		return err
+	}
+	feed, err := uh.UserServ.FetchFeed(ctx, uid, req.Amount, req.Page)
+	if err != nil {
+		return apierr.ToApiError(err)
+	}
+	return c.JSON(feed)
+}
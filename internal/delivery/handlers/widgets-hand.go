@@ -76,58 +76,6 @@ func (wh *WidgetHandl) SearchWidgets(c *fiber.Ctx) error {
 	return c.JSON(widgets)
 }
 
-// LikeWidget godoc
-// @Summary      Like Widget
-// @Description  Like widget by ID
-// @Tags         Widgets
-// @Produce      json
-// @Security     ApiKeyAuth
-// @Param        widget path string true "Widget ID"
-// @Success      200 {object} dto.SuccessResponse "Success response"
-// @Failure      400 {object} apierr.ApiErr "Bad request"
-// @Failure      404 {object} apierr.ApiErr "Not found"
-// @Failure      409 {object} apierr.ApiErr "Already exists"
-// @Failure      500 {object} apierr.ApiErr "Internal server error"
-// @Router       /api/widgets/like/{widget} [patch]
-func (wh *WidgetHandl) Like(c *fiber.Ctx) error {
-	ctx := c.UserContext()
-	id := c.Params("widget")
-	if err := helpers.ValidateId(c, id); err != nil {
-		return err
-	}
-	uid := c.Locals("userId").(string)
-	if err := wh.WidgetServ.Like(ctx, id, uid); err != nil {
-		return apierr.ToApiError(err)
-	}
-	return helpers.SuccessResponse(c)
-}
-
-// DislikeWidget godoc
-// @Summary      Dislike Widget
-// @Description  Dislike widget by ID
-// @Tags         Widgets
-// @Produce      json
-// @Security     ApiKeyAuth
-// @Param        widget path string true "Widget ID"
-// @Success      200 {object} dto.SuccessResponse "Success response"
-// @Failure      400 {object} apierr.ApiErr "Bad request"
-// @Failure      404 {object} apierr.ApiErr "Not found"
-// @Failure      409 {object} apierr.ApiErr "Already exists"
-// @Failure      500 {object} apierr.ApiErr "Internal server error"
-// @Router       /api/widgets/dislike/{widget} [patch]
-func (wh *WidgetHandl) Dislike(c *fiber.Ctx) error {
-	ctx := c.UserContext()
-	id := c.Params("widget")
-	if err := helpers.ValidateId(c, id); err != nil {
-		return err
-	}
-	uid := c.Locals("userId").(string)
-	if err := wh.WidgetServ.Dislike(ctx, id, uid); err != nil {
-		return apierr.ToApiError(err)
-	}
-	return helpers.SuccessResponse(c)
-}
-
 // FetchFavoriteWidgets godoc
 // @Summary      Fetch Favorite Widgets
 // @Description  Fetch favorite widgets of current user
@@ -2,12 +2,20 @@ package middlewares
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"readmeow/internal/config"
 	"readmeow/internal/delivery/apierr"
+	"readmeow/internal/delivery/handlers/helpers"
 	"readmeow/internal/delivery/ratelimiter"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/domain/services/utils"
+	"readmeow/pkg/errs"
 	"readmeow/pkg/monitoring"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,37 +23,142 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func AuthMiddleware(acfg config.AuthConfig, valid map[string]bool) fiber.Handler {
+const bearerPrefix = "Bearer "
+
+// AuthMiddleware lets requests to the valid paths through without
+// credentials. When such a request still carries them, the caller is
+// identified on a best-effort basis so public endpoints can include what only
+// they may see; invalid credentials there are ignored.
+func AuthMiddleware(ks *utils.KeySet, as services.AuthServ, valid map[string]bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if valid[c.Path()] {
+		optional := valid[c.Path()]
+		auth := c.Get(fiber.HeaderAuthorization)
+		if optional && !strings.HasPrefix(auth, bearerPrefix) && c.Cookies("jwt") == "" {
+			return c.Next()
+		}
+		fail := func(err apierr.ApiErr) error {
+			if optional {
+				return c.Next()
+			}
+			return err
+		}
+		if strings.HasPrefix(auth, bearerPrefix) {
+			userId, scopes, err := as.AuthenticateAccessToken(c.UserContext(), strings.TrimPrefix(auth, bearerPrefix))
+			if err != nil {
+				if errors.Is(err, errs.ErrInvalidTokenBase) {
+					return fail(apierr.Unauthorized())
+				}
+				return fail(apierr.InternalServerError())
+			}
+			c.Locals("userId", userId)
+			c.Locals("role", models.RoleUser)
+			c.Locals("scopes", scopes)
 			return c.Next()
 		}
 		return jwtware.New(jwtware.Config{
-			SigningKey:  []byte(acfg.Secret),
+			KeyFunc:     ks.Keyfunc,
 			TokenLookup: "cookie:jwt",
 			SuccessHandler: func(c *fiber.Ctx) error {
 				token, ok := c.Locals("user").(*jwt.Token)
 				if !ok {
-					return apierr.InvalidRequest()
+					return fail(apierr.InvalidRequest())
 				}
 				claims, ok := token.Claims.(jwt.MapClaims)
 				if !ok {
-					return apierr.InvalidRequest()
+					return fail(apierr.InvalidRequest())
 				}
 				userId, ok := claims["sub"].(string)
 				if !ok {
-					return apierr.InvalidRequest()
+					return fail(apierr.InvalidRequest())
+				}
+				jti, ok := claims["jti"].(string)
+				if !ok {
+					return fail(apierr.InvalidRequest())
+				}
+				role, ok := claims["role"].(string)
+				if !ok {
+					role = models.RoleUser
+				}
+				revoked, err := as.IsRevoked(c.UserContext(), jti)
+				if err != nil {
+					return fail(apierr.InternalServerError())
+				}
+				if revoked {
+					return fail(apierr.Unauthorized())
 				}
 				c.Locals("userId", userId)
+				c.Locals("jti", jti)
+				c.Locals("role", role)
 				return c.Next()
 			},
 			ErrorHandler: func(c *fiber.Ctx, err error) error {
-				return apierr.Unauthorized()
+				return fail(apierr.Unauthorized())
 			},
 		})(c)
 	}
 }
 
+// CSRFMiddleware checks the double-submit token on state-changing requests.
+// Requests with a Bearer token carry no ambient credentials and are exempt, as
+// are the exempt paths: the pre-auth endpoints a client calls before it has a
+// session to protect.
+func CSRFMiddleware(exempt map[string]bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if exempt[c.Path()] {
+			return c.Next()
+		}
+		if strings.HasPrefix(c.Get(fiber.HeaderAuthorization), bearerPrefix) {
+			return c.Next()
+		}
+		cookie := c.Cookies(helpers.CSRFCookie)
+		header := c.Get(helpers.CSRFHeader)
+		if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+			return apierr.InvalidCSRFToken()
+		}
+		return c.Next()
+	}
+}
+
+func ScopeMiddleware(resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, ok := c.Locals("scopes").([]string)
+		if !ok {
+			return c.Next()
+		}
+		scope := resource + ":write"
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			scope = resource + ":read"
+		}
+		if !slices.Contains(scopes, scope) {
+			return apierr.Forbidden()
+		}
+		return c.Next()
+	}
+}
+
+func RoleMiddleware(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, ok := c.Locals("role").(string)
+		if !ok || !slices.Contains(roles, role) {
+			return apierr.Forbidden()
+		}
+		return c.Next()
+	}
+}
+
+func SessionOnlyMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("scopes").([]string); ok {
+			return apierr.Forbidden()
+		}
+		return c.Next()
+	}
+}
+
 func MetricsMiddleware(ps *monitoring.PrometheusSetup) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"readmeow/internal/delivery/handlers/helpers"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/domain/services/utils"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	token, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatalf("GenerateOpaqueToken: %v", err)
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(CSRFMiddleware(map[string]bool{"/login": true}))
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie string
+		header string
+		bearer bool
+		want   int
+	}{
+		{name: "get passes", method: fiber.MethodGet, path: "/readmes", want: fiber.StatusOK},
+		{name: "head passes", method: fiber.MethodHead, path: "/readmes", want: fiber.StatusOK},
+		{name: "options passes", method: fiber.MethodOptions, path: "/readmes", want: fiber.StatusOK},
+		{name: "missing cookie and header", method: fiber.MethodPost, path: "/readmes", want: fiber.StatusForbidden},
+		{name: "missing header", method: fiber.MethodPost, path: "/readmes", cookie: "token", want: fiber.StatusForbidden},
+		{name: "missing cookie", method: fiber.MethodDelete, path: "/readmes", header: "token", want: fiber.StatusForbidden},
+		{name: "mismatch", method: fiber.MethodPatch, path: "/readmes", cookie: "token", header: "other", want: fiber.StatusForbidden},
+		{name: "match", method: fiber.MethodPatch, path: "/readmes", cookie: "token", header: "token", want: fiber.StatusOK},
+		{name: "bearer exempt", method: fiber.MethodPost, path: "/readmes", bearer: true, want: fiber.StatusOK},
+		{name: "exempt path", method: fiber.MethodPost, path: "/login", want: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: helpers.CSRFCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(helpers.CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set(fiber.HeaderAuthorization, bearerPrefix+services.AccessTokenPrefix+token)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -10,9 +10,13 @@ import (
 )
 
 type OAuthConfig struct {
-	GoogleOAuthConfig *oauth2.Config
-	GithubOAuthConfig *oauth2.Config
-	StateExpire       time.Duration
+	GoogleOAuthConfig     *oauth2.Config
+	GithubOAuthConfig     *oauth2.Config
+	GoogleRedirectURL     string
+	GithubRedirectURL     string
+	GoogleLinkRedirectURL string
+	GithubLinkRedirectURL string
+	StateExpire           time.Duration
 }
 
 type oauthParams struct {
@@ -57,8 +61,12 @@ func NewOAuthConfig(cfg config.OAuthConfig) OAuthConfig {
 	githubOAuthConfig := newGithubOAuth(githubOAuthParams)
 	googleOAuthConfig := newGoogleOAuth(googleOAuthParams)
 	return OAuthConfig{
-		GoogleOAuthConfig: googleOAuthConfig,
-		GithubOAuthConfig: githubOAuthConfig,
-		StateExpire:       cfg.StateTTL,
+		GoogleOAuthConfig:     googleOAuthConfig,
+		GithubOAuthConfig:     githubOAuthConfig,
+		GoogleRedirectURL:     cfg.GoogleRedirectURL,
+		GithubRedirectURL:     cfg.GithubRedirectURL,
+		GoogleLinkRedirectURL: cfg.GoogleLinkRedirectURL,
+		GithubLinkRedirectURL: cfg.GithubLinkRedirectURL,
+		StateExpire:           cfg.StateTTL,
 	}
 }
@@ -0,0 +1,431 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"readmeow/internal/config"
+	"readmeow/internal/dto"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStatePrefix = "oauth_state:"
+	discoveryPath   = "/.well-known/openid-configuration"
+)
+
+var reservedProviders = map[string]bool{
+	"google": true,
+	"github": true,
+	"local":  true,
+}
+
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type Registry struct {
+	Providers  map[string]*OIDCProvider
+	Cache      *cache.Cache
+	HTTPClient *http.Client
+	StateTTL   time.Duration
+}
+
+type OIDCProvider struct {
+	Name   string
+	Config config.OIDCProviderConfig
+
+	mu          sync.Mutex
+	discovered  bool
+	issuer      string
+	userInfoURL string
+	jwksURL     string
+	oauth       *oauth2.Config
+	keys        map[string]any
+}
+
+type oidcState struct {
+	Provider   string `json:"provider"`
+	Verifier   string `json:"verifier"`
+	Nonce      string `json:"nonce"`
+	LinkUserId string `json:"link_user_id"`
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func MustNewRegistry(cfg config.OAuthConfig, c *cache.Cache, client *http.Client) *Registry {
+	providers := make(map[string]*OIDCProvider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		if p.Name == "" || p.Issuer == "" || p.ClientId == "" || p.RedirectURL == "" {
+			panic(fmt.Errorf("invalid oidc provider config: %q", p.Name))
+		}
+		if reservedProviders[p.Name] {
+			panic(fmt.Errorf("oidc provider name is reserved: %q", p.Name))
+		}
+		if _, ok := providers[p.Name]; ok {
+			panic(fmt.Errorf("duplicate oidc provider: %q", p.Name))
+		}
+		providers[p.Name] = &OIDCProvider{
+			Name:   p.Name,
+			Config: p,
+		}
+	}
+	return &Registry{
+		Providers:  providers,
+		Cache:      c,
+		HTTPClient: client,
+		StateTTL:   cfg.StateTTL,
+	}
+}
+
+func (r *Registry) Has(name string) bool {
+	_, ok := r.Providers[name]
+	return ok
+}
+
+func (r *Registry) AuthCodeURL(ctx context.Context, name, linkUserId string) (string, string, error) {
+	op := "oauth.Registry.AuthCodeURL"
+	p, ok := r.Providers[name]
+	if !ok {
+		return "", "", errs.ErrNotFound(op)
+	}
+	if err := p.discover(r.withClient(ctx), r.HTTPClient); err != nil {
+		return "", "", errs.NewAppError(op, err)
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", "", errs.NewAppError(op, err)
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", errs.NewAppError(op, err)
+	}
+	data := oidcState{
+		Provider:   name,
+		Verifier:   oauth2.GenerateVerifier(),
+		Nonce:      nonce,
+		LinkUserId: linkUserId,
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", "", errs.NewAppError(op, err)
+	}
+	if err := r.Cache.Redis.Set(ctx, oidcStatePrefix+state, raw, r.StateTTL).Err(); err != nil {
+		return "", "", errs.NewAppError(op, err)
+	}
+	url := p.oauth.AuthCodeURL(state,
+		oauth2.AccessTypeOnline,
+		oauth2.S256ChallengeOption(data.Verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return url, state, nil
+}
+
+func (r *Registry) Exchange(ctx context.Context, name, state, code string) (*dto.OAuthProfile, string, error) {
+	op := "oauth.Registry.Exchange"
+	p, ok := r.Providers[name]
+	if !ok {
+		return nil, "", errs.ErrNotFound(op)
+	}
+	raw, err := r.Cache.Redis.GetDel(ctx, oidcStatePrefix+state).Bytes()
+	if err != nil {
+		if err == cache.EMPTY {
+			return nil, "", errs.ErrInvalidToken(op)
+		}
+		return nil, "", errs.NewAppError(op, err)
+	}
+	data := oidcState{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, "", errs.NewAppError(op, err)
+	}
+	if data.Provider != name {
+		return nil, "", errs.ErrInvalidToken(op)
+	}
+	ctx = r.withClient(ctx)
+	if err := p.discover(ctx, r.HTTPClient); err != nil {
+		return nil, "", errs.NewAppError(op, err)
+	}
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(data.Verifier))
+	if err != nil {
+		return nil, "", errs.NewAppError(op, err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIdToken == "" {
+		return nil, "", errs.NewAppError(op, errors.New("id_token is missing"))
+	}
+	claims, err := p.verify(ctx, r.HTTPClient, rawIdToken, data.Nonce)
+	if err != nil {
+		return nil, "", errs.ErrInvalidToken(op)
+	}
+	profile := profileFromClaims(name, claims)
+	if profile.Email == "" && p.userInfoURL != "" {
+		if err := p.fillFromUserInfo(ctx, token, profile); err != nil {
+			return nil, "", errs.NewAppError(op, err)
+		}
+	}
+	// An untrusted issuer could claim any email as verified and take over the
+	// account that has it.
+	profile.VerifiedEmail = profile.VerifiedEmail && p.Config.TrustEmail
+	return profile, data.LinkUserId, nil
+}
+
+func (r *Registry) withClient(ctx context.Context) context.Context {
+	if r.HTTPClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, r.HTTPClient)
+}
+
+func (p *OIDCProvider) discover(ctx context.Context, client *http.Client) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+	doc := discoveryDocument{}
+	if err := getJSON(ctx, client, strings.TrimSuffix(p.Config.Issuer, "/")+discoveryPath, &doc); err != nil {
+		return err
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.Config.Issuer, "/") {
+		return fmt.Errorf("issuer mismatch: %q", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return errors.New("incomplete discovery document")
+	}
+	scopes := p.Config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.Config.ClientId,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  p.Config.RedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+	p.issuer = doc.Issuer
+	p.userInfoURL = doc.UserinfoEndpoint
+	p.jwksURL = doc.JWKSURI
+	p.discovered = true
+	return nil
+}
+
+func (p *OIDCProvider) verify(ctx context.Context, client *http.Client, rawIdToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(idTokenMethods))
+	if _, err := parser.ParseWithClaims(rawIdToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, client, kid)
+	}); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyIssuer(p.issuer, true) {
+		return nil, errors.New("invalid issuer")
+	}
+	if !claims.VerifyAudience(p.Config.ClientId, true) {
+		return nil, errors.New("invalid audience")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("invalid nonce")
+	}
+	return claims, nil
+}
+
+func (p *OIDCProvider) key(ctx context.Context, client *http.Client, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := getJSON(ctx, client, p.jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	p.keys = keys
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %q", kid)
+}
+
+func (p *OIDCProvider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) fillFromUserInfo(ctx context.Context, token *oauth2.Token, profile *dto.OAuthProfile) error {
+	claims := jwt.MapClaims{}
+	if err := getJSON(ctx, p.oauth.Client(ctx, token), p.userInfoURL, &claims); err != nil {
+		return err
+	}
+	if sub, _ := claims["sub"].(string); sub != profile.Id {
+		return errors.New("userinfo subject mismatch")
+	}
+	info := profileFromClaims(profile.Provider, claims)
+	profile.Email = info.Email
+	profile.VerifiedEmail = info.VerifiedEmail
+	if profile.Nickname == "" {
+		profile.Nickname = info.Nickname
+	}
+	if profile.Avatar == "" {
+		profile.Avatar = info.Avatar
+	}
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %q", k.Kty)
+	}
+}
+
+func profileFromClaims(provider string, claims jwt.MapClaims) *dto.OAuthProfile {
+	profile := &dto.OAuthProfile{Provider: provider}
+	profile.Id, _ = claims["sub"].(string)
+	profile.Email, _ = claims["email"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		profile.VerifiedEmail = v
+	case string:
+		profile.VerifiedEmail = v == "true"
+	}
+	for _, field := range []string{"preferred_username", "name", "nickname"} {
+		if v, _ := claims[field].(string); v != "" {
+			profile.Nickname = v
+			break
+		}
+	}
+	profile.Avatar, _ = claims["picture"].(string)
+	return profile
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,465 @@
+package oauth
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"readmeow/internal/config"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubIssuer is a local OpenID provider serving discovery, token and JWKS
+// endpoints. Codes are handed out by authorize, the way a browser would get
+// them after the redirect.
+type stubIssuer struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	// discoveryIssuer, tokenIssuer and tokenNonce override what the issuer
+	// announces and signs, empty means the honest value.
+	discoveryIssuer string
+	tokenIssuer     string
+	tokenNonce      string
+
+	mu    sync.Mutex
+	codes map[string]stubGrant
+}
+
+type stubGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIssuer{
+		t:     t,
+		key:   key,
+		codes: map[string]stubGrant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, s.discovery)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := s.srv.URL
+	if s.discoveryIssuer != "" {
+		issuer = s.discoveryIssuer
+	}
+	writeJSON(w, discoveryDocument{
+		Issuer:                issuer,
+		AuthorizationEndpoint: s.srv.URL + "/authorize",
+		TokenEndpoint:         s.srv.URL + "/token",
+		JWKSURI:               s.srv.URL + "/jwks",
+	})
+}
+
+func (s *stubIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"keys": []jsonWebKey{{
+			Kid: "stub",
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *stubIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	grant, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"invalid_grant"}`)
+		return
+	}
+	issuer, nonce := s.srv.URL, grant.nonce
+	if s.tokenIssuer != "" {
+		issuer = s.tokenIssuer
+	}
+	if s.tokenNonce != "" {
+		nonce = s.tokenNonce
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                issuer,
+		"aud":                "client",
+		"sub":                "user-1",
+		"nonce":              nonce,
+		"email":              "user@example.com",
+		"email_verified":     true,
+		"preferred_username": "stubuser",
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = "stub"
+	raw, err := idToken.SignedString(s.key)
+	if err != nil {
+		s.t.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     raw,
+	})
+}
+
+// authorize plays the user consenting on the authorization page and returns
+// the state and code the provider would redirect back with.
+func (s *stubIssuer) authorize(authURL string) (string, string) {
+	s.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		s.t.Fatalf("auth url has no S256 challenge: %s", authURL)
+	}
+	if q.Get("nonce") == "" {
+		s.t.Fatalf("auth url has no nonce: %s", authURL)
+	}
+	code := "code-" + q.Get("state")[:8]
+	s.mu.Lock()
+	s.codes[code] = stubGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	s.mu.Unlock()
+	return q.Get("state"), code
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestRegistry(t *testing.T, issuer string) *Registry {
+	t.Helper()
+	cfg := config.OAuthConfig{
+		StateTTL: time.Minute,
+		Providers: []config.OIDCProviderConfig{{
+			Name:        "stub",
+			Issuer:      issuer,
+			ClientId:    "client",
+			RedirectURL: "http://localhost/callback",
+			TrustEmail:  true,
+		}},
+	}
+	return MustNewRegistry(cfg, newStubCache(t), &http.Client{Timeout: 5 * time.Second})
+}
+
+func TestRegistryDiscovery(t *testing.T) {
+	stub := newStubIssuer(t)
+	r := newTestRegistry(t, stub.srv.URL)
+	ctx := context.Background()
+
+	authURL, state, err := r.AuthCodeURL(ctx, "stub", "")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, stub.srv.URL+"/authorize?") {
+		t.Fatalf("auth url does not use the discovered endpoint: %s", authURL)
+	}
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	if n, err := r.Cache.Redis.Exists(ctx, oidcStatePrefix+state).Result(); err != nil || n != 1 {
+		t.Fatalf("state is not stored: %d, %v", n, err)
+	}
+
+	if _, _, err := r.AuthCodeURL(ctx, "missing", ""); !errors.Is(err, errs.ErrNotFoundBase) {
+		t.Fatalf("unknown provider: got %v, want not found", err)
+	}
+}
+
+func TestRegistryDiscoveryIssuerMismatch(t *testing.T) {
+	stub := newStubIssuer(t)
+	stub.discoveryIssuer = "https://issuer.invalid"
+	r := newTestRegistry(t, stub.srv.URL)
+
+	if _, _, err := r.AuthCodeURL(context.Background(), "stub", ""); err == nil {
+		t.Fatal("discovery document of another issuer was accepted")
+	}
+}
+
+func TestRegistryExchange(t *testing.T) {
+	stub := newStubIssuer(t)
+	r := newTestRegistry(t, stub.srv.URL)
+	ctx := context.Background()
+
+	authURL, _, err := r.AuthCodeURL(ctx, "stub", "link-user")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	state, code := stub.authorize(authURL)
+
+	profile, linkUserId, err := r.Exchange(ctx, "stub", state, code)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if profile.Provider != "stub" || profile.Id != "user-1" || profile.Email != "user@example.com" || !profile.VerifiedEmail || profile.Nickname != "stubuser" {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if linkUserId != "link-user" {
+		t.Fatalf("link user id = %q, want %q", linkUserId, "link-user")
+	}
+
+	if n, err := r.Cache.Redis.Exists(ctx, oidcStatePrefix+state).Result(); err != nil || n != 0 {
+		t.Fatalf("state is not consumed: %d, %v", n, err)
+	}
+	if _, _, err := r.Exchange(ctx, "stub", state, code); !errors.Is(err, errs.ErrInvalidTokenBase) {
+		t.Fatalf("replayed state: got %v, want invalid token", err)
+	}
+}
+
+func TestRegistryExchangeUntrustedEmail(t *testing.T) {
+	stub := newStubIssuer(t)
+	r := newTestRegistry(t, stub.srv.URL)
+	r.Providers["stub"].Config.TrustEmail = false
+	ctx := context.Background()
+
+	authURL, _, err := r.AuthCodeURL(ctx, "stub", "")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	state, code := stub.authorize(authURL)
+
+	profile, _, err := r.Exchange(ctx, "stub", state, code)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if profile.Email != "user@example.com" || profile.VerifiedEmail {
+		t.Fatalf("email of an untrusted provider is verified: %+v", profile)
+	}
+}
+
+func TestRegistryExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(s *stubIssuer)
+		mutate func(state, code string) (string, string, string)
+		want   error
+	}{
+		{
+			name:  "wrong nonce",
+			setup: func(s *stubIssuer) { s.tokenNonce = "other-nonce" },
+			want:  errs.ErrInvalidTokenBase,
+		},
+		{
+			name:  "wrong issuer",
+			setup: func(s *stubIssuer) { s.tokenIssuer = "https://issuer.invalid" },
+			want:  errs.ErrInvalidTokenBase,
+		},
+		{
+			name: "unknown state",
+			mutate: func(state, code string) (string, string, string) {
+				return "stub", state + "x", code
+			},
+			want: errs.ErrInvalidTokenBase,
+		},
+		{
+			name: "unknown provider",
+			mutate: func(state, code string) (string, string, string) {
+				return "missing", state, code
+			},
+			want: errs.ErrNotFoundBase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubIssuer(t)
+			if tt.setup != nil {
+				tt.setup(stub)
+			}
+			r := newTestRegistry(t, stub.srv.URL)
+			ctx := context.Background()
+			authURL, _, err := r.AuthCodeURL(ctx, "stub", "")
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			state, code := stub.authorize(authURL)
+			name := "stub"
+			if tt.mutate != nil {
+				name, state, code = tt.mutate(state, code)
+			}
+			if _, _, err := r.Exchange(ctx, name, state, code); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryExchangeWrongVerifier(t *testing.T) {
+	stub := newStubIssuer(t)
+	r := newTestRegistry(t, stub.srv.URL)
+	ctx := context.Background()
+
+	authURL, _, err := r.AuthCodeURL(ctx, "stub", "")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	_, code := stub.authorize(authURL)
+	// A second login attempt holds another verifier, so its state can not
+	// redeem the first code.
+	otherURL, _, err := r.AuthCodeURL(ctx, "stub", "")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, _ := url.Parse(otherURL)
+	if _, _, err := r.Exchange(ctx, "stub", u.Query().Get("state"), code); err == nil {
+		t.Fatal("code was redeemed with a foreign verifier")
+	}
+}
+
+// newStubCache serves the few Redis commands the registry uses from memory,
+// so tests need no Redis server.
+func newStubCache(t *testing.T) *cache.Cache {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &stubRedis{data: map[string]string{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go store.serve(conn)
+		}
+	}()
+	client := redis.NewClient(&redis.Options{
+		Addr:            l.Addr().String(),
+		Protocol:        2,
+		DisableIdentity: true,
+	})
+	t.Cleanup(func() {
+		client.Close()
+		l.Close()
+	})
+	return &cache.Cache{Redis: client}
+}
+
+type stubRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (s *stubRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *stubRedis) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		s.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET", "GETDEL":
+		v, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		if strings.ToUpper(args[0]) == "GETDEL" {
+			delete(s.data, args[1])
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "EXISTS", "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := s.data[k]; ok {
+				n++
+				if strings.ToUpper(args[0]) == "DEL" {
+					delete(s.data, k)
+				}
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected line %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for range n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
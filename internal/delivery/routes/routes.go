@@ -2,27 +2,43 @@ package routes
 
 import (
 	"readmeow/internal/delivery/handlers"
+	"readmeow/internal/delivery/middlewares"
+	"readmeow/internal/domain/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type RouteConfig struct {
-	App           *fiber.App
-	UserHandl     *handlers.UserHandl
-	AuthHandl     *handlers.AuthHandl
-	TemplateHandl *handlers.TemplateHandl
-	ReadmeHandl   *handlers.ReadmeHandl
-	WidgetHandl   *handlers.WidgetHandl
+	App               *fiber.App
+	UserHandl         *handlers.UserHandl
+	AuthHandl         *handlers.AuthHandl
+	TemplateHandl     *handlers.TemplateHandl
+	ReadmeHandl       *handlers.ReadmeHandl
+	WidgetHandl       *handlers.WidgetHandl
+	AdminHandl        *handlers.AdminHandl
+	NotificationHandl *handlers.NotificationHandl
+	OrganizationHandl *handlers.OrganizationHandl
+	CollabHandl       *handlers.CollabHandl
+	CommentHandl      *handlers.CommentHandl
+	CollectionHandl   *handlers.CollectionHandl
+	ReactionHandl     *handlers.ReactionHandl
 }
 
-func NewRoutConfig(a *fiber.App, uh *handlers.UserHandl, ah *handlers.AuthHandl, th *handlers.TemplateHandl, rh *handlers.ReadmeHandl, wh *handlers.WidgetHandl) *RouteConfig {
+func NewRoutConfig(a *fiber.App, uh *handlers.UserHandl, ah *handlers.AuthHandl, th *handlers.TemplateHandl, rh *handlers.ReadmeHandl, wh *handlers.WidgetHandl, adh *handlers.AdminHandl, nh *handlers.NotificationHandl, oh *handlers.OrganizationHandl, ch *handlers.CollabHandl, cmh *handlers.CommentHandl, clh *handlers.CollectionHandl, rch *handlers.ReactionHandl) *RouteConfig {
 	return &RouteConfig{
-		App:           a,
-		UserHandl:     uh,
-		AuthHandl:     ah,
-		TemplateHandl: th,
-		ReadmeHandl:   rh,
-		WidgetHandl:   wh,
+		App:               a,
+		UserHandl:         uh,
+		AuthHandl:         ah,
+		TemplateHandl:     th,
+		ReadmeHandl:       rh,
+		WidgetHandl:       wh,
+		AdminHandl:        adh,
+		NotificationHandl: nh,
+		OrganizationHandl: oh,
+		CollabHandl:       ch,
+		CommentHandl:      cmh,
+		CollectionHandl:   clh,
+		ReactionHandl:     rch,
 	}
 }
 
@@ -32,69 +48,204 @@ func (rc *RouteConfig) SetupRoutes() {
 	rc.ReadmesRoutes()
 	rc.TemplatesRoutes()
 	rc.WidgetsRoutes()
+	rc.AdminRoutes()
+	rc.NotificationsRoutes()
+	rc.OrganizationsRoutes()
+	rc.CollectionsRoutes()
+	rc.WellKnownRoutes()
 }
 
 func (rc *RouteConfig) UsersRoutes() {
-	userGroup := rc.App.Group("/api/users")
+	userGroup := rc.App.Group("/api/users", middlewares.ScopeMiddleware("users"))
+	userGroup.Get("/export", middlewares.SessionOnlyMiddleware(), rc.UserHandl.Export)
+	userGroup.Get("/security/events", middlewares.SessionOnlyMiddleware(), rc.UserHandl.FetchLoginEvents)
+	userGroup.Get("/feed", rc.UserHandl.FetchFeed)
+	userGroup.Get("/@:handle", rc.UserHandl.GetUserByHandle)
+	userGroup.Get("/@:handle/readmes", rc.UserHandl.FetchReadmesByHandle)
+	userGroup.Get("/@:handle/readmes/:readme", rc.UserHandl.GetReadmeByHandle)
 	userGroup.Get("/:user", rc.UserHandl.GetUser)
+	userGroup.Get("/:user/followers", rc.UserHandl.FetchFollowers)
+	userGroup.Get("/:user/following", rc.UserHandl.FetchFollowing)
+	userGroup.Post("/:user/follow", rc.UserHandl.Follow)
+	userGroup.Delete("/:user/follow", rc.UserHandl.Unfollow)
 	userGroup.Patch("", rc.UserHandl.Update)
-	userGroup.Patch("/password", rc.UserHandl.ChangeUserPassword)
-	userGroup.Delete("", rc.UserHandl.Delete)
+	userGroup.Post("/password", middlewares.SessionOnlyMiddleware(), rc.UserHandl.SetUserPassword)
+	userGroup.Patch("/password", middlewares.SessionOnlyMiddleware(), rc.UserHandl.ChangeUserPassword)
+	userGroup.Patch("/handle", rc.UserHandl.ChangeHandle)
+	userGroup.Post("/email", middlewares.SessionOnlyMiddleware(), rc.UserHandl.ChangeEmail)
+	userGroup.Post("/email/confirm", middlewares.SessionOnlyMiddleware(), rc.UserHandl.ConfirmEmailChange)
+	userGroup.Delete("", middlewares.SessionOnlyMiddleware(), rc.UserHandl.Delete)
 }
 
 func (rc *RouteConfig) AuthRoutes() {
-	authGroup := rc.App.Group("/api/auth")
+	authGroup := rc.App.Group("/api/auth", middlewares.SessionOnlyMiddleware())
 
+	authGroup.Get("/csrf", rc.AuthHandl.CSRFToken)
 	authGroup.Post("/register", rc.AuthHandl.Register)
 	authGroup.Post("/verify", rc.AuthHandl.VerifyEmail)
 	authGroup.Post("/newcode", rc.AuthHandl.SendNewCode)
 	authGroup.Post("/login", rc.AuthHandl.Login)
 	authGroup.Post("/logout", rc.AuthHandl.Logout)
+	authGroup.Post("/refresh", rc.AuthHandl.Refresh)
+	authGroup.Post("/password/forgot", rc.AuthHandl.ForgotPassword)
+	authGroup.Post("/password/reset", rc.AuthHandl.ResetPassword)
+	authGroup.Post("/magic", rc.AuthHandl.RequestMagicLink)
+	authGroup.Post("/magic/verify", rc.AuthHandl.LoginMagicLink)
+
+	authGroup.Post("/2fa/login", rc.AuthHandl.LoginTwoFactor)
+	authGroup.Post("/2fa/enroll", rc.AuthHandl.EnrollTwoFactor)
+	authGroup.Post("/2fa/confirm", rc.AuthHandl.ConfirmTwoFactor)
+	authGroup.Post("/2fa/recovery", rc.AuthHandl.RegenerateRecoveryCodes)
+	authGroup.Delete("/2fa", rc.AuthHandl.DisableTwoFactor)
+
+	authGroup.Get("/tokens", rc.AuthHandl.FetchAccessTokens)
+	authGroup.Post("/tokens", rc.AuthHandl.CreateAccessToken)
+	authGroup.Delete("/tokens/:token", rc.AuthHandl.RevokeAccessToken)
+
+	authGroup.Get("/sessions", rc.AuthHandl.FetchSessions)
+	authGroup.Delete("/sessions", rc.AuthHandl.RevokeAllSessions)
+	authGroup.Delete("/sessions/:session", rc.AuthHandl.RevokeSession)
 
 	authGroup.Get("/google", rc.AuthHandl.GoogleOAuth)
 	authGroup.Get("/github", rc.AuthHandl.GitHubOAuth)
 	authGroup.Get("/google/callback", rc.AuthHandl.GoogleOAthCallback)
 	authGroup.Get("/github/callback", rc.AuthHandl.GitHubOAuthCallback)
+	authGroup.Get("/google/link", rc.AuthHandl.GoogleLink)
+	authGroup.Get("/github/link", rc.AuthHandl.GitHubLink)
+	authGroup.Get("/google/link/callback", rc.AuthHandl.GoogleLinkCallback)
+	authGroup.Get("/github/link/callback", rc.AuthHandl.GitHubLinkCallback)
+	authGroup.Get("/oidc/:provider", rc.AuthHandl.OIDCLogin)
+	authGroup.Get("/oidc/:provider/link", rc.AuthHandl.OIDCLink)
+	authGroup.Get("/oidc/:provider/callback", rc.AuthHandl.OIDCCallback)
+	authGroup.Get("/identities", rc.AuthHandl.FetchIdentities)
+	authGroup.Delete("/identities/:provider", rc.AuthHandl.UnlinkIdentity)
 	authGroup.Get("/profile", rc.AuthHandl.Profile)
 }
 
 func (rc *RouteConfig) WidgetsRoutes() {
-	widgetGroup := rc.App.Group("/api/widgets")
+	widgetGroup := rc.App.Group("/api/widgets", middlewares.ScopeMiddleware("widgets"))
 
 	widgetGroup.Get("", rc.WidgetHandl.SearchWidgets)
 	widgetGroup.Get("/favorite", rc.WidgetHandl.FetchFavoriteWidgets)
 	widgetGroup.Get("/:widget", rc.WidgetHandl.GetWidgetById)
 
-	widgetGroup.Patch("/like/:widget", rc.WidgetHandl.Like)
-	widgetGroup.Patch("/dislike/:widget", rc.WidgetHandl.Dislike)
+	widgetGroup.Post("/:widget/reactions", rc.ReactionHandl.ReactWidget)
+
+	widgetGroup.Delete("/:widget/reactions/:reaction", rc.ReactionHandl.UnreactWidget)
 }
 
 func (rc *RouteConfig) TemplatesRoutes() {
-	templateGroup := rc.App.Group("/api/templates")
+	templateGroup := rc.App.Group("/api/templates", middlewares.ScopeMiddleware("templates"))
 
 	templateGroup.Post("", rc.TemplateHandl.CreateTemplate)
+	templateGroup.Post("/:template/comments", rc.CommentHandl.CreateTemplateComment)
+	templateGroup.Post("/:template/ratings", rc.TemplateHandl.Rate)
+	templateGroup.Post("/:template/reactions", rc.ReactionHandl.ReactTemplate)
 
 	templateGroup.Delete("/:template", rc.TemplateHandl.DeleteTemplate)
+	templateGroup.Delete("/:template/comments/:comment", rc.CommentHandl.DeleteTemplateComment)
+	templateGroup.Delete("/:template/ratings", rc.TemplateHandl.DeleteRating)
+	templateGroup.Delete("/:template/reactions/:reaction", rc.ReactionHandl.UnreactTemplate)
 
 	templateGroup.Get("", rc.TemplateHandl.SearchTemplate)
 	templateGroup.Get("/favorite", rc.TemplateHandl.FetchFavoriteTemplates)
 	templateGroup.Get("/:template", rc.TemplateHandl.GetTemplate)
+	templateGroup.Get("/:template/comments", rc.CommentHandl.FetchTemplateComments)
+	templateGroup.Get("/:template/ratings", rc.TemplateHandl.FetchRatings)
 
 	templateGroup.Patch("", rc.TemplateHandl.UpdateTemplate)
-	templateGroup.Patch("/like/:template", rc.TemplateHandl.Like)
-	templateGroup.Patch("/dislike/:template", rc.TemplateHandl.Dislike)
+	templateGroup.Patch("/:template/comments/:comment/resolve", rc.CommentHandl.ResolveTemplateComment)
+	templateGroup.Patch("/:template/comments/:comment/unresolve", rc.CommentHandl.UnresolveTemplateComment)
 
 }
 
 func (rc *RouteConfig) ReadmesRoutes() {
-	readmeGroup := rc.App.Group("/api/readmes")
+	readmeGroup := rc.App.Group("/api/readmes", middlewares.ScopeMiddleware("readmes"))
 
 	readmeGroup.Post("", rc.ReadmeHandl.CreateReadme)
+	readmeGroup.Post("/:readme/collaborators", rc.ReadmeHandl.AddCollaborator)
+	readmeGroup.Post("/:readme/comments", rc.CommentHandl.CreateReadmeComment)
 
 	readmeGroup.Delete("/:readme", rc.ReadmeHandl.DeleteReadme)
+	readmeGroup.Delete("/:readme/collaborators/:user", rc.ReadmeHandl.RemoveCollaborator)
+	readmeGroup.Delete("/:readme/comments/:comment", rc.CommentHandl.DeleteReadmeComment)
 
 	readmeGroup.Patch("", rc.ReadmeHandl.UpdateReadme)
+	readmeGroup.Patch("/:readme/collaborators/:user", rc.ReadmeHandl.UpdateCollaborator)
+	readmeGroup.Patch("/:readme/comments/:comment/resolve", rc.CommentHandl.ResolveReadmeComment)
+	readmeGroup.Patch("/:readme/comments/:comment/unresolve", rc.CommentHandl.UnresolveReadmeComment)
 
 	readmeGroup.Get("", rc.ReadmeHandl.FetchReadmesByUser)
+	readmeGroup.Get("/shared", rc.ReadmeHandl.FetchSharedReadmes)
 	readmeGroup.Get("/:readme", rc.ReadmeHandl.GetReadmeById)
+	readmeGroup.Get("/:readme/collaborators", rc.ReadmeHandl.FetchCollaborators)
+	readmeGroup.Get("/:readme/comments", rc.CommentHandl.FetchReadmeComments)
+	readmeGroup.Get("/:readme/live", middlewares.SessionOnlyMiddleware(), rc.CollabHandl.EditReadme)
+}
+
+func (rc *RouteConfig) AdminRoutes() {
+	adminGroup := rc.App.Group("/api/admin", middlewares.SessionOnlyMiddleware(), middlewares.RoleMiddleware(models.RoleAdmin))
+
+	adminGroup.Patch("/users/:user/role", rc.AdminHandl.ChangeUserRole)
+}
+
+func (rc *RouteConfig) NotificationsRoutes() {
+	notificationGroup := rc.App.Group("/api/notifications", middlewares.ScopeMiddleware("notifications"))
+
+	notificationGroup.Get("", rc.NotificationHandl.FetchNotifications)
+	notificationGroup.Get("/unread", rc.NotificationHandl.UnreadCount)
+	notificationGroup.Get("/stream", rc.NotificationHandl.Stream)
+	notificationGroup.Get("/preferences", rc.NotificationHandl.FetchPreferences)
+
+	notificationGroup.Patch("/read", rc.NotificationHandl.MarkAllRead)
+	notificationGroup.Patch("/preferences", rc.NotificationHandl.UpdatePreferences)
+	notificationGroup.Patch("/:notification/read", rc.NotificationHandl.MarkRead)
+}
+
+func (rc *RouteConfig) OrganizationsRoutes() {
+	orgGroup := rc.App.Group("/api/orgs", middlewares.ScopeMiddleware("orgs"))
+
+	orgGroup.Post("", rc.OrganizationHandl.CreateOrganization)
+	orgGroup.Post("/:org/members", rc.OrganizationHandl.AddMember)
+
+	orgGroup.Get("", rc.OrganizationHandl.FetchOrganizations)
+	orgGroup.Get("/:org", rc.OrganizationHandl.GetOrganization)
+	orgGroup.Get("/:org/members", rc.OrganizationHandl.FetchMembers)
+	orgGroup.Get("/:org/templates", rc.OrganizationHandl.FetchTemplates)
+	orgGroup.Get("/:org/readmes", rc.OrganizationHandl.FetchReadmes)
+
+	orgGroup.Patch("/:org", rc.OrganizationHandl.UpdateOrganization)
+	orgGroup.Patch("/:org/members/:user", rc.OrganizationHandl.UpdateMember)
+
+	orgGroup.Delete("/:org", rc.OrganizationHandl.DeleteOrganization)
+	orgGroup.Delete("/:org/members/:user", rc.OrganizationHandl.RemoveMember)
+}
+
+func (rc *RouteConfig) CollectionsRoutes() {
+	collectionGroup := rc.App.Group("/api/collections", middlewares.ScopeMiddleware("collections"))
+
+	collectionGroup.Post("", rc.CollectionHandl.CreateCollection)
+	collectionGroup.Post("/:collection/items", rc.CollectionHandl.AddItem)
+	collectionGroup.Post("/:collection/share", rc.CollectionHandl.Share)
+	collectionGroup.Post("/:collection/follow", rc.CollectionHandl.Follow)
+
+	collectionGroup.Get("", rc.CollectionHandl.SearchCollections)
+	collectionGroup.Get("/followed", rc.CollectionHandl.FetchFollowedCollections)
+	collectionGroup.Get("/shared/:token", rc.CollectionHandl.GetSharedCollection)
+	collectionGroup.Get("/user/:user", rc.CollectionHandl.FetchUserCollections)
+	collectionGroup.Get("/:collection", rc.CollectionHandl.GetCollection)
+
+	collectionGroup.Patch("/:collection", rc.CollectionHandl.UpdateCollection)
+	collectionGroup.Patch("/:collection/items", rc.CollectionHandl.ReorderItems)
+
+	collectionGroup.Delete("/:collection", rc.CollectionHandl.DeleteCollection)
+	collectionGroup.Delete("/:collection/items/:item", rc.CollectionHandl.RemoveItem)
+	collectionGroup.Delete("/:collection/share", rc.CollectionHandl.Unshare)
+	collectionGroup.Delete("/:collection/follow", rc.CollectionHandl.Unfollow)
+}
+
+func (rc *RouteConfig) WellKnownRoutes() {
+	wellKnownGroup := rc.App.Group("/.well-known")
+
+	wellKnownGroup.Get("/jwks.json", rc.AuthHandl.JWKS)
 }
@@ -8,7 +8,10 @@ import (
 	"os"
 	"readmeow/internal/config"
 	"readmeow/internal/delivery/middlewares"
+	"readmeow/internal/domain/services"
+	"readmeow/internal/domain/services/utils"
 	"readmeow/pkg/monitoring"
+	"strings"
 	"time"
 
 	_ "readmeow/docs"
@@ -18,6 +21,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/swagger"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp"
 )
 
 type Server struct {
@@ -30,15 +34,27 @@ const (
 	register           = "/api/auth/register"
 	verify             = "/api/auth/verify"
 	newcode            = "/api/auth/newcode"
+	refresh            = "/api/auth/refresh"
+	forgotPassword     = "/api/auth/password/forgot"
+	resetPassword      = "/api/auth/password/reset"
+	twoFactorLogin     = "/api/auth/2fa/login"
+	magicLink          = "/api/auth/magic"
+	magicLinkLogin     = "/api/auth/magic/verify"
 	googleAuth         = "/api/auth/google"
 	googleAuthCallback = "/api/auth/google/callback"
 	githubAuth         = "/api/auth/github"
 	githubAuthCallback = "/api/auth/github/callback"
 	fetchTemplates     = "/api/templates"
 	fetchWidgets       = "/api/widgets"
+	fetchCollections   = "/api/collections"
+	oidcAuth           = "/api/auth/oidc/%s"
+	oidcAuthCallback   = "/api/auth/oidc/%s/callback"
+	jwks               = "/.well-known/jwks.json"
+	csrfToken          = "/api/auth/csrf"
+	notificationStream = "/api/notifications/stream"
 )
 
-func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.AppConfig, ps *monitoring.PrometheusSetup) *Server {
+func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, ocfg config.OAuthConfig, apcfg config.AppConfig, ncfg config.NotificationsConfig, ps *monitoring.PrometheusSetup, as services.AuthServ, ks *utils.KeySet) *Server {
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  time.Duration(scfg.ReadTimeout),
 		WriteTimeout: time.Duration(scfg.WriteTimeout),
@@ -46,6 +62,15 @@ func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.Ap
 		AppName:      apcfg.Name,
 		ErrorHandler: middlewares.ErrorHandler,
 	})
+	// The write timeout is a deadline for the whole response, which would cut
+	// the notification stream after a few seconds.
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+		if path == notificationStream {
+			return fasthttp.RequestConfig{WriteTimeout: ncfg.StreamTimeout}
+		}
+		return fasthttp.RequestConfig{}
+	}
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
 	metrics := &http.Server{
@@ -58,7 +83,7 @@ func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.Ap
 		AllowCredentials: true,
 		AllowMethods:     "GET,POST,DELETE,PATCH,OPTIONS",
 		ExposeHeaders:    "Content-Length",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-CSRF-Token",
 	})
 
 	swaggerGroup := app.Group("/api/swagger")
@@ -72,12 +97,21 @@ func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.Ap
 		register:           true,
 		verify:             true,
 		newcode:            true,
+		refresh:            true,
+		forgotPassword:     true,
+		resetPassword:      true,
+		twoFactorLogin:     true,
+		magicLink:          true,
+		magicLinkLogin:     true,
 		googleAuth:         true,
 		googleAuthCallback: true,
 		githubAuth:         true,
 		githubAuthCallback: true,
 		fetchTemplates:     true,
 		fetchWidgets:       true,
+		fetchCollections:   true,
+		jwks:               true,
+		csrfToken:          true,
 	}
 
 	validAlreadyLoginPaths := map[string]bool{
@@ -85,12 +119,33 @@ func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.Ap
 		register:           true,
 		verify:             true,
 		newcode:            true,
+		twoFactorLogin:     true,
+		magicLink:          true,
+		magicLinkLogin:     true,
 		googleAuth:         true,
 		googleAuthCallback: true,
 		githubAuth:         true,
 		githubAuthCallback: true,
 	}
 
+	validCSRFExemptPaths := map[string]bool{
+		login:          true,
+		register:       true,
+		verify:         true,
+		newcode:        true,
+		forgotPassword: true,
+		resetPassword:  true,
+		twoFactorLogin: true,
+		magicLink:      true,
+		magicLinkLogin: true,
+	}
+
+	for _, p := range ocfg.Providers {
+		validAuthPaths[fmt.Sprintf(oidcAuth, p.Name)] = true
+		validAuthPaths[fmt.Sprintf(oidcAuthCallback, p.Name)] = true
+		validAlreadyLoginPaths[fmt.Sprintf(oidcAuth, p.Name)] = true
+	}
+
 	swaggerGroup.Use(
 		corsMiddleware,
 		middlewares.ValidIpsMiddleware(validIps),
@@ -112,7 +167,8 @@ func NewServer(scfg config.ServerConfig, acfg config.AuthConfig, apcfg config.Ap
 	app.Use(
 		log,
 		corsMiddleware,
-		middlewares.AuthMiddleware(acfg, validAuthPaths),
+		middlewares.CSRFMiddleware(validCSRFExemptPaths),
+		middlewares.AuthMiddleware(ks, as, validAuthPaths),
 		middlewares.AlreadyLoginCheck(validAlreadyLoginPaths),
 		middlewares.RateLimiterMiddleware(scfg),
 		middlewares.RequestTimeoutMiddleware(acfg.TokenTTL),
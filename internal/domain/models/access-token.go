@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccessToken struct {
+	Id           uuid.UUID  `json:"id"`
+	UserId       uuid.UUID  `json:"user_id"`
+	Name         string     `json:"name"`
+	Token        []byte     `json:"-"`
+	Scopes       []string   `json:"scopes"`
+	CreateTime   time.Time  `json:"create_time"`
+	LastUsedTime *time.Time `json:"last_used_time"`
+	ExpiredTime  time.Time  `json:"expired_time"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReadmeCollaborator struct {
+	ReadmeId   uuid.UUID  `json:"readme_id"`
+	UserId     uuid.UUID  `json:"user_id"`
+	Access     string     `json:"access"`
+	InvitedBy  *uuid.UUID `json:"invited_by"`
+	CreateTime time.Time  `json:"create_time"`
+}
+
+type ReadmeCollaboratorUser struct {
+	ReadmeCollaborator
+	Nickname string `json:"nickname"`
+	Handle   string `json:"handle"`
+	Avatar   string `json:"avatar"`
+}
+
+type SharedReadme struct {
+	Readme
+	Access string `json:"access"`
+}
+
+// ReadmeAccessOwner is never stored: it stands for whoever may delete the
+// readme and manage its collaborators.
+const (
+	ReadmeAccessViewer = "viewer"
+	ReadmeAccessEditor = "editor"
+	ReadmeAccessOwner  = "owner"
+)
+
+var readmeAccessRanks = map[string]int{
+	ReadmeAccessViewer: 1,
+	ReadmeAccessEditor: 2,
+	ReadmeAccessOwner:  3,
+}
+
+// ReadmeAccessAtLeast reports whether the access level allows everything the
+// minimal one does. No access allows nothing.
+func ReadmeAccessAtLeast(access, min string) bool {
+	return readmeAccessRanks[access] > 0 && readmeAccessRanks[access] >= readmeAccessRanks[min]
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Collection struct {
+	Id             uuid.UUID `json:"id"`
+	OwnerId        uuid.UUID `json:"owner_id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	IsPublic       bool      `json:"is_public"`
+	ShareToken     *string   `json:"share_token"`
+	NumOfFollowers uint32    `json:"num_of_followers"`
+	NumOfItems     uint32    `json:"num_of_items"`
+	CreateTime     time.Time `json:"create_time"`
+	LastUpdateTime time.Time `json:"last_update_time"`
+}
+
+type CollectionWithOwner struct {
+	Collection
+	OwnerNickname string `json:"owner_nickname"`
+	OwnerAvatar   string `json:"owner_avatar"`
+}
+
+// CollectionItem holds either a template or a widget.
+type CollectionItem struct {
+	Id           uuid.UUID  `json:"id"`
+	CollectionId uuid.UUID  `json:"collection_id"`
+	TemplateId   *uuid.UUID `json:"template_id"`
+	WidgetId     *uuid.UUID `json:"widget_id"`
+	Position     int        `json:"position"`
+	AddTime      time.Time  `json:"add_time"`
+}
+
+type CollectionTemplateItem struct {
+	CollectionItem
+	Template TemplateWithOwner `json:"template"`
+}
+
+type CollectionWidgetItem struct {
+	CollectionItem
+	Widget Widget `json:"widget"`
+}
+
+const (
+	CollectionItemTemplate = "template"
+	CollectionItemWidget   = "widget"
+)
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment belongs to either a readme or a template. Thread roots are anchored
+// to a block through AnchorField and AnchorIndex, except discussion comments
+// on public templates; replies take the anchor of their thread.
+type Comment struct {
+	Id          uuid.UUID  `json:"id"`
+	ReadmeId    *uuid.UUID `json:"readme_id"`
+	TemplateId  *uuid.UUID `json:"template_id"`
+	ParentId    *uuid.UUID `json:"parent_id"`
+	AuthorId    uuid.UUID  `json:"author_id"`
+	AnchorField *string    `json:"anchor_field"`
+	AnchorIndex *int       `json:"anchor_index"`
+	Body        string     `json:"body"`
+	IsResolved  bool       `json:"is_resolved"`
+	ResolvedBy  *uuid.UUID `json:"resolved_by"`
+	ResolveTime *time.Time `json:"resolve_time"`
+	CreateTime  time.Time  `json:"create_time"`
+}
+
+type CommentWithAuthor struct {
+	Comment
+	AuthorNickname string `json:"author_nickname"`
+	AuthorHandle   string `json:"author_handle"`
+	AuthorAvatar   string `json:"author_avatar"`
+}
+
+const (
+	CommentTargetReadme   = "readme"
+	CommentTargetTemplate = "template"
+)
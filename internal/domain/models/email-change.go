@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EmailChange struct {
+	UserId      uuid.UUID `json:"user_id"`
+	NewEmail    string    `json:"new_email"`
+	Code        []byte    `json:"-"`
+	Attempts    int       `json:"attempts"`
+	ExpiredTime time.Time `json:"expired_time"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	FollowerId uuid.UUID `json:"follower_id"`
+	FolloweeId uuid.UUID `json:"followee_id"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+type FollowUser struct {
+	Id         uuid.UUID `json:"id"`
+	Nickname   string    `json:"nickname"`
+	Avatar     string    `json:"avatar"`
+	FollowTime time.Time `json:"follow_time"`
+}
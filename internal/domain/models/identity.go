@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Identity struct {
+	Provider   string    `json:"provider"`
+	ProviderId string    `json:"provider_id"`
+	UserId     uuid.UUID `json:"user_id"`
+	Email      string    `json:"email"`
+	CreateTime time.Time `json:"create_time"`
+}
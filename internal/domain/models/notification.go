@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	Id         uuid.UUID  `json:"id"`
+	UserId     uuid.UUID  `json:"user_id"`
+	ActorId    uuid.UUID  `json:"actor_id"`
+	Type       string     `json:"type"`
+	EntityId   *uuid.UUID `json:"entity_id"`
+	IsRead     bool       `json:"is_read"`
+	CreateTime time.Time  `json:"create_time"`
+}
+
+type NotificationWithActor struct {
+	Notification
+	ActorNickname string `json:"actor_nickname"`
+	ActorAvatar   string `json:"actor_avatar"`
+}
+
+const (
+	NotificationTemplateLiked = "template_liked"
+	NotificationTemplateUsed  = "template_used"
+	NotificationNewFollower   = "new_follower"
+	NotificationReadmeShared  = "readme_shared"
+	// Collection follows point to the followed collection.
+	NotificationCollectionFollowed = "collection_followed"
+	// Mentions point to the readme or template the comment was left on.
+	NotificationReadmeMention   = "readme_comment_mention"
+	NotificationTemplateMention = "template_comment_mention"
+)
+
+var NotificationTypes = []string{
+	NotificationTemplateLiked,
+	NotificationTemplateUsed,
+	NotificationNewFollower,
+	NotificationReadmeShared,
+	NotificationReadmeMention,
+	NotificationTemplateMention,
+	NotificationCollectionFollowed,
+}
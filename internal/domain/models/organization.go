@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Organization struct {
+	Id          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreateTime  time.Time `json:"create_time"`
+}
+
+type OrganizationWithRole struct {
+	Organization
+	Role string `json:"role"`
+}
+
+type OrganizationMember struct {
+	OrgId      uuid.UUID `json:"org_id"`
+	UserId     uuid.UUID `json:"user_id"`
+	Role       string    `json:"role"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+type OrganizationMemberUser struct {
+	OrganizationMember
+	Nickname string `json:"nickname"`
+	Handle   string `json:"handle"`
+	Avatar   string `json:"avatar"`
+}
+
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleEditor = "editor"
+	OrgRoleViewer = "viewer"
+)
+
+var orgRoleRanks = map[string]int{
+	OrgRoleViewer: 1,
+	OrgRoleEditor: 2,
+	OrgRoleAdmin:  3,
+	OrgRoleOwner:  4,
+}
+
+// OrgRoleAtLeast reports whether the organization role grants everything the
+// minimal role does. Unknown roles grant nothing.
+func OrgRoleAtLeast(role, min string) bool {
+	return orgRoleRanks[role] > 0 && orgRoleRanks[role] >= orgRoleRanks[min]
+}
@@ -0,0 +1,26 @@
+package models
+
+// Reactions are kept per user, so one user may leave several different
+// reactions on the same template or widget.
+const (
+	ReactionLike   = "like"
+	ReactionLove   = "love"
+	ReactionLaugh  = "laugh"
+	ReactionHooray = "hooray"
+	ReactionRocket = "rocket"
+	ReactionEyes   = "eyes"
+)
+
+var Reactions = []string{
+	ReactionLike,
+	ReactionLove,
+	ReactionLaugh,
+	ReactionHooray,
+	ReactionRocket,
+	ReactionEyes,
+}
+
+const (
+	ReactionTargetTemplate = "template"
+	ReactionTargetWidget   = "widget"
+)
@@ -18,4 +18,5 @@ type Readme struct {
 	RenderOrder    []string            `json:"render_order"`
 	CreateTime     time.Time           `json:"create_time"`
 	LastUpdateTime time.Time           `json:"last_update_time"`
+	OrgId          *uuid.UUID          `json:"org_id"`
 }
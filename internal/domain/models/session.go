@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	Id           uuid.UUID `json:"id"`
+	UserId       uuid.UUID `json:"user_id"`
+	RefreshToken []byte    `json:"-"`
+	Jti          string    `json:"jti"`
+	UserAgent    string    `json:"user_agent"`
+	Ip           string    `json:"ip"`
+	CreateTime   time.Time `json:"create_time"`
+	LastUsedTime time.Time `json:"last_used_time"`
+	ExpiredTime  time.Time `json:"expired_time"`
+}
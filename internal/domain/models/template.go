@@ -21,6 +21,10 @@ type Template struct {
 	CreateTime     time.Time           `json:"create_time"`
 	LastUpdateTime time.Time           `json:"last_update_time"`
 	IsPublic       bool                `json:"is_public"`
+	OrgId          *uuid.UUID          `json:"org_id"`
+	Rating         float64             `json:"rating"`
+	NumOfRatings   uint32              `json:"num_of_ratings"`
+	Reactions      map[string]uint32   `json:"reactions"`
 }
 
 type TemplateWithOwner struct {
@@ -28,3 +32,19 @@ type TemplateWithOwner struct {
 	OwnerAvatar   string `json:"owner_avatar"`
 	OwnerNickname string `json:"owner_nickname"`
 }
+
+type TemplateRating struct {
+	TemplateId uuid.UUID `json:"template_id"`
+	UserId     uuid.UUID `json:"user_id"`
+	Stars      int       `json:"stars"`
+	Review     *string   `json:"review"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+type TemplateRatingUser struct {
+	TemplateRating
+	UserNickname string `json:"user_nickname"`
+	UserHandle   string `json:"user_handle"`
+	UserAvatar   string `json:"user_avatar"`
+}
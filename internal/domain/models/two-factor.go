@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactor struct {
+	UserId     uuid.UUID `json:"user_id"`
+	Secret     string    `json:"-"`
+	Enabled    bool      `json:"enabled"`
+	LastStep   int64     `json:"-"`
+	CreateTime time.Time `json:"create_time"`
+}
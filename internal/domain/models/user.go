@@ -7,12 +7,30 @@ import (
 )
 
 type User struct {
-	Id uuid.UUID `json:"id"`
+	Id     uuid.UUID `json:"id"`
+	Handle string    `json:"handle"`
 	Credentials
 	Avatar         string    `json:"avatar"`
 	TimeOfRegister time.Time `json:"time_of_register"`
 	NumOfTemplates uint32    `json:"num_of_templates"`
 	NumOfReadmes   uint32    `json:"num_of_readmes"`
+	Role           string    `json:"role"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func IsPrivileged(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
+type HandleHistory struct {
+	Handle     string    `json:"handle"`
+	UserId     uuid.UUID `json:"user_id"`
+	ChangeTime time.Time `json:"change_time"`
 }
 
 type Credentials struct {
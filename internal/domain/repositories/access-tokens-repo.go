@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type AccessTokenRepo interface {
+	Create(ctx context.Context, token *models.AccessToken) error
+	GetByToken(ctx context.Context, token []byte) (*models.AccessToken, error)
+	FetchByUser(ctx context.Context, uid string) ([]models.AccessToken, error)
+	Touch(ctx context.Context, id string) error
+	Delete(ctx context.Context, id, uid string) error
+	DeleteAllByUser(ctx context.Context, uid string) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type accessTokenRepo struct {
+	Storage *storage.Storage
+}
+
+func NewAccessTokenRepo(s *storage.Storage) AccessTokenRepo {
+	return &accessTokenRepo{
+		Storage: s,
+	}
+}
+
+func (ar *accessTokenRepo) Create(ctx context.Context, token *models.AccessToken) error {
+	op := "accessTokenRepo.Create"
+	query := "INSERT INTO personal_access_tokens (id, user_id, name, token, scopes, create_time, last_used_time, expired_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8)"
+	qd := helpers.NewQueryData(ctx, ar.Storage, op, query, token.Id, token.UserId, token.Name, token.Token, token.Scopes, token.CreateTime, token.LastUsedTime, token.ExpiredTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepo) GetByToken(ctx context.Context, token []byte) (*models.AccessToken, error) {
+	op := "accessTokenRepo.GetByToken"
+	query := "SELECT * FROM personal_access_tokens WHERE token = $1"
+	accessToken := &models.AccessToken{}
+	qd := helpers.NewQueryData(ctx, ar.Storage, op, query, token)
+	if err := qd.QueryRowWithTx(accessToken); err != nil {
+		return nil, err
+	}
+	return accessToken, nil
+}
+
+func (ar *accessTokenRepo) FetchByUser(ctx context.Context, uid string) ([]models.AccessToken, error) {
+	op := "accessTokenRepo.FetchByUser"
+	query := "SELECT * FROM personal_access_tokens WHERE user_id = $1 ORDER BY create_time DESC"
+	rows, err := ar.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	tokens := []models.AccessToken{}
+	for rows.Next() {
+		token := models.AccessToken{}
+		if err := rows.Scan(
+			&token.Id,
+			&token.UserId,
+			&token.Name,
+			&token.Token,
+			&token.Scopes,
+			&token.CreateTime,
+			&token.LastUsedTime,
+			&token.ExpiredTime,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (ar *accessTokenRepo) Touch(ctx context.Context, id string) error {
+	op := "accessTokenRepo.Touch"
+	query := "UPDATE personal_access_tokens SET last_used_time = NOW() WHERE id = $1 AND (last_used_time IS NULL OR last_used_time < NOW() - INTERVAL '1 minute')"
+	if _, err := ar.Storage.Pool.Exec(ctx, query, id); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (ar *accessTokenRepo) Delete(ctx context.Context, id, uid string) error {
+	op := "accessTokenRepo.Delete"
+	query := "DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, ar.Storage, op, query, id, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepo) DeleteAllByUser(ctx context.Context, uid string) error {
+	op := "accessTokenRepo.DeleteAllByUser"
+	query := "DELETE FROM personal_access_tokens WHERE user_id = $1"
+	qd := helpers.NewQueryData(ctx, ar.Storage, op, query, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepo) DeleteExpired(ctx context.Context) error {
+	op := "accessTokenRepo.DeleteExpired"
+	query := "DELETE FROM personal_access_tokens WHERE expired_time <= NOW()"
+	if _, err := ar.Storage.Pool.Exec(ctx, query); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,512 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"readmeow/internal/config"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/search"
+	"readmeow/pkg/storage"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9/esutil"
+	s "github.com/elastic/go-elasticsearch/v9/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v9/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v9/typedapi/types/enums/sortorder"
+)
+
+type CollectionRepo interface {
+	Create(ctx context.Context, collection *models.Collection) error
+	Get(ctx context.Context, id string) (*models.CollectionWithOwner, error)
+	GetByShareToken(ctx context.Context, token string) (*models.CollectionWithOwner, error)
+	Update(ctx context.Context, id, title, description string, isPublic *bool, updateTime time.Time) error
+	SetShareToken(ctx context.Context, id string, token *string) error
+	Delete(ctx context.Context, id string) error
+	FetchByUser(ctx context.Context, uid string, showPrivate bool, amount, page uint) ([]models.CollectionWithOwner, error)
+	FetchFollowed(ctx context.Context, uid string, amount, page uint) ([]models.CollectionWithOwner, error)
+	AddItem(ctx context.Context, item *models.CollectionItem) error
+	RemoveItem(ctx context.Context, id, itemId string) error
+	FetchItemIds(ctx context.Context, id string) ([]string, error)
+	Reorder(ctx context.Context, id string, itemIds []string) error
+	FetchTemplateItems(ctx context.Context, id, uid string) ([]models.CollectionTemplateItem, error)
+	FetchWidgetItems(ctx context.Context, id string) ([]models.CollectionWidgetItem, error)
+	Follow(ctx context.Context, id, uid string, followTime time.Time) (bool, error)
+	Unfollow(ctx context.Context, id, uid string) error
+	IsFollowing(ctx context.Context, id, uid string) (bool, error)
+	AddFollowers(ctx context.Context, id string, delta int) error
+	Search(ctx context.Context, amount, page uint, query string, sort map[string]string) ([]models.CollectionWithOwner, error)
+	MustBulk(ctx context.Context, cfg config.SearchConfig) error
+}
+
+type collectionRepo struct {
+	Storage      *storage.Storage
+	SearchClient *search.SearchClient
+}
+
+func NewCollectionRepo(s *storage.Storage, sc *search.SearchClient) CollectionRepo {
+	return &collectionRepo{
+		Storage:      s,
+		SearchClient: sc,
+	}
+}
+
+const collectionColumns = "c.id, c.owner_id, c.title, c.description, c.is_public, c.share_token, c.num_of_followers, (SELECT COUNT(*) FROM collection_items i WHERE i.collection_id = c.id), c.create_time, c.last_update_time, u.nickname, u.avatar"
+
+const collectionItemColumns = "i.id, i.collection_id, i.template_id, i.widget_id, i.position, i.add_time"
+
+func collectionFields(c *models.CollectionWithOwner) []any {
+	return []any{
+		&c.Id,
+		&c.OwnerId,
+		&c.Title,
+		&c.Description,
+		&c.IsPublic,
+		&c.ShareToken,
+		&c.NumOfFollowers,
+		&c.NumOfItems,
+		&c.CreateTime,
+		&c.LastUpdateTime,
+		&c.OwnerNickname,
+		&c.OwnerAvatar,
+	}
+}
+
+func collectionItemFields(i *models.CollectionItem) []any {
+	return []any{
+		&i.Id,
+		&i.CollectionId,
+		&i.TemplateId,
+		&i.WidgetId,
+		&i.Position,
+		&i.AddTime,
+	}
+}
+
+func (cr *collectionRepo) Create(ctx context.Context, collection *models.Collection) error {
+	op := "collectionRepo.Create"
+	query := "INSERT INTO collections (id, owner_id, title, description, is_public, create_time, last_update_time) VALUES($1,$2,$3,$4,$5,$6,$7)"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, collection.Id, collection.OwnerId, collection.Title, collection.Description, collection.IsPublic, collection.CreateTime, collection.LastUpdateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) Get(ctx context.Context, id string) (*models.CollectionWithOwner, error) {
+	op := "collectionRepo.Get"
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.id = $1", collectionColumns)
+	return cr.get(ctx, op, query, id)
+}
+
+func (cr *collectionRepo) GetByShareToken(ctx context.Context, token string) (*models.CollectionWithOwner, error) {
+	op := "collectionRepo.GetByShareToken"
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.share_token = $1", collectionColumns)
+	return cr.get(ctx, op, query, token)
+}
+
+func (cr *collectionRepo) get(ctx context.Context, op, query string, arg any) (*models.CollectionWithOwner, error) {
+	collection := &models.CollectionWithOwner{}
+	if err := cr.Storage.Pool.QueryRow(ctx, query, arg).Scan(collectionFields(collection)...); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return nil, errs.ErrNotFound(op)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
+	return collection, nil
+}
+
+// Update keeps the fields left empty and always moves the last update time,
+// so adding or reordering items can bump it alone.
+func (cr *collectionRepo) Update(ctx context.Context, id, title, description string, isPublic *bool, updateTime time.Time) error {
+	op := "collectionRepo.Update"
+	query := "UPDATE collections SET title = COALESCE(NULLIF($2, ''), title), description = COALESCE(NULLIF($3, ''), description), is_public = COALESCE($4, is_public), last_update_time = $5 WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, title, description, isPublic, updateTime)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetShareToken replaces the share link token, a nil token turns the link off.
+func (cr *collectionRepo) SetShareToken(ctx context.Context, id string, token *string) error {
+	op := "collectionRepo.SetShareToken"
+	query := "UPDATE collections SET share_token = $2 WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, token)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) Delete(ctx context.Context, id string) error {
+	op := "collectionRepo.Delete"
+	query := "DELETE FROM collections WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) FetchByUser(ctx context.Context, uid string, showPrivate bool, amount, page uint) ([]models.CollectionWithOwner, error) {
+	op := "collectionRepo.FetchByUser"
+	p := ""
+	if !showPrivate {
+		p = "AND c.is_public = TRUE"
+	}
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.owner_id = $1 %s ORDER BY c.last_update_time DESC OFFSET $2 LIMIT $3", collectionColumns, p)
+	return cr.fetch(ctx, op, query, uid, amount*page-amount, amount)
+}
+
+// FetchFollowed lists followed collections that are still public, recently
+// followed first.
+func (cr *collectionRepo) FetchFollowed(ctx context.Context, uid string, amount, page uint) ([]models.CollectionWithOwner, error) {
+	op := "collectionRepo.FetchFollowed"
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN collection_follows f ON f.collection_id = c.id JOIN users u ON u.id = c.owner_id WHERE f.user_id = $1 AND c.is_public = TRUE ORDER BY f.create_time DESC OFFSET $2 LIMIT $3", collectionColumns)
+	return cr.fetch(ctx, op, query, uid, amount*page-amount, amount)
+}
+
+func (cr *collectionRepo) fetch(ctx context.Context, op, query string, args ...any) ([]models.CollectionWithOwner, error) {
+	rows, err := cr.Storage.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	collections := []models.CollectionWithOwner{}
+	for rows.Next() {
+		collection := models.CollectionWithOwner{}
+		if err := rows.Scan(collectionFields(&collection)...); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+// AddItem appends the item to the end of the collection.
+func (cr *collectionRepo) AddItem(ctx context.Context, item *models.CollectionItem) error {
+	op := "collectionRepo.AddItem"
+	query := "INSERT INTO collection_items (id, collection_id, template_id, widget_id, position, add_time) SELECT $1, $2, $3, $4, COALESCE(MAX(position) + 1, 0), $5 FROM collection_items WHERE collection_id = $2"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, item.Id, item.CollectionId, item.TemplateId, item.WidgetId, item.AddTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) RemoveItem(ctx context.Context, id, itemId string) error {
+	op := "collectionRepo.RemoveItem"
+	query := "DELETE FROM collection_items WHERE collection_id = $1 AND id = $2"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, itemId)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) FetchItemIds(ctx context.Context, id string) ([]string, error) {
+	op := "collectionRepo.FetchItemIds"
+	query := "SELECT id FROM collection_items WHERE collection_id = $1 ORDER BY position"
+	rows, err := cr.Storage.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var itemId string
+		if err := rows.Scan(&itemId); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		ids = append(ids, itemId)
+	}
+	return ids, nil
+}
+
+// Reorder places the items in the given order.
+func (cr *collectionRepo) Reorder(ctx context.Context, id string, itemIds []string) error {
+	op := "collectionRepo.Reorder"
+	query := "UPDATE collection_items i SET position = o.position - 1 FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, position) WHERE i.collection_id = $1 AND i.id = o.id"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, itemIds)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FetchTemplateItems lists templates of the collection the user can see:
+// public ones, their own and those of their organizations.
+func (cr *collectionRepo) FetchTemplateItems(ctx context.Context, id, uid string) ([]models.CollectionTemplateItem, error) {
+	op := "collectionRepo.FetchTemplateItems"
+	query := fmt.Sprintf("SELECT %s, t.id, t.owner_id, t.title, t.image, t.description, t.likes, t.num_of_users, t.last_update_time, t.is_public, t.rating, t.num_of_ratings, t.reactions, u.nickname, u.avatar FROM collection_items i JOIN templates t ON t.id = i.template_id JOIN users u ON u.id = t.owner_id WHERE i.collection_id = $1 AND (t.is_public = TRUE OR t.owner_id = $2 OR t.org_id IN (SELECT org_id FROM organization_members WHERE user_id = $2)) ORDER BY i.position", collectionItemColumns)
+	rows, err := cr.Storage.Pool.Query(ctx, query, id, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	items := []models.CollectionTemplateItem{}
+	for rows.Next() {
+		item := models.CollectionTemplateItem{}
+		t := &item.Template
+		fields := append(collectionItemFields(&item.CollectionItem),
+			&t.Id,
+			&t.OwnerId,
+			&t.Title,
+			&t.Image,
+			&t.Description,
+			&t.Likes,
+			&t.NumOfUsers,
+			&t.LastUpdateTime,
+			&t.IsPublic,
+			&t.Rating,
+			&t.NumOfRatings,
+			&t.Reactions,
+			&t.OwnerNickname,
+			&t.OwnerAvatar,
+		)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (cr *collectionRepo) FetchWidgetItems(ctx context.Context, id string) ([]models.CollectionWidgetItem, error) {
+	op := "collectionRepo.FetchWidgetItems"
+	query := fmt.Sprintf("SELECT %s, w.id, w.title, w.image, w.description, w.type, w.tags, w.link, w.likes, w.num_of_users, w.reactions FROM collection_items i JOIN widgets w ON w.id = i.widget_id WHERE i.collection_id = $1 ORDER BY i.position", collectionItemColumns)
+	rows, err := cr.Storage.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	items := []models.CollectionWidgetItem{}
+	for rows.Next() {
+		item := models.CollectionWidgetItem{}
+		w := &item.Widget
+		fields := append(collectionItemFields(&item.CollectionItem),
+			&w.Id,
+			&w.Title,
+			&w.Image,
+			&w.Description,
+			&w.Type,
+			&w.Tags,
+			&w.Link,
+			&w.Likes,
+			&w.NumOfUsers,
+			&w.Reactions,
+		)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Follow reports whether a new follow was stored; following the same
+// collection again is not an error.
+func (cr *collectionRepo) Follow(ctx context.Context, id, uid string, followTime time.Time) (bool, error) {
+	op := "collectionRepo.Follow"
+	query := "INSERT INTO collection_follows (collection_id, user_id, create_time) VALUES($1,$2,$3) ON CONFLICT (collection_id, user_id) DO NOTHING"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, uid, followTime)
+	if err := qd.InsertWithTx(); err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (cr *collectionRepo) Unfollow(ctx context.Context, id, uid string) error {
+	op := "collectionRepo.Unfollow"
+	query := "DELETE FROM collection_follows WHERE collection_id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *collectionRepo) IsFollowing(ctx context.Context, id, uid string) (bool, error) {
+	op := "collectionRepo.IsFollowing"
+	query := "SELECT EXISTS(SELECT 1 FROM collection_follows WHERE collection_id = $1 AND user_id = $2)"
+	var following bool
+	if err := cr.Storage.Pool.QueryRow(ctx, query, id, uid).Scan(&following); err != nil {
+		return false, errs.NewAppError(op, err)
+	}
+	return following, nil
+}
+
+func (cr *collectionRepo) AddFollowers(ctx context.Context, id string, delta int) error {
+	op := "collectionRepo.AddFollowers"
+	query := "UPDATE collections SET num_of_followers = GREATEST(num_of_followers + $2, 0) WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, delta)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Search finds public collections only.
+func (cr *collectionRepo) Search(ctx context.Context, amount, page uint, query string, sort map[string]string) ([]models.CollectionWithOwner, error) {
+	op := "collectionRepo.Search"
+	var mainQuery types.Query
+	if query != "" {
+		mainQuery = types.Query{
+			MultiMatch: &types.MultiMatchQuery{
+				Query:     query,
+				Fields:    []string{"Title^2", "Description"},
+				Fuzziness: "AUTO",
+			},
+		}
+	} else {
+		mainQuery = types.Query{
+			MatchAll: &types.MatchAllQuery{},
+		}
+	}
+
+	sorts := []types.SortCombinations{}
+	if len(sort) > 0 {
+		validSortFields := map[string]bool{
+			"NumOfFollowers": true,
+			"NumOfItems":     true,
+			"LastUpdateTime": true,
+		}
+		validSortValues := map[string]bool{
+			"desc": true,
+			"asc":  true,
+		}
+		for k, v := range sort {
+			if !validSortFields[k] {
+				return nil, errs.ErrInvalidFields(op)
+			} else if !validSortValues[strings.ToLower(v)] {
+				return nil, errs.ErrInvalidValues(op)
+			}
+			order := &sortorder.Desc
+			if v == "asc" {
+				order = &sortorder.Asc
+			}
+			sorts = append(sorts, &types.SortOptions{
+				SortOptions: map[string]types.FieldSort{
+					k: {
+						Order: order,
+					},
+				},
+			})
+		}
+	}
+
+	ptr := func(i int) *int {
+		return &i
+	}
+
+	res, err := cr.SearchClient.Client.Search().Index("collections").Request(&s.Request{
+		From: ptr(int(amount*page - amount)),
+		Size: ptr(int(amount)),
+		Query: &types.Query{
+			Bool: &types.BoolQuery{
+				Must: []types.Query{mainQuery},
+			},
+		},
+		Sort:    sorts,
+		Source_: &types.SourceFilter{Includes: []string{"id"}},
+	}).Do(ctx)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	ids := []string{}
+	for _, hit := range res.Hits.Hits {
+		if hit.Id_ != nil {
+			ids = append(ids, *hit.Id_)
+		}
+	}
+	if len(ids) == 0 {
+		return []models.CollectionWithOwner{}, nil
+	}
+	collections, err := cr.getByIds(ctx, ids)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+
+	return collections, nil
+}
+
+// getByIds keeps the order of ids and drops collections made private since
+// they were indexed.
+func (cr *collectionRepo) getByIds(ctx context.Context, ids []string) ([]models.CollectionWithOwner, error) {
+	op := "collectionRepo.SearchPreparing.GetByIds"
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.id = ANY($1) AND c.is_public = TRUE", collectionColumns)
+	found, err := cr.fetch(ctx, op, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]models.CollectionWithOwner, len(found))
+	for _, c := range found {
+		byId[c.Id.String()] = c
+	}
+	collections := make([]models.CollectionWithOwner, 0, len(found))
+	for _, id := range ids {
+		if c, ok := byId[id]; ok {
+			collections = append(collections, c)
+		}
+	}
+	return collections, nil
+}
+
+func (cr *collectionRepo) MustBulk(ctx context.Context, cfg config.SearchConfig) error {
+	op := "collectionRepo.SearchPreparing.Bulk"
+	query := fmt.Sprintf("SELECT %s FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.is_public = TRUE", collectionColumns)
+	collections, err := cr.fetch(ctx, op, query)
+	if err != nil {
+		return err
+	}
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client: cr.SearchClient.Client,
+		Index:  "collections",
+	})
+	if err != nil {
+		return errs.NewAppError(op, err)
+	}
+	type doc struct {
+		Id             string
+		OwnerId        string
+		Title          string
+		Description    string
+		NumOfFollowers uint32
+		NumOfItems     uint32
+		LastUpdateTime time.Time
+	}
+	for _, c := range collections {
+		data, err := json.Marshal(doc{
+			Id:             c.Id.String(),
+			OwnerId:        c.OwnerId.String(),
+			Title:          c.Title,
+			Description:    c.Description,
+			NumOfFollowers: c.NumOfFollowers,
+			NumOfItems:     c.NumOfItems,
+			LastUpdateTime: c.LastUpdateTime,
+		})
+		if err != nil {
+			return errs.NewAppError(op, err)
+		}
+		if err := bi.Add(ctx, esutil.BulkIndexerItem{
+			Action:     "index",
+			DocumentID: c.Id.String(),
+			Body:       bytes.NewReader(data),
+		}); err != nil {
+			return errs.NewAppError(op, err)
+		}
+	}
+	if err := bi.Close(ctx); err != nil {
+		return errs.NewAppError(op, fmt.Errorf("%w, stats: flushed - %d, failed - %d", err, bi.Stats().NumFlushed, bi.Stats().NumFailed))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type CommentRepo interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	Get(ctx context.Context, id string) (*models.Comment, error)
+	Delete(ctx context.Context, id string) error
+	Resolve(ctx context.Context, id, uid string, resolveTime time.Time) error
+	Unresolve(ctx context.Context, id string) error
+	FetchThreads(ctx context.Context, target, targetId string, anchored bool, amount, page uint) ([]models.CommentWithAuthor, error)
+	FetchReplies(ctx context.Context, parentIds []string) ([]models.CommentWithAuthor, error)
+}
+
+type commentRepo struct {
+	Storage *storage.Storage
+}
+
+func NewCommentRepo(s *storage.Storage) CommentRepo {
+	return &commentRepo{
+		Storage: s,
+	}
+}
+
+const commentColumns = "c.id, c.readme_id, c.template_id, c.parent_id, c.author_id, c.anchor_field, c.anchor_index, c.body, c.is_resolved, c.resolved_by, c.resolve_time, c.create_time"
+
+var commentTargetColumns = map[string]string{
+	models.CommentTargetReadme:   "readme_id",
+	models.CommentTargetTemplate: "template_id",
+}
+
+func (cr *commentRepo) Create(ctx context.Context, comment *models.Comment) error {
+	op := "commentRepo.Create"
+	query := "INSERT INTO comments (id, readme_id, template_id, parent_id, author_id, anchor_field, anchor_index, body, create_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, comment.Id, comment.ReadmeId, comment.TemplateId, comment.ParentId, comment.AuthorId, comment.AnchorField, comment.AnchorIndex, comment.Body, comment.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *commentRepo) Get(ctx context.Context, id string) (*models.Comment, error) {
+	op := "commentRepo.Get"
+	query := fmt.Sprintf("SELECT %s FROM comments c WHERE c.id = $1", commentColumns)
+	comment := &models.Comment{}
+	if err := cr.Storage.Pool.QueryRow(ctx, query, id).Scan(
+		&comment.Id,
+		&comment.ReadmeId,
+		&comment.TemplateId,
+		&comment.ParentId,
+		&comment.AuthorId,
+		&comment.AnchorField,
+		&comment.AnchorIndex,
+		&comment.Body,
+		&comment.IsResolved,
+		&comment.ResolvedBy,
+		&comment.ResolveTime,
+		&comment.CreateTime,
+	); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return nil, errs.ErrNotFound(op)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
+	return comment, nil
+}
+
+// Delete removes the comment together with its replies.
+func (cr *commentRepo) Delete(ctx context.Context, id string) error {
+	op := "commentRepo.Delete"
+	query := "DELETE FROM comments WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *commentRepo) Resolve(ctx context.Context, id, uid string, resolveTime time.Time) error {
+	op := "commentRepo.Resolve"
+	query := "UPDATE comments SET is_resolved = TRUE, resolved_by = $2, resolve_time = $3 WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id, uid, resolveTime)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *commentRepo) Unresolve(ctx context.Context, id string) error {
+	op := "commentRepo.Unresolve"
+	query := "UPDATE comments SET is_resolved = FALSE, resolved_by = NULL, resolve_time = NULL WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, cr.Storage, op, query, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FetchThreads lists thread roots of the readme or template, oldest first.
+// anchored picks review threads over general discussion.
+func (cr *commentRepo) FetchThreads(ctx context.Context, target, targetId string, anchored bool, amount, page uint) ([]models.CommentWithAuthor, error) {
+	op := "commentRepo.FetchThreads"
+	column, ok := commentTargetColumns[target]
+	if !ok {
+		return nil, errs.ErrInvalidFields(op)
+	}
+	query := fmt.Sprintf("SELECT %s, u.nickname, u.handle, u.avatar FROM comments c JOIN users u ON u.id = c.author_id WHERE c.%s = $1 AND c.parent_id IS NULL AND (c.anchor_field IS NOT NULL) = $2 ORDER BY c.create_time OFFSET $3 LIMIT $4", commentColumns, column)
+	return cr.fetch(ctx, op, query, targetId, anchored, amount*page-amount, amount)
+}
+
+// FetchReplies lists replies of the threads, oldest first.
+func (cr *commentRepo) FetchReplies(ctx context.Context, parentIds []string) ([]models.CommentWithAuthor, error) {
+	op := "commentRepo.FetchReplies"
+	query := fmt.Sprintf("SELECT %s, u.nickname, u.handle, u.avatar FROM comments c JOIN users u ON u.id = c.author_id WHERE c.parent_id = ANY($1) ORDER BY c.create_time", commentColumns)
+	return cr.fetch(ctx, op, query, parentIds)
+}
+
+func (cr *commentRepo) fetch(ctx context.Context, op, query string, args ...any) ([]models.CommentWithAuthor, error) {
+	rows, err := cr.Storage.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	comments := []models.CommentWithAuthor{}
+	for rows.Next() {
+		comment := models.CommentWithAuthor{}
+		if err := rows.Scan(
+			&comment.Id,
+			&comment.ReadmeId,
+			&comment.TemplateId,
+			&comment.ParentId,
+			&comment.AuthorId,
+			&comment.AnchorField,
+			&comment.AnchorIndex,
+			&comment.Body,
+			&comment.IsResolved,
+			&comment.ResolvedBy,
+			&comment.ResolveTime,
+			&comment.CreateTime,
+			&comment.AuthorNickname,
+			&comment.AuthorHandle,
+			&comment.AuthorAvatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type EmailChangeRepo interface {
+	AddCode(ctx context.Context, uid, email string, code []byte, ttl time.Time, attempts int) error
+	Get(ctx context.Context, uid string) (*models.EmailChange, error)
+	MinusAttempts(ctx context.Context, uid string) (int, error)
+	Delete(ctx context.Context, uid string) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type emailChangeRepo struct {
+	Storage *storage.Storage
+}
+
+func NewEmailChangeRepo(s *storage.Storage) EmailChangeRepo {
+	return &emailChangeRepo{
+		Storage: s,
+	}
+}
+
+func (er *emailChangeRepo) AddCode(ctx context.Context, uid, email string, code []byte, ttl time.Time, attempts int) error {
+	op := "emailChangeRepo.AddCode"
+	query := "INSERT INTO email_changes (user_id, new_email, code, attempts, expired_time) VALUES($1,$2,$3,$4,$5) ON CONFLICT (user_id) DO UPDATE SET new_email = EXCLUDED.new_email, code = EXCLUDED.code, attempts = EXCLUDED.attempts, expired_time = EXCLUDED.expired_time"
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid, email, code, attempts, ttl)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (er *emailChangeRepo) Get(ctx context.Context, uid string) (*models.EmailChange, error) {
+	op := "emailChangeRepo.Get"
+	query := "SELECT user_id, new_email, code, attempts, expired_time FROM email_changes WHERE user_id = $1"
+	change := &models.EmailChange{}
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid)
+	if err := qd.QueryRowWithTx(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (er *emailChangeRepo) MinusAttempts(ctx context.Context, uid string) (int, error) {
+	op := "emailChangeRepo.MinusAttempts"
+	query := "UPDATE email_changes SET attempts = attempts - 1 WHERE user_id = $1 AND attempts > 0 RETURNING attempts"
+	var attempts int
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid)
+	if err := qd.QueryRowWithTx(&attempts); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func (er *emailChangeRepo) Delete(ctx context.Context, uid string) error {
+	op := "emailChangeRepo.Delete"
+	query := "DELETE FROM email_changes WHERE user_id = $1"
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (er *emailChangeRepo) DeleteExpired(ctx context.Context) error {
+	op := "emailChangeRepo.DeleteExpired"
+	query := "DELETE FROM email_changes WHERE expired_time <= NOW()"
+	if _, err := er.Storage.Pool.Exec(ctx, query); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type ErasureRepo interface {
+	Create(ctx context.Context, uid string) error
+	FetchPending(ctx context.Context, limit int) ([]string, error)
+	IsPending(ctx context.Context, uid string) (bool, error)
+	Erase(ctx context.Context, uid, baseTid string) ([]string, error)
+}
+
+type erasureRepo struct {
+	Storage *storage.Storage
+}
+
+func NewErasureRepo(s *storage.Storage) ErasureRepo {
+	return &erasureRepo{
+		Storage: s,
+	}
+}
+
+func (er *erasureRepo) Create(ctx context.Context, uid string) error {
+	op := "erasureRepo.Create"
+	query := "INSERT INTO erasure_requests (user_id, request_time) VALUES($1,NOW()) ON CONFLICT (user_id) DO NOTHING"
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid)
+	if err := qd.InsertWithTx(); err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		return err
+	}
+	return nil
+}
+
+func (er *erasureRepo) FetchPending(ctx context.Context, limit int) ([]string, error) {
+	op := "erasureRepo.FetchPending"
+	query := "SELECT user_id FROM erasure_requests ORDER BY request_time LIMIT $1"
+	rows, err := er.Storage.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	uids := []string{}
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
+func (er *erasureRepo) IsPending(ctx context.Context, uid string) (bool, error) {
+	op := "erasureRepo.IsPending"
+	query := "SELECT EXISTS(SELECT 1 FROM erasure_requests WHERE user_id = $1)"
+	var pending bool
+	qd := helpers.NewQueryData(ctx, er.Storage, op, query, uid)
+	if err := qd.QueryRowWithTx(&pending); err != nil {
+		return false, err
+	}
+	return pending, nil
+}
+
+// Erase must run inside a transaction. It returns image URLs of the deleted
+// rows so they can be removed from the cloud storage after commit.
+func (er *erasureRepo) Erase(ctx context.Context, uid, baseTid string) ([]string, error) {
+	op := "erasureRepo.Erase"
+	tx, ok := storage.GetTx(ctx)
+	if !ok {
+		return nil, errs.NewAppError(op, errors.New("transaction is required"))
+	}
+	// Organizations outlive their members: content the user created there is
+	// handed to the highest ranked remaining member, who also becomes owner
+	// if the user was the only one. Organizations without other members go
+	// away and their content is erased with the rest of the user's.
+	successor := `SELECT DISTINCT ON (m.org_id) m.org_id, m.user_id FROM organization_members m
+		WHERE m.user_id <> $1 AND m.org_id IN (SELECT org_id FROM organization_members WHERE user_id = $1)
+		ORDER BY m.org_id, CASE m.role WHEN 'owner' THEN 4 WHEN 'admin' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END DESC, m.create_time`
+	orgs := []string{
+		`DELETE FROM organizations WHERE id IN (
+			SELECT org_id FROM organization_members WHERE org_id IN (SELECT org_id FROM organization_members WHERE user_id = $1)
+			GROUP BY org_id HAVING bool_and(user_id = $1)
+		)`,
+		`WITH moved AS (
+			UPDATE templates t SET owner_id = s.user_id FROM (` + successor + `) s
+			WHERE t.org_id = s.org_id AND t.owner_id = $1 RETURNING t.owner_id
+		) UPDATE users u SET num_of_templates = u.num_of_templates + c.cnt FROM (
+			SELECT owner_id, COUNT(*) AS cnt FROM moved GROUP BY owner_id
+		) c WHERE u.id = c.owner_id`,
+		`WITH moved AS (
+			UPDATE readmes r SET owner_id = s.user_id FROM (` + successor + `) s
+			WHERE r.org_id = s.org_id AND r.owner_id = $1 RETURNING r.owner_id
+		) UPDATE users u SET num_of_readmes = u.num_of_readmes + c.cnt FROM (
+			SELECT owner_id, COUNT(*) AS cnt FROM moved GROUP BY owner_id
+		) c WHERE u.id = c.owner_id`,
+		`UPDATE organization_members m SET role = 'owner' FROM (` + successor + `) s
+		WHERE m.org_id = s.org_id AND m.user_id = s.user_id AND NOT EXISTS (
+			SELECT 1 FROM organization_members o WHERE o.org_id = m.org_id AND o.role = 'owner' AND o.user_id <> $1
+		)`,
+	}
+	for _, query := range orgs {
+		if _, err := tx.Exec(ctx, query, uid); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+	counters := []string{
+		`UPDATE widgets w SET num_of_users = GREATEST(w.num_of_users - c.cnt, 0) FROM (
+			SELECT k.wid, COUNT(*) AS cnt FROM (
+				SELECT DISTINCT r.id, jsonb_object_keys(x) AS wid FROM readmes r, unnest(r.widgets) x WHERE r.owner_id = $1
+				UNION ALL
+				SELECT DISTINCT t.id, jsonb_object_keys(x) AS wid FROM templates t, unnest(t.widgets) x WHERE t.owner_id = $1
+			) k GROUP BY k.wid
+		) c WHERE w.id::text = c.wid`,
+		"UPDATE templates t SET num_of_users = GREATEST(t.num_of_users - c.cnt, 0) FROM (SELECT template_id, COUNT(*) AS cnt FROM readmes WHERE owner_id = $1 GROUP BY template_id) c WHERE t.id = c.template_id",
+		"UPDATE templates t SET reactions = COALESCE((SELECT jsonb_object_agg(c.reaction, c.cnt) FROM (SELECT reaction, COUNT(*) AS cnt FROM template_reactions WHERE template_id = t.id AND user_id <> $1 GROUP BY reaction) c), '{}'), likes = (SELECT COUNT(*) FROM template_reactions WHERE template_id = t.id AND user_id <> $1 AND reaction = 'like') WHERE t.id IN (SELECT template_id FROM template_reactions WHERE user_id = $1)",
+		"UPDATE templates t SET rating = COALESCE(c.avg, 0), num_of_ratings = c.cnt FROM (SELECT r.template_id, AVG(o.stars) AS avg, COUNT(o.stars) AS cnt FROM template_ratings r LEFT JOIN template_ratings o ON o.template_id = r.template_id AND o.user_id <> $1 WHERE r.user_id = $1 GROUP BY r.template_id) c WHERE t.id = c.template_id",
+		"UPDATE widgets w SET reactions = COALESCE((SELECT jsonb_object_agg(c.reaction, c.cnt) FROM (SELECT reaction, COUNT(*) AS cnt FROM widget_reactions WHERE widget_id = w.id AND user_id <> $1 GROUP BY reaction) c), '{}'), likes = (SELECT COUNT(*) FROM widget_reactions WHERE widget_id = w.id AND user_id <> $1 AND reaction = 'like') WHERE w.id IN (SELECT widget_id FROM widget_reactions WHERE user_id = $1)",
+		"UPDATE collections SET num_of_followers = GREATEST(num_of_followers - 1, 0) WHERE id IN (SELECT collection_id FROM collection_follows WHERE user_id = $1)",
+	}
+	for _, query := range counters {
+		if _, err := tx.Exec(ctx, query, uid); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+	collect := func(query string) ([]string, error) {
+		rows, err := tx.Query(ctx, query, uid)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		res := []string{}
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				return nil, err
+			}
+			res = append(res, s)
+		}
+		return res, rows.Err()
+	}
+	images := []string{}
+	readmeImages, err := collect("DELETE FROM readmes WHERE owner_id = $1 RETURNING image")
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	images = append(images, readmeImages...)
+	res, err := tx.Exec(ctx, "UPDATE readmes SET template_id = $2 WHERE template_id IN (SELECT id FROM templates WHERE owner_id = $1)", uid, baseTid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	if moved := res.RowsAffected(); moved > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE templates SET num_of_users = num_of_users + $2 WHERE id = $1", baseTid, moved); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+	templateImages, err := collect("DELETE FROM templates WHERE owner_id = $1 RETURNING image")
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	images = append(images, templateImages...)
+	avatars, err := collect("DELETE FROM users WHERE id = $1 RETURNING avatar")
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	if len(avatars) == 0 {
+		return nil, errs.ErrNotFound(op)
+	}
+	images = append(images, avatars...)
+	return images, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type FollowRepo interface {
+	Create(ctx context.Context, follow *models.Follow) (bool, error)
+	Delete(ctx context.Context, followerId, followeeId string) error
+	FetchFollowers(ctx context.Context, uid string, amount, page uint) ([]models.FollowUser, error)
+	FetchFollowing(ctx context.Context, uid string, amount, page uint) ([]models.FollowUser, error)
+	Counts(ctx context.Context, uid string) (uint32, uint32, error)
+}
+
+type followRepo struct {
+	Storage *storage.Storage
+}
+
+func NewFollowRepo(s *storage.Storage) FollowRepo {
+	return &followRepo{
+		Storage: s,
+	}
+}
+
+// Create reports whether a new follow was stored; following the same user
+// again is not an error.
+func (fr *followRepo) Create(ctx context.Context, follow *models.Follow) (bool, error) {
+	op := "followRepo.Create"
+	query := "INSERT INTO follows (follower_id, followee_id, create_time) VALUES($1,$2,$3) ON CONFLICT (follower_id, followee_id) DO NOTHING"
+	qd := helpers.NewQueryData(ctx, fr.Storage, op, query, follow.FollowerId, follow.FolloweeId, follow.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (fr *followRepo) Delete(ctx context.Context, followerId, followeeId string) error {
+	op := "followRepo.Delete"
+	query := "DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2"
+	qd := helpers.NewQueryData(ctx, fr.Storage, op, query, followerId, followeeId)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fr *followRepo) FetchFollowers(ctx context.Context, uid string, amount, page uint) ([]models.FollowUser, error) {
+	op := "followRepo.FetchFollowers"
+	query := "SELECT u.id, u.nickname, u.avatar, f.create_time FROM follows f JOIN users u ON u.id = f.follower_id WHERE f.followee_id = $1 ORDER BY f.create_time DESC OFFSET $2 LIMIT $3"
+	return fr.fetchUsers(ctx, op, query, uid, amount, page)
+}
+
+func (fr *followRepo) FetchFollowing(ctx context.Context, uid string, amount, page uint) ([]models.FollowUser, error) {
+	op := "followRepo.FetchFollowing"
+	query := "SELECT u.id, u.nickname, u.avatar, f.create_time FROM follows f JOIN users u ON u.id = f.followee_id WHERE f.follower_id = $1 ORDER BY f.create_time DESC OFFSET $2 LIMIT $3"
+	return fr.fetchUsers(ctx, op, query, uid, amount, page)
+}
+
+func (fr *followRepo) fetchUsers(ctx context.Context, op, query, uid string, amount, page uint) ([]models.FollowUser, error) {
+	rows, err := fr.Storage.Pool.Query(ctx, query, uid, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	users := []models.FollowUser{}
+	for rows.Next() {
+		user := models.FollowUser{}
+		if err := rows.Scan(
+			&user.Id,
+			&user.Nickname,
+			&user.Avatar,
+			&user.FollowTime,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// Counts returns the number of followers and followed users.
+func (fr *followRepo) Counts(ctx context.Context, uid string) (uint32, uint32, error) {
+	op := "followRepo.Counts"
+	query := "SELECT (SELECT COUNT(*) FROM follows WHERE followee_id = $1), (SELECT COUNT(*) FROM follows WHERE follower_id = $1)"
+	var followers, following uint32
+	if err := fr.Storage.Pool.QueryRow(ctx, query, uid).Scan(&followers, &following); err != nil {
+		return 0, 0, errs.NewAppError(op, err)
+	}
+	return followers, following, nil
+}
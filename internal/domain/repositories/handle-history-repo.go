@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type HandleHistoryRepo interface {
+	Create(ctx context.Context, history *models.HandleHistory) error
+	GetByHandle(ctx context.Context, handle string) (*models.HandleHistory, error)
+	Delete(ctx context.Context, handle, uid string) error
+}
+
+type handleHistoryRepo struct {
+	Storage *storage.Storage
+}
+
+func NewHandleHistoryRepo(s *storage.Storage) HandleHistoryRepo {
+	return &handleHistoryRepo{
+		Storage: s,
+	}
+}
+
+func (hr *handleHistoryRepo) Create(ctx context.Context, history *models.HandleHistory) error {
+	op := "handleHistoryRepo.Create"
+	query := "INSERT INTO handle_history (handle, user_id, change_time) VALUES($1,$2,$3)"
+	qd := helpers.NewQueryData(ctx, hr.Storage, op, query, history.Handle, history.UserId, history.ChangeTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (hr *handleHistoryRepo) GetByHandle(ctx context.Context, handle string) (*models.HandleHistory, error) {
+	op := "handleHistoryRepo.GetByHandle"
+	query := "SELECT handle, user_id, change_time FROM handle_history WHERE LOWER(handle) = LOWER($1)"
+	history := &models.HandleHistory{}
+	if err := hr.Storage.Pool.QueryRow(ctx, query, handle).Scan(&history.Handle, &history.UserId, &history.ChangeTime); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return nil, errs.ErrNotFound(op)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
+	return history, nil
+}
+
+// Delete drops the user's old handle when they take it back, so it is not
+// both current and redirected.
+func (hr *handleHistoryRepo) Delete(ctx context.Context, handle, uid string) error {
+	op := "handleHistoryRepo.Delete"
+	query := "DELETE FROM handle_history WHERE LOWER(handle) = LOWER($1) AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, hr.Storage, op, query, handle, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		return err
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package helpers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"readmeow/internal/domain/models"
 	"readmeow/pkg/errs"
 	"readmeow/pkg/storage"
@@ -26,6 +27,15 @@ func NewQueryData(ctx context.Context, s *storage.Storage, op string, query stri
 	}
 }
 
+// LockRow locks the row with the id until the transaction of ctx ends, so
+// that counters kept on it are recounted by one transaction at a time.
+func LockRow(ctx context.Context, s *storage.Storage, op, table, id string) error {
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = $1 FOR UPDATE", table)
+	var rid string
+	qd := NewQueryData(ctx, s, op, query, id)
+	return qd.QueryRowWithTx(&rid)
+}
+
 func (qd *QueryData) InsertWithTx() error {
 	if tx, ok := storage.GetTx(qd.Ctx); ok {
 		res, err := tx.Exec(qd.Ctx, qd.Query, qd.Args...)
@@ -106,6 +116,8 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			&e.TimeOfRegister,
 			&e.NumOfTemplates,
 			&e.NumOfReadmes,
+			&e.Role,
+			&e.Handle,
 		}
 		if err := qd.queryRow(userData...); err != nil {
 			return err
@@ -124,6 +136,7 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			&e.RenderOrder,
 			&e.CreateTime,
 			&e.LastUpdateTime,
+			&e.OrgId,
 		}
 		if err := qd.queryRow(readmeData...); err != nil {
 			return err
@@ -145,6 +158,10 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			&e.LastUpdateTime,
 			&e.NumOfUsers,
 			&e.IsPublic,
+			&e.OrgId,
+			&e.Rating,
+			&e.NumOfRatings,
+			&e.Reactions,
 		}
 		if err := qd.queryRow(templateData...); err != nil {
 			return err
@@ -166,6 +183,10 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			&e.LastUpdateTime,
 			&e.NumOfUsers,
 			&e.IsPublic,
+			&e.OrgId,
+			&e.Rating,
+			&e.NumOfRatings,
+			&e.Reactions,
 			&e.OwnerNickname,
 			&e.OwnerAvatar,
 		}
@@ -184,6 +205,7 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			&e.Link,
 			&e.Likes,
 			&e.NumOfUsers,
+			&e.Reactions,
 		}
 		if err := qd.queryRow(widgetData...); err != nil {
 			return err
@@ -200,6 +222,73 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			return err
 		}
 		return nil
+	case *models.Session:
+		sessionData := []any{
+			&e.Id,
+			&e.UserId,
+			&e.RefreshToken,
+			&e.Jti,
+			&e.UserAgent,
+			&e.Ip,
+			&e.CreateTime,
+			&e.LastUsedTime,
+			&e.ExpiredTime,
+		}
+		if err := qd.queryRow(sessionData...); err != nil {
+			return err
+		}
+		return nil
+	case *models.EmailChange:
+		emailChangeData := []any{
+			&e.UserId,
+			&e.NewEmail,
+			&e.Code,
+			&e.Attempts,
+			&e.ExpiredTime,
+		}
+		if err := qd.queryRow(emailChangeData...); err != nil {
+			return err
+		}
+		return nil
+	case *models.TwoFactor:
+		twoFactorData := []any{
+			&e.UserId,
+			&e.Secret,
+			&e.Enabled,
+			&e.LastStep,
+			&e.CreateTime,
+		}
+		if err := qd.queryRow(twoFactorData...); err != nil {
+			return err
+		}
+		return nil
+	case *models.AccessToken:
+		accessTokenData := []any{
+			&e.Id,
+			&e.UserId,
+			&e.Name,
+			&e.Token,
+			&e.Scopes,
+			&e.CreateTime,
+			&e.LastUsedTime,
+			&e.ExpiredTime,
+		}
+		if err := qd.queryRow(accessTokenData...); err != nil {
+			return err
+		}
+		return nil
+	case *models.Identity:
+		identityData := []any{
+			&e.Provider,
+			&e.ProviderId,
+			&e.UserId,
+			&e.Email,
+			&e.CreateTime,
+		}
+		if err := qd.queryRow(identityData...); err != nil {
+			return err
+		}
+		return nil
 	case *int:
 		if err := qd.queryRow(e); err != nil {
 			return err
@@ -215,6 +304,21 @@ func (qd *QueryData) QueryRowWithTx(entity any) error {
 			return err
 		}
 		return nil
+	case *bool:
+		if err := qd.queryRow(e); err != nil {
+			return err
+		}
+		return nil
+	case *[]string:
+		if err := qd.queryRow(e); err != nil {
+			return err
+		}
+		return nil
+	case *map[string]uint32:
+		if err := qd.queryRow(e); err != nil {
+			return err
+		}
+		return nil
 	default:
 		return errs.NewAppError(qd.Operation, errors.New("invalid entity"))
 	}
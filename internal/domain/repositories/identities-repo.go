@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type IdentityRepo interface {
+	Create(ctx context.Context, identity *models.Identity) error
+	Get(ctx context.Context, provider, pid string) (*models.Identity, error)
+	FetchByUser(ctx context.Context, uid string) ([]models.Identity, error)
+	Delete(ctx context.Context, uid, provider string) error
+}
+
+type identityRepo struct {
+	Storage *storage.Storage
+}
+
+func NewIdentityRepo(s *storage.Storage) IdentityRepo {
+	return &identityRepo{
+		Storage: s,
+	}
+}
+
+func (ir *identityRepo) Create(ctx context.Context, identity *models.Identity) error {
+	op := "identityRepo.Create"
+	query := "INSERT INTO identities (provider, provider_id, user_id, email, create_time) VALUES($1,$2,$3,$4,$5)"
+	qd := helpers.NewQueryData(ctx, ir.Storage, op, query, identity.Provider, identity.ProviderId, identity.UserId, identity.Email, identity.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ir *identityRepo) Get(ctx context.Context, provider, pid string) (*models.Identity, error) {
+	op := "identityRepo.Get"
+	query := "SELECT provider, provider_id, user_id, email, create_time FROM identities WHERE provider = $1 AND provider_id = $2"
+	identity := &models.Identity{}
+	qd := helpers.NewQueryData(ctx, ir.Storage, op, query, provider, pid)
+	if err := qd.QueryRowWithTx(identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (ir *identityRepo) FetchByUser(ctx context.Context, uid string) ([]models.Identity, error) {
+	op := "identityRepo.FetchByUser"
+	query := "SELECT provider, provider_id, user_id, email, create_time FROM identities WHERE user_id = $1 ORDER BY create_time"
+	rows, err := ir.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	identities := []models.Identity{}
+	for rows.Next() {
+		identity := models.Identity{}
+		if err := rows.Scan(
+			&identity.Provider,
+			&identity.ProviderId,
+			&identity.UserId,
+			&identity.Email,
+			&identity.CreateTime,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+func (ir *identityRepo) Delete(ctx context.Context, uid, provider string) error {
+	op := "identityRepo.Delete"
+	query := "DELETE FROM identities WHERE user_id = $1 AND provider = $2"
+	qd := helpers.NewQueryData(ctx, ir.Storage, op, query, uid, provider)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"time"
+)
+
+type LoginAttemptRepo interface {
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Delay(ctx context.Context, key string, ttl time.Duration) error
+	Lock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type loginAttemptRepo struct {
+	Cache *cache.Cache
+}
+
+func NewLoginAttemptRepo(c *cache.Cache) LoginAttemptRepo {
+	return &loginAttemptRepo{
+		Cache: c,
+	}
+}
+
+const (
+	loginAttemptsPrefix = "login_attempts:"
+	loginDelayPrefix    = "login_delay:"
+	loginLockPrefix     = "login_lock:"
+)
+
+func (lr *loginAttemptRepo) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	op := "loginAttemptRepo.AddFailure"
+	pipe := lr.Cache.Redis.TxPipeline()
+	incr := pipe.Incr(ctx, loginAttemptsPrefix+key)
+	pipe.ExpireNX(ctx, loginAttemptsPrefix+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, errs.NewAppError(op, err)
+	}
+	return int(incr.Val()), nil
+}
+
+func (lr *loginAttemptRepo) Delay(ctx context.Context, key string, ttl time.Duration) error {
+	op := "loginAttemptRepo.Delay"
+	if err := lr.Cache.Redis.Set(ctx, loginDelayPrefix+key, 1, ttl).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (lr *loginAttemptRepo) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	op := "loginAttemptRepo.Lock"
+	locked, err := lr.Cache.Redis.SetNX(ctx, loginLockPrefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, errs.NewAppError(op, err)
+	}
+	return locked, nil
+}
+
+func (lr *loginAttemptRepo) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	op := "loginAttemptRepo.BlockedFor"
+	pipe := lr.Cache.Redis.Pipeline()
+	lock := pipe.PTTL(ctx, loginLockPrefix+key)
+	delay := pipe.PTTL(ctx, loginDelayPrefix+key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, errs.NewAppError(op, err)
+	}
+	return max(lock.Val(), delay.Val(), 0), nil
+}
+
+func (lr *loginAttemptRepo) Reset(ctx context.Context, key string) error {
+	op := "loginAttemptRepo.Reset"
+	if err := lr.Cache.Redis.Del(ctx, loginAttemptsPrefix+key, loginDelayPrefix+key, loginLockPrefix+key).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
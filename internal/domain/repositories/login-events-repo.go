@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type LoginEventRepo interface {
+	Create(ctx context.Context, event *models.LoginEvent) error
+	FetchByUser(ctx context.Context, uid string, amount, page uint) ([]models.LoginEvent, error)
+	CheckDevice(ctx context.Context, uid, device, ip string) (bool, bool, error)
+}
+
+type loginEventRepo struct {
+	Storage *storage.Storage
+}
+
+func NewLoginEventRepo(s *storage.Storage) LoginEventRepo {
+	return &loginEventRepo{
+		Storage: s,
+	}
+}
+
+func (lr *loginEventRepo) Create(ctx context.Context, event *models.LoginEvent) error {
+	op := "loginEventRepo.Create"
+	query := "INSERT INTO login_events (id, user_id, method, success, ip, user_agent, device, create_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8)"
+	qd := helpers.NewQueryData(ctx, lr.Storage, op, query, event.Id, event.UserId, event.Method, event.Success, event.Ip, event.UserAgent, event.Device, event.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lr *loginEventRepo) FetchByUser(ctx context.Context, uid string, amount, page uint) ([]models.LoginEvent, error) {
+	op := "loginEventRepo.FetchByUser"
+	query := "SELECT id, user_id, method, success, ip, user_agent, device, create_time FROM login_events WHERE user_id = $1 ORDER BY create_time DESC OFFSET $2 LIMIT $3"
+	rows, err := lr.Storage.Pool.Query(ctx, query, uid, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	events := []models.LoginEvent{}
+	for rows.Next() {
+		event := models.LoginEvent{}
+		if err := rows.Scan(
+			&event.Id,
+			&event.UserId,
+			&event.Method,
+			&event.Success,
+			&event.Ip,
+			&event.UserAgent,
+			&event.Device,
+			&event.CreateTime,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CheckDevice reports whether the user has any successful login yet and
+// whether both the device and the ip were already seen in one.
+func (lr *loginEventRepo) CheckDevice(ctx context.Context, uid, device, ip string) (bool, bool, error) {
+	op := "loginEventRepo.CheckDevice"
+	query := "SELECT COUNT(*) > 0, COALESCE(BOOL_OR(device = $2), FALSE) AND COALESCE(BOOL_OR(ip = $3), FALSE) FROM login_events WHERE user_id = $1 AND success = TRUE"
+	var seen, known bool
+	if err := lr.Storage.Pool.QueryRow(ctx, query, uid, device, ip).Scan(&seen, &known); err != nil {
+		return false, false, errs.NewAppError(op, err)
+	}
+	return seen, known, nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type MagicLinkRepo interface {
+	Create(ctx context.Context, uid string, token []byte, ttl time.Time) error
+	Get(ctx context.Context, token []byte) (string, time.Time, error)
+	Delete(ctx context.Context, token []byte) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type magicLinkRepo struct {
+	Storage *storage.Storage
+}
+
+func NewMagicLinkRepo(s *storage.Storage) MagicLinkRepo {
+	return &magicLinkRepo{
+		Storage: s,
+	}
+}
+
+func (mr *magicLinkRepo) Create(ctx context.Context, uid string, token []byte, ttl time.Time) error {
+	op := "magicLinkRepo.Create"
+	query := "INSERT INTO magic_links (token, user_id, expired_time) VALUES($1,$2,$3) ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, expired_time = EXCLUDED.expired_time"
+	qd := helpers.NewQueryData(ctx, mr.Storage, op, query, token, uid, ttl)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mr *magicLinkRepo) Get(ctx context.Context, token []byte) (string, time.Time, error) {
+	op := "magicLinkRepo.Get"
+	query := "SELECT user_id, expired_time FROM magic_links WHERE token = $1 FOR UPDATE"
+	var (
+		uid string
+		ttl time.Time
+	)
+	if tx, ok := storage.GetTx(ctx); ok {
+		if err := tx.QueryRow(ctx, query, token).Scan(&uid, &ttl); err != nil {
+			if errors.Is(err, storage.ErrNotFound()) {
+				return "", time.Time{}, errs.ErrNotFound(op)
+			}
+			return "", time.Time{}, errs.NewAppError(op, err)
+		}
+		return uid, ttl, nil
+	}
+	if err := mr.Storage.Pool.QueryRow(ctx, query, token).Scan(&uid, &ttl); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return "", time.Time{}, errs.ErrNotFound(op)
+		}
+		return "", time.Time{}, errs.NewAppError(op, err)
+	}
+	return uid, ttl, nil
+}
+
+func (mr *magicLinkRepo) Delete(ctx context.Context, token []byte) error {
+	op := "magicLinkRepo.Delete"
+	query := "DELETE FROM magic_links WHERE token = $1"
+	qd := helpers.NewQueryData(ctx, mr.Storage, op, query, token)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mr *magicLinkRepo) DeleteExpired(ctx context.Context) error {
+	op := "magicLinkRepo.DeleteExpired"
+	query := "DELETE FROM magic_links WHERE expired_time <= NOW()"
+	if _, err := mr.Storage.Pool.Exec(ctx, query); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type NotificationRepo interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	Get(ctx context.Context, id string) (*models.NotificationWithActor, error)
+	FetchByUser(ctx context.Context, uid string, amount, page uint) ([]models.NotificationWithActor, error)
+	FetchAfter(ctx context.Context, uid, id string, limit uint) ([]models.NotificationWithActor, error)
+	CountUnread(ctx context.Context, uid string) (uint32, error)
+	MarkRead(ctx context.Context, id, uid string) error
+	MarkAllRead(ctx context.Context, uid string) error
+	FetchPreferences(ctx context.Context, uid string) (map[string]bool, error)
+	SetPreference(ctx context.Context, uid, notificationType string, enabled bool) error
+	IsEnabled(ctx context.Context, uid, notificationType string) (bool, error)
+	Publish(ctx context.Context, uid string, payload []byte) error
+	Subscribe(ctx context.Context, uid string) (<-chan string, func() error)
+}
+
+type notificationRepo struct {
+	Storage *storage.Storage
+	Cache   *cache.Cache
+}
+
+func NewNotificationRepo(s *storage.Storage, c *cache.Cache) NotificationRepo {
+	return &notificationRepo{
+		Storage: s,
+		Cache:   c,
+	}
+}
+
+const notificationsChannelPrefix = "notifications:"
+
+const notificationWithActorQuery = "SELECT n.id, n.user_id, n.actor_id, n.type, n.entity_id, n.is_read, n.create_time, u.nickname, u.avatar FROM notifications n JOIN users u ON u.id = n.actor_id"
+
+func (nr *notificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	op := "notificationRepo.Create"
+	query := "INSERT INTO notifications (id, user_id, actor_id, type, entity_id, is_read, create_time) VALUES($1,$2,$3,$4,$5,$6,$7)"
+	qd := helpers.NewQueryData(ctx, nr.Storage, op, query, notification.Id, notification.UserId, notification.ActorId, notification.Type, notification.EntityId, notification.IsRead, notification.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *notificationRepo) Get(ctx context.Context, id string) (*models.NotificationWithActor, error) {
+	op := "notificationRepo.Get"
+	query := notificationWithActorQuery + " WHERE n.id = $1"
+	notifications, err := nr.fetch(ctx, op, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, errs.ErrNotFound(op)
+	}
+	return &notifications[0], nil
+}
+
+func (nr *notificationRepo) FetchByUser(ctx context.Context, uid string, amount, page uint) ([]models.NotificationWithActor, error) {
+	op := "notificationRepo.FetchByUser"
+	query := notificationWithActorQuery + " WHERE n.user_id = $1 ORDER BY n.create_time DESC OFFSET $2 LIMIT $3"
+	return nr.fetch(ctx, op, query, uid, amount*page-amount, amount)
+}
+
+// FetchAfter returns the oldest notifications created after the given one,
+// so a reconnecting stream can replay what it missed in order.
+func (nr *notificationRepo) FetchAfter(ctx context.Context, uid, id string, limit uint) ([]models.NotificationWithActor, error) {
+	op := "notificationRepo.FetchAfter"
+	query := notificationWithActorQuery + " WHERE n.user_id = $1 AND n.create_time > (SELECT create_time FROM notifications WHERE id = $2 AND user_id = $1) ORDER BY n.create_time LIMIT $3"
+	return nr.fetch(ctx, op, query, uid, id, limit)
+}
+
+func (nr *notificationRepo) fetch(ctx context.Context, op, query string, args ...any) ([]models.NotificationWithActor, error) {
+	rows, err := nr.Storage.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	notifications := []models.NotificationWithActor{}
+	for rows.Next() {
+		notification := models.NotificationWithActor{}
+		if err := rows.Scan(
+			&notification.Id,
+			&notification.UserId,
+			&notification.ActorId,
+			&notification.Type,
+			&notification.EntityId,
+			&notification.IsRead,
+			&notification.CreateTime,
+			&notification.ActorNickname,
+			&notification.ActorAvatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+func (nr *notificationRepo) CountUnread(ctx context.Context, uid string) (uint32, error) {
+	op := "notificationRepo.CountUnread"
+	query := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = FALSE"
+	var count uint32
+	if err := nr.Storage.Pool.QueryRow(ctx, query, uid).Scan(&count); err != nil {
+		return 0, errs.NewAppError(op, err)
+	}
+	return count, nil
+}
+
+func (nr *notificationRepo) MarkRead(ctx context.Context, id, uid string) error {
+	op := "notificationRepo.MarkRead"
+	query := "UPDATE notifications SET is_read = TRUE WHERE id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, nr.Storage, op, query, id, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *notificationRepo) MarkAllRead(ctx context.Context, uid string) error {
+	op := "notificationRepo.MarkAllRead"
+	query := "UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE"
+	if _, err := nr.Storage.Pool.Exec(ctx, query, uid); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+// FetchPreferences returns the setting of every notification type. A type
+// without a stored preference is enabled.
+func (nr *notificationRepo) FetchPreferences(ctx context.Context, uid string) (map[string]bool, error) {
+	op := "notificationRepo.FetchPreferences"
+	query := "SELECT type, enabled FROM notification_preferences WHERE user_id = $1"
+	rows, err := nr.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		preferences[t] = true
+	}
+	for rows.Next() {
+		var t string
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		preferences[t] = enabled
+	}
+	return preferences, nil
+}
+
+func (nr *notificationRepo) SetPreference(ctx context.Context, uid, notificationType string, enabled bool) error {
+	op := "notificationRepo.SetPreference"
+	query := "INSERT INTO notification_preferences (user_id, type, enabled) VALUES($1,$2,$3) ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled"
+	qd := helpers.NewQueryData(ctx, nr.Storage, op, query, uid, notificationType, enabled)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *notificationRepo) IsEnabled(ctx context.Context, uid, notificationType string) (bool, error) {
+	op := "notificationRepo.IsEnabled"
+	query := "SELECT COALESCE((SELECT enabled FROM notification_preferences WHERE user_id = $1 AND type = $2), TRUE)"
+	var enabled bool
+	if err := nr.Storage.Pool.QueryRow(ctx, query, uid, notificationType).Scan(&enabled); err != nil {
+		return false, errs.NewAppError(op, err)
+	}
+	return enabled, nil
+}
+
+func (nr *notificationRepo) Publish(ctx context.Context, uid string, payload []byte) error {
+	op := "notificationRepo.Publish"
+	if err := nr.Cache.Redis.Publish(ctx, notificationsChannelPrefix+uid, payload).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+// Subscribe listens for notifications published to the user on any instance.
+// The returned channel is closed once the returned func has been called.
+func (nr *notificationRepo) Subscribe(ctx context.Context, uid string) (<-chan string, func() error) {
+	pubsub := nr.Cache.Redis.Subscribe(ctx, notificationsChannelPrefix+uid)
+	messages := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(messages)
+		for msg := range pubsub.Channel() {
+			select {
+			case messages <- msg.Payload:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return messages, func() error {
+		close(done)
+		return pubsub.Close()
+	}
+}
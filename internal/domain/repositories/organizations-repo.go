@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type OrganizationRepo interface {
+	Create(ctx context.Context, org *models.Organization) error
+	Get(ctx context.Context, id string) (*models.Organization, error)
+	Update(ctx context.Context, id, name, description string) error
+	Delete(ctx context.Context, id string) error
+	FetchByUser(ctx context.Context, uid string) ([]models.OrganizationWithRole, error)
+	FetchIds(ctx context.Context, uid string) ([]string, error)
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	UpdateMemberRole(ctx context.Context, orgId, uid, role string) error
+	RemoveMember(ctx context.Context, orgId, uid string) error
+	GetMemberRole(ctx context.Context, orgId, uid string) (string, error)
+	FetchMembers(ctx context.Context, orgId string, amount, page uint) ([]models.OrganizationMemberUser, error)
+	CountOwners(ctx context.Context, orgId string) (int, error)
+}
+
+type organizationRepo struct {
+	Storage *storage.Storage
+}
+
+func NewOrganizationRepo(s *storage.Storage) OrganizationRepo {
+	return &organizationRepo{
+		Storage: s,
+	}
+}
+
+func (or *organizationRepo) Create(ctx context.Context, org *models.Organization) error {
+	op := "organizationRepo.Create"
+	query := "INSERT INTO organizations (id, name, description, create_time) VALUES($1,$2,$3,$4)"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, org.Id, org.Name, org.Description, org.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (or *organizationRepo) Get(ctx context.Context, id string) (*models.Organization, error) {
+	op := "organizationRepo.Get"
+	query := "SELECT id, name, description, create_time FROM organizations WHERE id = $1"
+	org := &models.Organization{}
+	if err := or.Storage.Pool.QueryRow(ctx, query, id).Scan(&org.Id, &org.Name, &org.Description, &org.CreateTime); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return nil, errs.ErrNotFound(op)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
+	return org, nil
+}
+
+func (or *organizationRepo) Update(ctx context.Context, id, name, description string) error {
+	op := "organizationRepo.Update"
+	query := "UPDATE organizations SET name = COALESCE(NULLIF($2, ''), name), description = COALESCE(NULLIF($3, ''), description) WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, id, name, description)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete removes the organization with its memberships. Its templates and
+// readmes stay with their owners as personal content.
+func (or *organizationRepo) Delete(ctx context.Context, id string) error {
+	op := "organizationRepo.Delete"
+	query := "DELETE FROM organizations WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (or *organizationRepo) FetchByUser(ctx context.Context, uid string) ([]models.OrganizationWithRole, error) {
+	op := "organizationRepo.FetchByUser"
+	query := "SELECT o.id, o.name, o.description, o.create_time, m.role FROM organization_members m JOIN organizations o ON o.id = m.org_id WHERE m.user_id = $1 ORDER BY o.name"
+	rows, err := or.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	orgs := []models.OrganizationWithRole{}
+	for rows.Next() {
+		org := models.OrganizationWithRole{}
+		if err := rows.Scan(
+			&org.Id,
+			&org.Name,
+			&org.Description,
+			&org.CreateTime,
+			&org.Role,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (or *organizationRepo) FetchIds(ctx context.Context, uid string) ([]string, error) {
+	op := "organizationRepo.FetchIds"
+	query := "SELECT org_id FROM organization_members WHERE user_id = $1"
+	rows, err := or.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (or *organizationRepo) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	op := "organizationRepo.AddMember"
+	query := "INSERT INTO organization_members (org_id, user_id, role, create_time) VALUES($1,$2,$3,$4)"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, member.OrgId, member.UserId, member.Role, member.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (or *organizationRepo) UpdateMemberRole(ctx context.Context, orgId, uid, role string) error {
+	op := "organizationRepo.UpdateMemberRole"
+	query := "UPDATE organization_members SET role = $3 WHERE org_id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, orgId, uid, role)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (or *organizationRepo) RemoveMember(ctx context.Context, orgId, uid string) error {
+	op := "organizationRepo.RemoveMember"
+	query := "DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, orgId, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMemberRole returns a not found error when the user is not a member.
+func (or *organizationRepo) GetMemberRole(ctx context.Context, orgId, uid string) (string, error) {
+	op := "organizationRepo.GetMemberRole"
+	query := "SELECT role FROM organization_members WHERE org_id = $1 AND user_id = $2"
+	var role string
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, orgId, uid)
+	if err := qd.QueryRowWithTx(&role); err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (or *organizationRepo) FetchMembers(ctx context.Context, orgId string, amount, page uint) ([]models.OrganizationMemberUser, error) {
+	op := "organizationRepo.FetchMembers"
+	query := "SELECT m.org_id, m.user_id, m.role, m.create_time, u.nickname, u.handle, u.avatar FROM organization_members m JOIN users u ON u.id = m.user_id WHERE m.org_id = $1 ORDER BY m.create_time OFFSET $2 LIMIT $3"
+	rows, err := or.Storage.Pool.Query(ctx, query, orgId, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	members := []models.OrganizationMemberUser{}
+	for rows.Next() {
+		member := models.OrganizationMemberUser{}
+		if err := rows.Scan(
+			&member.OrgId,
+			&member.UserId,
+			&member.Role,
+			&member.CreateTime,
+			&member.Nickname,
+			&member.Handle,
+			&member.Avatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (or *organizationRepo) CountOwners(ctx context.Context, orgId string) (int, error) {
+	op := "organizationRepo.CountOwners"
+	query := "SELECT COUNT(*) FROM organization_members WHERE org_id = $1 AND role = 'owner'"
+	var count int
+	qd := helpers.NewQueryData(ctx, or.Storage, op, query, orgId)
+	if err := qd.QueryRowWithTx(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
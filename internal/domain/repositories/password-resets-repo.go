@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type PasswordResetRepo interface {
+	Create(ctx context.Context, uid string, token []byte, ttl time.Time) error
+	Get(ctx context.Context, token []byte) (string, time.Time, error)
+	Delete(ctx context.Context, token []byte) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type passwordResetRepo struct {
+	Storage *storage.Storage
+}
+
+func NewPasswordResetRepo(s *storage.Storage) PasswordResetRepo {
+	return &passwordResetRepo{
+		Storage: s,
+	}
+}
+
+func (pr *passwordResetRepo) Create(ctx context.Context, uid string, token []byte, ttl time.Time) error {
+	op := "passwordResetRepo.Create"
+	query := "INSERT INTO password_resets (token, user_id, expired_time) VALUES($1,$2,$3) ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, expired_time = EXCLUDED.expired_time"
+	qd := helpers.NewQueryData(ctx, pr.Storage, op, query, token, uid, ttl)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *passwordResetRepo) Get(ctx context.Context, token []byte) (string, time.Time, error) {
+	op := "passwordResetRepo.Get"
+	query := "SELECT user_id, expired_time FROM password_resets WHERE token = $1 FOR UPDATE"
+	var (
+		uid string
+		ttl time.Time
+	)
+	if tx, ok := storage.GetTx(ctx); ok {
+		if err := tx.QueryRow(ctx, query, token).Scan(&uid, &ttl); err != nil {
+			if errors.Is(err, storage.ErrNotFound()) {
+				return "", time.Time{}, errs.ErrNotFound(op)
+			}
+			return "", time.Time{}, errs.NewAppError(op, err)
+		}
+		return uid, ttl, nil
+	}
+	if err := pr.Storage.Pool.QueryRow(ctx, query, token).Scan(&uid, &ttl); err != nil {
+		if errors.Is(err, storage.ErrNotFound()) {
+			return "", time.Time{}, errs.ErrNotFound(op)
+		}
+		return "", time.Time{}, errs.NewAppError(op, err)
+	}
+	return uid, ttl, nil
+}
+
+func (pr *passwordResetRepo) Delete(ctx context.Context, token []byte) error {
+	op := "passwordResetRepo.Delete"
+	query := "DELETE FROM password_resets WHERE token = $1"
+	qd := helpers.NewQueryData(ctx, pr.Storage, op, query, token)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *passwordResetRepo) DeleteExpired(ctx context.Context) error {
+	op := "passwordResetRepo.DeleteExpired"
+	query := "DELETE FROM password_resets WHERE expired_time <= NOW()"
+	if _, err := pr.Storage.Pool.Exec(ctx, query); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type ReactionRepo interface {
+	Add(ctx context.Context, target, targetId, uid, reaction string, reactTime time.Time) (bool, error)
+	Remove(ctx context.Context, target, targetId, uid, reaction string) error
+	Lock(ctx context.Context, target, targetId string) error
+	Refresh(ctx context.Context, target, targetId string) (map[string]uint32, error)
+	FetchByUser(ctx context.Context, target, targetId, uid string) ([]string, error)
+}
+
+type reactionRepo struct {
+	Storage *storage.Storage
+	Cache   *cache.Cache
+}
+
+func NewReactionRepo(s *storage.Storage, c *cache.Cache) ReactionRepo {
+	return &reactionRepo{
+		Storage: s,
+		Cache:   c,
+	}
+}
+
+type reactionTarget struct {
+	table     string
+	column    string
+	itemTable string
+}
+
+var reactionTargets = map[string]reactionTarget{
+	models.ReactionTargetTemplate: {table: "template_reactions", column: "template_id", itemTable: "templates"},
+	models.ReactionTargetWidget:   {table: "widget_reactions", column: "widget_id", itemTable: "widgets"},
+}
+
+// Add reports whether a new reaction was stored; leaving the same reaction
+// again is not an error.
+func (rr *reactionRepo) Add(ctx context.Context, target, targetId, uid, reaction string, reactTime time.Time) (bool, error) {
+	op := "reactionRepo.Add"
+	t, ok := reactionTargets[target]
+	if !ok {
+		return false, errs.ErrInvalidFields(op)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s, user_id, reaction, create_time) VALUES($1,$2,$3,$4) ON CONFLICT (%s, user_id, reaction) DO NOTHING", t.table, t.column, t.column)
+	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, targetId, uid, reaction, reactTime)
+	if err := qd.InsertWithTx(); err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (rr *reactionRepo) Remove(ctx context.Context, target, targetId, uid, reaction string) error {
+	op := "reactionRepo.Remove"
+	t, ok := reactionTargets[target]
+	if !ok {
+		return errs.ErrInvalidFields(op)
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1 AND user_id = $2 AND reaction = $3", t.table, t.column)
+	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, targetId, uid, reaction)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Lock serializes reaction changes of the template or widget.
+func (rr *reactionRepo) Lock(ctx context.Context, target, targetId string) error {
+	op := "reactionRepo.Lock"
+	t, ok := reactionTargets[target]
+	if !ok {
+		return errs.ErrInvalidFields(op)
+	}
+	return helpers.LockRow(ctx, rr.Storage, op, t.itemTable, targetId)
+}
+
+// Refresh recounts reactions of the template or widget and returns the new
+// counts. Likes are kept in step with the like reactions, since search and
+// sorting still rely on them.
+func (rr *reactionRepo) Refresh(ctx context.Context, target, targetId string) (map[string]uint32, error) {
+	op := "reactionRepo.Refresh"
+	t, ok := reactionTargets[target]
+	if !ok {
+		return nil, errs.ErrInvalidFields(op)
+	}
+	query := fmt.Sprintf("UPDATE %s SET reactions = COALESCE((SELECT jsonb_object_agg(c.reaction, c.cnt) FROM (SELECT reaction, COUNT(*) AS cnt FROM %s WHERE %s = $1 GROUP BY reaction) c), '{}'), likes = (SELECT COUNT(*) FROM %s WHERE %s = $1 AND reaction = $2) WHERE id = $1 RETURNING reactions", t.itemTable, t.table, t.column, t.table, t.column)
+	reactions := map[string]uint32{}
+	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, targetId, models.ReactionLike)
+	if err := qd.QueryRowWithTx(&reactions); err != nil {
+		return nil, err
+	}
+	if err := rr.Cache.Redis.Del(ctx, targetId).Err(); err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	return reactions, nil
+}
+
+// FetchByUser lists reactions the user left on the template or widget.
+func (rr *reactionRepo) FetchByUser(ctx context.Context, target, targetId, uid string) ([]string, error) {
+	op := "reactionRepo.FetchByUser"
+	t, ok := reactionTargets[target]
+	if !ok {
+		return nil, errs.ErrInvalidFields(op)
+	}
+	query := fmt.Sprintf("SELECT COALESCE(array_agg(reaction ORDER BY create_time), '{}') FROM %s WHERE %s = $1 AND user_id = $2", t.table, t.column)
+	reactions := []string{}
+	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, targetId, uid)
+	if err := qd.QueryRowWithTx(&reactions); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
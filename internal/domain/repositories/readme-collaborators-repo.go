@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type ReadmeCollaboratorRepo interface {
+	Create(ctx context.Context, collaborator *models.ReadmeCollaborator) error
+	UpdateAccess(ctx context.Context, readmeId, uid, access string) error
+	Delete(ctx context.Context, readmeId, uid string) error
+	GetAccess(ctx context.Context, readmeId, uid string) (string, error)
+	FetchByReadme(ctx context.Context, readmeId string) ([]models.ReadmeCollaboratorUser, error)
+	FetchShared(ctx context.Context, uid string, amount, page uint) ([]models.SharedReadme, error)
+}
+
+type readmeCollaboratorRepo struct {
+	Storage *storage.Storage
+}
+
+func NewReadmeCollaboratorRepo(s *storage.Storage) ReadmeCollaboratorRepo {
+	return &readmeCollaboratorRepo{
+		Storage: s,
+	}
+}
+
+func (rcr *readmeCollaboratorRepo) Create(ctx context.Context, collaborator *models.ReadmeCollaborator) error {
+	op := "readmeCollaboratorRepo.Create"
+	query := "INSERT INTO readme_collaborators (readme_id, user_id, access, invited_by, create_time) VALUES($1,$2,$3,$4,$5)"
+	qd := helpers.NewQueryData(ctx, rcr.Storage, op, query, collaborator.ReadmeId, collaborator.UserId, collaborator.Access, collaborator.InvitedBy, collaborator.CreateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rcr *readmeCollaboratorRepo) UpdateAccess(ctx context.Context, readmeId, uid, access string) error {
+	op := "readmeCollaboratorRepo.UpdateAccess"
+	query := "UPDATE readme_collaborators SET access = $3 WHERE readme_id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, rcr.Storage, op, query, readmeId, uid, access)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rcr *readmeCollaboratorRepo) Delete(ctx context.Context, readmeId, uid string) error {
+	op := "readmeCollaboratorRepo.Delete"
+	query := "DELETE FROM readme_collaborators WHERE readme_id = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, rcr.Storage, op, query, readmeId, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAccess returns a not found error when the readme is not shared with the
+// user.
+func (rcr *readmeCollaboratorRepo) GetAccess(ctx context.Context, readmeId, uid string) (string, error) {
+	op := "readmeCollaboratorRepo.GetAccess"
+	query := "SELECT access FROM readme_collaborators WHERE readme_id = $1 AND user_id = $2"
+	var access string
+	qd := helpers.NewQueryData(ctx, rcr.Storage, op, query, readmeId, uid)
+	if err := qd.QueryRowWithTx(&access); err != nil {
+		return "", err
+	}
+	return access, nil
+}
+
+func (rcr *readmeCollaboratorRepo) FetchByReadme(ctx context.Context, readmeId string) ([]models.ReadmeCollaboratorUser, error) {
+	op := "readmeCollaboratorRepo.FetchByReadme"
+	query := "SELECT c.readme_id, c.user_id, c.access, c.invited_by, c.create_time, u.nickname, u.handle, u.avatar FROM readme_collaborators c JOIN users u ON u.id = c.user_id WHERE c.readme_id = $1 ORDER BY c.create_time"
+	rows, err := rcr.Storage.Pool.Query(ctx, query, readmeId)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	collaborators := []models.ReadmeCollaboratorUser{}
+	for rows.Next() {
+		collaborator := models.ReadmeCollaboratorUser{}
+		if err := rows.Scan(
+			&collaborator.ReadmeId,
+			&collaborator.UserId,
+			&collaborator.Access,
+			&collaborator.InvitedBy,
+			&collaborator.CreateTime,
+			&collaborator.Nickname,
+			&collaborator.Handle,
+			&collaborator.Avatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	return collaborators, nil
+}
+
+// FetchShared lists readmes shared with the user, most recently shared first.
+func (rcr *readmeCollaboratorRepo) FetchShared(ctx context.Context, uid string, amount, page uint) ([]models.SharedReadme, error) {
+	op := "readmeCollaboratorRepo.FetchShared"
+	query := "SELECT r.*, c.access FROM readme_collaborators c JOIN readmes r ON r.id = c.readme_id WHERE c.user_id = $1 ORDER BY c.create_time DESC OFFSET $2 LIMIT $3"
+	rows, err := rcr.Storage.Pool.Query(ctx, query, uid, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	readmes := []models.SharedReadme{}
+	for rows.Next() {
+		readme := models.SharedReadme{}
+		if err := rows.Scan(
+			&readme.Id,
+			&readme.OwnerId,
+			&readme.TemplateId,
+			&readme.Image,
+			&readme.Title,
+			&readme.Text,
+			&readme.Links,
+			&readme.Widgets,
+			&readme.RenderOrder,
+			&readme.CreateTime,
+			&readme.LastUpdateTime,
+			&readme.OrgId,
+			&readme.Access,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		readmes = append(readmes, readme)
+	}
+	return readmes, nil
+}
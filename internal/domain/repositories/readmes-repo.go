@@ -19,6 +19,8 @@ type ReadmeRepo interface {
 	Get(ctx context.Context, id string) (*models.Readme, error)
 	ChangeTemplateToBase(ctx context.Context, id string) error
 	FetchByUser(ctx context.Context, amount, page uint, uid string) ([]models.Readme, error)
+	FetchSharedByUser(ctx context.Context, amount, page uint, ownerId, uid string) ([]models.Readme, error)
+	FetchByOrg(ctx context.Context, amount, page uint, orgId string) ([]models.Readme, error)
 	FetchByTemplate(ctx context.Context, tid string) ([]models.Readme, error)
 }
 
@@ -34,8 +36,8 @@ func NewReadmeStorage(s *storage.Storage) ReadmeRepo {
 
 func (rr *readmeRepo) Create(ctx context.Context, readme *models.Readme) error {
 	op := "readmeRepo.Create"
-	query := "INSERT INTO readmes (id, owner_id, template_id, image, title, text, links, widgets, render_order, create_time, last_update_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)"
-	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, readme.Id, readme.OwnerId, readme.TemplateId, readme.Image, readme.Title, readme.Text, readme.Links, readme.Widgets, readme.RenderOrder, readme.CreateTime, readme.LastUpdateTime)
+	query := "INSERT INTO readmes (id, owner_id, template_id, image, title, text, links, widgets, render_order, create_time, last_update_time, org_id) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)"
+	qd := helpers.NewQueryData(ctx, rr.Storage, op, query, readme.Id, readme.OwnerId, readme.TemplateId, readme.Image, readme.Title, readme.Text, readme.Links, readme.Widgets, readme.RenderOrder, readme.CreateTime, readme.LastUpdateTime, readme.OrgId)
 	if err := qd.InsertWithTx(); err != nil {
 		return err
 	}
@@ -112,8 +114,26 @@ func (rr *readmeRepo) Get(ctx context.Context, id string) (*models.Readme, error
 
 func (rr *readmeRepo) FetchByUser(ctx context.Context, amount, page uint, uid string) ([]models.Readme, error) {
 	op := "readmeRepo.FetchByUser"
-	query := "SELECT * FROM readmes WHERE owner_id = $1 OFFSET $2 LIMIT $3"
-	rows, err := rr.Storage.Pool.Query(ctx, query, uid, amount*page-amount, amount)
+	query := "SELECT * FROM readmes WHERE owner_id = $1 AND org_id IS NULL OFFSET $2 LIMIT $3"
+	return rr.fetch(ctx, op, query, uid, amount*page-amount, amount)
+}
+
+// FetchSharedByUser lists personal readmes of the owner the user collaborates
+// on.
+func (rr *readmeRepo) FetchSharedByUser(ctx context.Context, amount, page uint, ownerId, uid string) ([]models.Readme, error) {
+	op := "readmeRepo.FetchSharedByUser"
+	query := "SELECT r.* FROM readmes r JOIN readme_collaborators rc ON rc.readme_id = r.id WHERE r.owner_id = $1 AND r.org_id IS NULL AND rc.user_id = $2 OFFSET $3 LIMIT $4"
+	return rr.fetch(ctx, op, query, ownerId, uid, amount*page-amount, amount)
+}
+
+func (rr *readmeRepo) FetchByOrg(ctx context.Context, amount, page uint, orgId string) ([]models.Readme, error) {
+	op := "readmeRepo.FetchByOrg"
+	query := "SELECT * FROM readmes WHERE org_id = $1 ORDER BY last_update_time DESC OFFSET $2 LIMIT $3"
+	return rr.fetch(ctx, op, query, orgId, amount*page-amount, amount)
+}
+
+func (rr *readmeRepo) fetch(ctx context.Context, op, query string, args ...any) ([]models.Readme, error) {
+	rows, err := rr.Storage.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, errs.NewAppError(op, err)
 	}
@@ -133,6 +153,7 @@ func (rr *readmeRepo) FetchByUser(ctx context.Context, amount, page uint, uid st
 			&readme.RenderOrder,
 			&readme.CreateTime,
 			&readme.LastUpdateTime,
+			&readme.OrgId,
 		); err != nil {
 			return nil, errs.NewAppError(op, err)
 		}
@@ -164,6 +185,7 @@ func (rr *readmeRepo) FetchByTemplate(ctx context.Context, tid string) ([]models
 			&readme.RenderOrder,
 			&readme.CreateTime,
 			&readme.LastUpdateTime,
+			&readme.OrgId,
 		); err != nil {
 			return nil, errs.NewAppError(op, err)
 		}
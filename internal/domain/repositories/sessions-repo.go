@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type SessionRepo interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByToken(ctx context.Context, token []byte) (*models.Session, error)
+	Rotate(ctx context.Context, id string, token []byte, jti string, lastUsed, ttl time.Time) error
+	FetchByUser(ctx context.Context, uid string) ([]models.Session, error)
+	Delete(ctx context.Context, id, uid string) (string, error)
+	DeleteByJti(ctx context.Context, jti, uid string) error
+	DeleteAllByUser(ctx context.Context, uid string) ([]string, error)
+	DeleteExpired(ctx context.Context) error
+	Deny(ctx context.Context, jti string, ttl time.Duration) error
+	IsDenied(ctx context.Context, jti string) (bool, error)
+}
+
+type sessionRepo struct {
+	Storage *storage.Storage
+	Cache   *cache.Cache
+}
+
+func NewSessionRepo(s *storage.Storage, c *cache.Cache) SessionRepo {
+	return &sessionRepo{
+		Storage: s,
+		Cache:   c,
+	}
+}
+
+const denylistPrefix = "denylist:"
+
+func (sr *sessionRepo) Create(ctx context.Context, session *models.Session) error {
+	op := "sessionRepo.Create"
+	query := "INSERT INTO sessions (id, user_id, refresh_token, jti, user_agent, ip, create_time, last_used_time, expired_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)"
+	qd := helpers.NewQueryData(ctx, sr.Storage, op, query, session.Id, session.UserId, session.RefreshToken, session.Jti, session.UserAgent, session.Ip, session.CreateTime, session.LastUsedTime, session.ExpiredTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepo) GetByToken(ctx context.Context, token []byte) (*models.Session, error) {
+	op := "sessionRepo.GetByToken"
+	query := "SELECT * FROM sessions WHERE refresh_token = $1 FOR UPDATE"
+	session := &models.Session{}
+	qd := helpers.NewQueryData(ctx, sr.Storage, op, query, token)
+	if err := qd.QueryRowWithTx(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (sr *sessionRepo) Rotate(ctx context.Context, id string, token []byte, jti string, lastUsed, ttl time.Time) error {
+	op := "sessionRepo.Rotate"
+	query := "UPDATE sessions SET refresh_token = $1, jti = $2, last_used_time = $3, expired_time = $4 WHERE id = $5"
+	qd := helpers.NewQueryData(ctx, sr.Storage, op, query, token, jti, lastUsed, ttl, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepo) FetchByUser(ctx context.Context, uid string) ([]models.Session, error) {
+	op := "sessionRepo.FetchByUser"
+	query := "SELECT * FROM sessions WHERE user_id = $1 AND expired_time > NOW() ORDER BY last_used_time DESC"
+	rows, err := sr.Storage.Pool.Query(ctx, query, uid)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	sessions := []models.Session{}
+	for rows.Next() {
+		session := models.Session{}
+		if err := rows.Scan(
+			&session.Id,
+			&session.UserId,
+			&session.RefreshToken,
+			&session.Jti,
+			&session.UserAgent,
+			&session.Ip,
+			&session.CreateTime,
+			&session.LastUsedTime,
+			&session.ExpiredTime,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (sr *sessionRepo) Delete(ctx context.Context, id, uid string) (string, error) {
+	op := "sessionRepo.Delete"
+	query := "DELETE FROM sessions WHERE id = $1 AND user_id = $2 RETURNING jti"
+	var jti string
+	qd := helpers.NewQueryData(ctx, sr.Storage, op, query, id, uid)
+	if err := qd.QueryRowWithTx(&jti); err != nil {
+		return "", err
+	}
+	return jti, nil
+}
+
+func (sr *sessionRepo) DeleteByJti(ctx context.Context, jti, uid string) error {
+	op := "sessionRepo.DeleteByJti"
+	query := "DELETE FROM sessions WHERE jti = $1 AND user_id = $2"
+	qd := helpers.NewQueryData(ctx, sr.Storage, op, query, jti, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepo) DeleteAllByUser(ctx context.Context, uid string) ([]string, error) {
+	op := "sessionRepo.DeleteAllByUser"
+	query := "DELETE FROM sessions WHERE user_id = $1 RETURNING jti"
+	jtis := []string{}
+	if tx, ok := storage.GetTx(ctx); ok {
+		rows, err := tx.Query(ctx, query, uid)
+		if err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var jti string
+			if err := rows.Scan(&jti); err != nil {
+				return nil, errs.NewAppError(op, err)
+			}
+			jtis = append(jtis, jti)
+		}
+	} else {
+		rows, err := sr.Storage.Pool.Query(ctx, query, uid)
+		if err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var jti string
+			if err := rows.Scan(&jti); err != nil {
+				return nil, errs.NewAppError(op, err)
+			}
+			jtis = append(jtis, jti)
+		}
+	}
+	return jtis, nil
+}
+
+func (sr *sessionRepo) DeleteExpired(ctx context.Context) error {
+	op := "sessionRepo.DeleteExpired"
+	query := "DELETE FROM sessions WHERE expired_time <= NOW()"
+	if _, err := sr.Storage.Pool.Exec(ctx, query); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (sr *sessionRepo) Deny(ctx context.Context, jti string, ttl time.Duration) error {
+	op := "sessionRepo.Deny"
+	if err := sr.Cache.Redis.Set(ctx, denylistPrefix+jti, 1, ttl).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (sr *sessionRepo) IsDenied(ctx context.Context, jti string) (bool, error) {
+	op := "sessionRepo.IsDenied"
+	n, err := sr.Cache.Redis.Exists(ctx, denylistPrefix+jti).Result()
+	if err != nil {
+		return false, errs.NewAppError(op, err)
+	}
+	return n > 0, nil
+}
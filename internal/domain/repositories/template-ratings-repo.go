@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+)
+
+type TemplateRatingRepo interface {
+	Upsert(ctx context.Context, rating *models.TemplateRating) error
+	Delete(ctx context.Context, id, uid string) error
+	FetchByTemplate(ctx context.Context, id string, amount, page uint) ([]models.TemplateRatingUser, error)
+}
+
+type templateRatingRepo struct {
+	Storage *storage.Storage
+}
+
+func NewTemplateRatingRepo(s *storage.Storage) TemplateRatingRepo {
+	return &templateRatingRepo{
+		Storage: s,
+	}
+}
+
+// Upsert rates the template or replaces the previous rating of the user.
+func (trr *templateRatingRepo) Upsert(ctx context.Context, rating *models.TemplateRating) error {
+	op := "templateRatingRepo.Upsert"
+	query := "INSERT INTO template_ratings (template_id, user_id, stars, review, create_time, update_time) VALUES($1,$2,$3,$4,$5,$6) ON CONFLICT (template_id, user_id) DO UPDATE SET stars = EXCLUDED.stars, review = EXCLUDED.review, update_time = EXCLUDED.update_time"
+	qd := helpers.NewQueryData(ctx, trr.Storage, op, query, rating.TemplateId, rating.UserId, rating.Stars, rating.Review, rating.CreateTime, rating.UpdateTime)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (trr *templateRatingRepo) Delete(ctx context.Context, id, uid string) error {
+	op := "templateRatingRepo.Delete"
+	query := "DELETE FROM template_ratings WHERE (template_id, user_id) = ($1,$2)"
+	qd := helpers.NewQueryData(ctx, trr.Storage, op, query, id, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FetchByTemplate lists ratings of the template, recently updated first.
+func (trr *templateRatingRepo) FetchByTemplate(ctx context.Context, id string, amount, page uint) ([]models.TemplateRatingUser, error) {
+	op := "templateRatingRepo.FetchByTemplate"
+	query := "SELECT r.template_id, r.user_id, r.stars, r.review, r.create_time, r.update_time, u.nickname, u.handle, u.avatar FROM template_ratings r JOIN users u ON u.id = r.user_id WHERE r.template_id = $1 ORDER BY r.update_time DESC OFFSET $2 LIMIT $3"
+	rows, err := trr.Storage.Pool.Query(ctx, query, id, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	ratings := []models.TemplateRatingUser{}
+	for rows.Next() {
+		rating := models.TemplateRatingUser{}
+		if err := rows.Scan(
+			&rating.TemplateId,
+			&rating.UserId,
+			&rating.Stars,
+			&rating.Review,
+			&rating.CreateTime,
+			&rating.UpdateTime,
+			&rating.UserNickname,
+			&rating.UserHandle,
+			&rating.UserAvatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		ratings = append(ratings, rating)
+	}
+	return ratings, nil
+}
@@ -12,6 +12,7 @@ import (
 	"readmeow/pkg/errs"
 	"readmeow/pkg/search"
 	"readmeow/pkg/storage"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,11 +30,13 @@ type TemplateRepo interface {
 	Get(ctx context.Context, id string) (*models.TemplateWithOwner, error)
 	GetImage(ctx context.Context, id string) (string, error)
 	FetchByUser(ctx context.Context, id string, showPrivate bool) ([]models.Template, error)
-	Like(ctx context.Context, id, uid string) error
-	Dislike(ctx context.Context, id, uid string) error
 	FetchFavorite(ctx context.Context, id string, amount, page uint) ([]models.TemplateWithOwner, error)
-	Search(ctx context.Context, amount, page uint, query string, filter map[string]bool, sort map[string]string) ([]models.TemplateWithOwner, error)
+	FetchFeed(ctx context.Context, uid string, amount, page uint) ([]models.TemplateWithOwner, error)
+	FetchByOrg(ctx context.Context, orgId string, amount, page uint) ([]models.TemplateWithOwner, error)
+	Search(ctx context.Context, amount, page uint, query, orgId string, memberOrgIds []string, filter map[string]bool, minRating float64, sort map[string]string) ([]models.TemplateWithOwner, error)
 	MustBulk(ctx context.Context, cfg config.SearchConfig) error
+	LockRating(ctx context.Context, id string) error
+	RefreshRating(ctx context.Context, id string) error
 }
 
 type templateRepo struct {
@@ -52,8 +55,8 @@ func NewTemplateRepo(s *storage.Storage, c *cache.Cache, sc *search.SearchClient
 
 func (tr *templateRepo) Create(ctx context.Context, template *models.Template) error {
 	op := "templateRepo.Create"
-	query := "INSERT INTO templates (id, owner_id, title, image, description, text, links, widgets,num_of_users, render_order, create_time, last_update_time, is_public) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)"
-	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, template.Id, template.OwnerId, template.Title, template.Image, template.Description, template.Text, template.Links, template.Widgets, template.NumOfUsers, template.RenderOrder, template.CreateTime, template.LastUpdateTime, template.IsPublic)
+	query := "INSERT INTO templates (id, owner_id, title, image, description, text, links, widgets,num_of_users, render_order, create_time, last_update_time, is_public, org_id) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, template.Id, template.OwnerId, template.Title, template.Image, template.Description, template.Text, template.Links, template.Widgets, template.NumOfUsers, template.RenderOrder, template.CreateTime, template.LastUpdateTime, template.IsPublic, template.OrgId)
 	if err := qd.InsertWithTx(); err != nil {
 		return err
 	}
@@ -71,11 +74,10 @@ func (tr *templateRepo) Update(ctx context.Context, updates map[string]any, id s
 		"widgets":          true,
 		"render_order":     true,
 		"num_of_users":     true,
-		"likes":            true,
 		"last_update_time": true,
 		"is_public":        true,
 	}
-	validValuesForLikesAndNumOfUsers := map[string]bool{
+	validValuesForNumOfUsers := map[string]bool{
 		"+": true,
 		"-": true,
 	}
@@ -86,9 +88,9 @@ func (tr *templateRepo) Update(ctx context.Context, updates map[string]any, id s
 		if !validFields[k] {
 			return errs.ErrInvalidFields(op)
 		}
-		if k == "likes" || k == "num_of_users" {
+		if k == "num_of_users" {
 			val := v.(string)
-			if !validValuesForLikesAndNumOfUsers[val] {
+			if !validValuesForNumOfUsers[val] {
 				return errs.ErrInvalidFields(op)
 			}
 			str = append(str, fmt.Sprintf(" %s = GREATEST(%s %s 1, 0)", k, k, val))
@@ -116,7 +118,7 @@ func (tr *templateRepo) FetchByUser(ctx context.Context, id string, showPrivate
 	if !showPrivate {
 		p = "AND is_public = TRUE"
 	}
-	query := fmt.Sprintf("SELECT * FROM templates WHERE owner_id = $1 %s ORDER BY num_of_users DESC", p)
+	query := fmt.Sprintf("SELECT * FROM templates WHERE owner_id = $1 AND org_id IS NULL %s ORDER BY num_of_users DESC", p)
 	templates := []models.Template{}
 	rows, err := tr.Storage.Pool.Query(ctx, query, id)
 	if err != nil {
@@ -140,6 +142,10 @@ func (tr *templateRepo) FetchByUser(ctx context.Context, id string, showPrivate
 			&template.LastUpdateTime,
 			&template.NumOfUsers,
 			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
+			&template.Reactions,
 		); err != nil {
 			return nil, errs.NewAppError(op, err)
 		}
@@ -162,23 +168,24 @@ func (tr *templateRepo) GetImage(ctx context.Context, id string) (string, error)
 	return url, nil
 }
 
-func (tr *templateRepo) Like(ctx context.Context, id, uid string) error {
-	op := "templateRepo.Like"
-	query := "INSERT INTO favorite_templates (template_id, user_id) VALUES ($1,$2)"
-	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, id, uid)
-	if err := qd.InsertWithTx(); err != nil {
-		return err
-	}
-	return nil
+// LockRating serializes rating changes of the template.
+func (tr *templateRepo) LockRating(ctx context.Context, id string) error {
+	op := "templateRepo.LockRating"
+	return helpers.LockRow(ctx, tr.Storage, op, "templates", id)
 }
 
-func (tr *templateRepo) Dislike(ctx context.Context, id, uid string) error {
-	op := "templateRepo.Dislike"
-	query := "DELETE FROM favorite_templates WHERE (template_id, user_id) = ($1,$2)"
-	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, id, uid)
+// RefreshRating recounts the average rating of the template and the number
+// of its ratings.
+func (tr *templateRepo) RefreshRating(ctx context.Context, id string) error {
+	op := "templateRepo.RefreshRating"
+	query := "UPDATE templates SET (rating, num_of_ratings) = (SELECT COALESCE(AVG(stars), 0), COUNT(*) FROM template_ratings WHERE template_id = $1) WHERE id = $1"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, id)
 	if err := qd.DeleteOrUpdateWithTx(); err != nil {
 		return err
 	}
+	if err := tr.Cache.Redis.Del(ctx, id).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
 	return nil
 }
 
@@ -236,7 +243,7 @@ func (tr *templateRepo) Get(ctx context.Context, id string) (*models.TemplateWit
 
 func (tr *templateRepo) FetchFavorite(ctx context.Context, id string, amount, page uint) ([]models.TemplateWithOwner, error) {
 	op := "templateRepo.FetchFavorite"
-	query := "SELECT t.*,u.nickname as owner_nickname, u.avatar as owner_avatar FROM templates t JOIN favorite_templates ft ON t.id=ft.template_id JOIN users u ON t.owner_id=u.id WHERE ft.user_id=$1 ORDER BY t.num_of_users DESC OFFSET $2 LIMIT $3"
+	query := "SELECT t.*,u.nickname as owner_nickname, u.avatar as owner_avatar FROM templates t JOIN template_reactions tr ON t.id=tr.template_id AND tr.reaction='like' JOIN users u ON t.owner_id=u.id WHERE tr.user_id=$1 ORDER BY t.num_of_users DESC OFFSET $2 LIMIT $3"
 	templates := []models.TemplateWithOwner{}
 	rows, err := tr.Storage.Pool.Query(ctx, query, id, amount*page-amount, amount)
 	if err != nil {
@@ -260,6 +267,55 @@ func (tr *templateRepo) FetchFavorite(ctx context.Context, id string, amount, pa
 			&template.LastUpdateTime,
 			&template.NumOfUsers,
 			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
+			&template.Reactions,
+			&template.OwnerNickname,
+			&template.OwnerAvatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		templates = append(templates, template)
+	}
+	if len(templates) == 0 {
+		return []models.TemplateWithOwner{}, nil
+	}
+	return templates, nil
+}
+
+// FetchFeed reads the timeline on demand: public templates of followed users,
+// most recently created or updated first.
+func (tr *templateRepo) FetchFeed(ctx context.Context, uid string, amount, page uint) ([]models.TemplateWithOwner, error) {
+	op := "templateRepo.FetchFeed"
+	query := "SELECT t.*,u.nickname as owner_nickname, u.avatar as owner_avatar FROM follows f JOIN templates t ON t.owner_id=f.followee_id JOIN users u ON t.owner_id=u.id WHERE f.follower_id=$1 AND t.is_public=TRUE ORDER BY t.last_update_time DESC OFFSET $2 LIMIT $3"
+	templates := []models.TemplateWithOwner{}
+	rows, err := tr.Storage.Pool.Query(ctx, query, uid, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		template := models.TemplateWithOwner{}
+		if err := rows.Scan(
+			&template.Id,
+			&template.OwnerId,
+			&template.Title,
+			&template.Image,
+			&template.Description,
+			&template.Text,
+			&template.Links,
+			&template.Widgets,
+			&template.Likes,
+			&template.RenderOrder,
+			&template.CreateTime,
+			&template.LastUpdateTime,
+			&template.NumOfUsers,
+			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
+			&template.Reactions,
 			&template.OwnerNickname,
 			&template.OwnerAvatar,
 		); err != nil {
@@ -273,7 +329,50 @@ func (tr *templateRepo) FetchFavorite(ctx context.Context, id string, amount, pa
 	return templates, nil
 }
 
-func (tr *templateRepo) Search(ctx context.Context, amount, page uint, query string, filter map[string]bool, sort map[string]string) ([]models.TemplateWithOwner, error) {
+func (tr *templateRepo) FetchByOrg(ctx context.Context, orgId string, amount, page uint) ([]models.TemplateWithOwner, error) {
+	op := "templateRepo.FetchByOrg"
+	query := "SELECT t.*,u.nickname as owner_nickname, u.avatar as owner_avatar FROM templates t JOIN users u ON t.owner_id=u.id WHERE t.org_id=$1 ORDER BY t.last_update_time DESC OFFSET $2 LIMIT $3"
+	templates := []models.TemplateWithOwner{}
+	rows, err := tr.Storage.Pool.Query(ctx, query, orgId, amount*page-amount, amount)
+	if err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		template := models.TemplateWithOwner{}
+		if err := rows.Scan(
+			&template.Id,
+			&template.OwnerId,
+			&template.Title,
+			&template.Image,
+			&template.Description,
+			&template.Text,
+			&template.Links,
+			&template.Widgets,
+			&template.Likes,
+			&template.RenderOrder,
+			&template.CreateTime,
+			&template.LastUpdateTime,
+			&template.NumOfUsers,
+			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
+			&template.Reactions,
+			&template.OwnerNickname,
+			&template.OwnerAvatar,
+		); err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// Search looks through public templates and, for members, the private
+// templates of their organizations. A non-empty orgId limits the search to
+// templates of that organization.
+func (tr *templateRepo) Search(ctx context.Context, amount, page uint, query, orgId string, memberOrgIds []string, filter map[string]bool, minRating float64, sort map[string]string) ([]models.TemplateWithOwner, error) {
 	op := "templateRepo.Search"
 	var mainQuery types.Query
 	if query != "" {
@@ -296,6 +395,8 @@ func (tr *templateRepo) Search(ctx context.Context, amount, page uint, query str
 			"Likes":          true,
 			"NumOfUsers":     true,
 			"LastUpdateTime": true,
+			"Rating":         true,
+			"NumOfRatings":   true,
 		}
 		validSortValues := map[string]bool{
 			"desc": true,
@@ -352,6 +453,49 @@ func (tr *templateRepo) Search(ctx context.Context, amount, page uint, query str
 		}
 	}
 
+	if minRating > 0 {
+		gte := types.Float64(minRating)
+		filters = append(filters, types.Query{
+			Range: map[string]types.RangeQuery{
+				"Rating": types.NumberRangeQuery{Gte: &gte},
+			},
+		})
+	}
+
+	visible := []types.Query{
+		{
+			Term: map[string]types.TermQuery{
+				"IsPublic": {Value: true},
+			},
+		},
+	}
+	if len(memberOrgIds) > 0 {
+		orgIds := make([]types.FieldValue, 0, len(memberOrgIds))
+		for _, id := range memberOrgIds {
+			orgIds = append(orgIds, id)
+		}
+		visible = append(visible, types.Query{
+			Terms: &types.TermsQuery{
+				TermsQuery: map[string]types.TermsQueryField{
+					"OrgId.keyword": orgIds,
+				},
+			},
+		})
+	}
+	filters = append(filters, types.Query{
+		Bool: &types.BoolQuery{
+			Should:             visible,
+			MinimumShouldMatch: 1,
+		},
+	})
+	if orgId != "" {
+		filters = append(filters, types.Query{
+			Term: map[string]types.TermQuery{
+				"OrgId.keyword": {Value: orgId},
+			},
+		})
+	}
+
 	ptr := func(i int) *int {
 		return &i
 	}
@@ -419,6 +563,10 @@ func (tr *templateRepo) getByIds(ctx context.Context, ids []string) ([]models.Te
 			&template.LastUpdateTime,
 			&template.NumOfUsers,
 			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
+			&template.Reactions,
 			&template.OwnerNickname,
 			&template.OwnerAvatar,
 		); err != nil {
@@ -439,7 +587,7 @@ func (tr *templateRepo) getByIds(ctx context.Context, ids []string) ([]models.Te
 
 func (tr *templateRepo) getAll(ctx context.Context) ([]models.Template, error) {
 	op := "templateRepo.SearchPreparing.getAll"
-	query := "SELECT id, owner_id, title, description, likes, num_of_users, last_update_time FROM templates WHERE is_public=TRUE"
+	query := "SELECT id, owner_id, title, description, likes, num_of_users, last_update_time, is_public, org_id, rating, num_of_ratings FROM templates WHERE is_public=TRUE OR org_id IS NOT NULL"
 	templates := []models.Template{}
 	rows, err := tr.Storage.Pool.Query(ctx, query)
 	if err != nil {
@@ -457,6 +605,10 @@ func (tr *templateRepo) getAll(ctx context.Context) ([]models.Template, error) {
 			&template.Likes,
 			&template.NumOfUsers,
 			&template.LastUpdateTime,
+			&template.IsPublic,
+			&template.OrgId,
+			&template.Rating,
+			&template.NumOfRatings,
 		); err != nil {
 			return nil, errs.NewAppError(op, err)
 		}
@@ -490,6 +642,12 @@ func (tr *templateRepo) MustBulk(ctx context.Context, cfg config.SearchConfig) e
 		Likes          uint32
 		NumOfUsers     uint32
 		LastUpdateTime time.Time
+		IsPublic       bool
+		OrgId          string `json:",omitempty"`
+		// Rating always carries a fraction so that the dynamic mapping
+		// picks a float field even when the first indexed rating is whole.
+		Rating       json.Number
+		NumOfRatings uint32
 	}
 	for _, t := range templates {
 		d := doc{
@@ -500,6 +658,12 @@ func (tr *templateRepo) MustBulk(ctx context.Context, cfg config.SearchConfig) e
 			NumOfUsers:     t.NumOfUsers,
 			Likes:          t.Likes,
 			LastUpdateTime: t.LastUpdateTime,
+			IsPublic:       t.IsPublic,
+			Rating:         json.Number(strconv.FormatFloat(t.Rating, 'f', 2, 64)),
+			NumOfRatings:   t.NumOfRatings,
+		}
+		if t.OrgId != nil {
+			d.OrgId = t.OrgId.String()
 		}
 		data, err := json.Marshal(d)
 		if err != nil {
@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories/helpers"
+	"readmeow/pkg/cache"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type TwoFactorRepo interface {
+	Create(ctx context.Context, uid, secret string) error
+	Get(ctx context.Context, uid string) (*models.TwoFactor, error)
+	Enable(ctx context.Context, uid string) error
+	UseStep(ctx context.Context, uid string, step int64) error
+	Delete(ctx context.Context, uid string) error
+	ReplaceRecoveryCodes(ctx context.Context, uid string, codes [][]byte) error
+	UseRecoveryCode(ctx context.Context, uid string, code []byte) error
+	CreateChallenge(ctx context.Context, token []byte, uid string, attempts int, ttl time.Duration) error
+	GetChallenge(ctx context.Context, token []byte) (string, error)
+	MinusChallengeAttempts(ctx context.Context, token []byte) (int, error)
+	DeleteChallenge(ctx context.Context, token []byte) error
+}
+
+type twoFactorRepo struct {
+	Storage *storage.Storage
+	Cache   *cache.Cache
+}
+
+func NewTwoFactorRepo(s *storage.Storage, c *cache.Cache) TwoFactorRepo {
+	return &twoFactorRepo{
+		Storage: s,
+		Cache:   c,
+	}
+}
+
+const twoFactorChallengePrefix = "2fa:"
+
+func challengeKey(token []byte) string {
+	return twoFactorChallengePrefix + hex.EncodeToString(token)
+}
+
+func (tr *twoFactorRepo) Create(ctx context.Context, uid, secret string) error {
+	op := "twoFactorRepo.Create"
+	query := "INSERT INTO two_factors (user_id, secret, enabled, last_step, create_time) VALUES($1,$2,FALSE,0,NOW()) ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, create_time = EXCLUDED.create_time WHERE two_factors.enabled = FALSE"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid, secret)
+	if err := qd.InsertWithTx(); err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return errs.ErrAlreadyExists(op, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) Get(ctx context.Context, uid string) (*models.TwoFactor, error) {
+	op := "twoFactorRepo.Get"
+	query := "SELECT user_id, secret, enabled, last_step, create_time FROM two_factors WHERE user_id = $1"
+	twoFactor := &models.TwoFactor{}
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid)
+	if err := qd.QueryRowWithTx(twoFactor); err != nil {
+		return nil, err
+	}
+	return twoFactor, nil
+}
+
+func (tr *twoFactorRepo) Enable(ctx context.Context, uid string) error {
+	op := "twoFactorRepo.Enable"
+	query := "UPDATE two_factors SET enabled = TRUE WHERE user_id = $1 AND enabled = FALSE"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) UseStep(ctx context.Context, uid string, step int64) error {
+	op := "twoFactorRepo.UseStep"
+	query := "UPDATE two_factors SET last_step = $1 WHERE user_id = $2 AND last_step < $1"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, step, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) Delete(ctx context.Context, uid string) error {
+	op := "twoFactorRepo.Delete"
+	query := "DELETE FROM two_factors WHERE user_id = $1"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	query = "DELETE FROM recovery_codes WHERE user_id = $1"
+	if tx, ok := storage.GetTx(ctx); ok {
+		if _, err := tx.Exec(ctx, query, uid); err != nil {
+			return errs.NewAppError(op, err)
+		}
+		return nil
+	}
+	if _, err := tr.Storage.Pool.Exec(ctx, query, uid); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, uid string, codes [][]byte) error {
+	op := "twoFactorRepo.ReplaceRecoveryCodes"
+	query := "DELETE FROM recovery_codes WHERE user_id = $1"
+	if tx, ok := storage.GetTx(ctx); ok {
+		if _, err := tx.Exec(ctx, query, uid); err != nil {
+			return errs.NewAppError(op, err)
+		}
+	} else {
+		if _, err := tr.Storage.Pool.Exec(ctx, query, uid); err != nil {
+			return errs.NewAppError(op, err)
+		}
+	}
+	query = "INSERT INTO recovery_codes (user_id, code) SELECT $1, unnest($2::bytea[])"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid, codes)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) UseRecoveryCode(ctx context.Context, uid string, code []byte) error {
+	op := "twoFactorRepo.UseRecoveryCode"
+	query := "DELETE FROM recovery_codes WHERE user_id = $1 AND code = $2"
+	qd := helpers.NewQueryData(ctx, tr.Storage, op, query, uid, code)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) CreateChallenge(ctx context.Context, token []byte, uid string, attempts int, ttl time.Duration) error {
+	op := "twoFactorRepo.CreateChallenge"
+	key := challengeKey(token)
+	pipe := tr.Cache.Redis.TxPipeline()
+	pipe.HSet(ctx, key, "uid", uid, "attempts", attempts)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
+
+func (tr *twoFactorRepo) GetChallenge(ctx context.Context, token []byte) (string, error) {
+	op := "twoFactorRepo.GetChallenge"
+	uid, err := tr.Cache.Redis.HGet(ctx, challengeKey(token), "uid").Result()
+	if err != nil {
+		if err == cache.EMPTY {
+			return "", errs.ErrNotFound(op)
+		}
+		return "", errs.NewAppError(op, err)
+	}
+	return uid, nil
+}
+
+func (tr *twoFactorRepo) MinusChallengeAttempts(ctx context.Context, token []byte) (int, error) {
+	op := "twoFactorRepo.MinusChallengeAttempts"
+	attempts, err := tr.Cache.Redis.HIncrBy(ctx, challengeKey(token), "attempts", -1).Result()
+	if err != nil {
+		return 0, errs.NewAppError(op, err)
+	}
+	return int(attempts), nil
+}
+
+func (tr *twoFactorRepo) DeleteChallenge(ctx context.Context, token []byte) error {
+	op := "twoFactorRepo.DeleteChallenge"
+	if err := tr.Cache.Redis.Del(ctx, challengeKey(token)).Err(); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	return nil
+}
@@ -16,14 +16,23 @@ type UserRepo interface {
 	Get(ctx context.Context, id string) (*models.User, error)
 	GetByProviderId(ctx context.Context, pid, provider string) (*models.User, error)
 	GetByLogin(ctx context.Context, login string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByAnyEmail(ctx context.Context, email string) (*models.User, error)
 	GetByIds(ctx context.Context, ids []string) ([]models.User, error)
+	GetByHandle(ctx context.Context, handle string) (*models.User, error)
 	Update(ctx context.Context, updates map[string]any, id string) error
 	Delete(ctx context.Context, id string) error
 	IdCheck(ctx context.Context, id string) (bool, error)
 	GetAvatar(ctx context.Context, id string) (string, error)
 	ExistanceCheck(ctx context.Context, login, email string) (bool, error)
+	EmailExistanceCheck(ctx context.Context, email string) (bool, error)
+	ChangeEmail(ctx context.Context, id, email string) error
+	ChangeRole(ctx context.Context, id, role string) error
 	ChangePassword(ctx context.Context, id string, password []byte) error
 	GetPassword(ctx context.Context, id string) ([]byte, error)
+	SetPassword(ctx context.Context, id, login string, password []byte) error
+	HandleCheck(ctx context.Context, handle, id string) (bool, error)
+	ChangeHandle(ctx context.Context, id, handle string) error
 }
 
 type userRepo struct {
@@ -38,8 +47,8 @@ func NewUserRepo(s *storage.Storage) UserRepo {
 
 func (ur *userRepo) Create(ctx context.Context, user *models.User) error {
 	op := "userRepo.Create"
-	query := "INSERT INTO users (id, nickname, login, email, avatar, password, time_of_register, num_of_templates, num_of_readmes, provider, provider_id) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,$11)"
-	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, user.Id, user.Nickname, user.Login, user.Email, user.Avatar, user.Password, user.TimeOfRegister, user.NumOfTemplates, user.NumOfReadmes, user.Provider, user.ProviderId)
+	query := "INSERT INTO users (id, nickname, login, email, avatar, password, time_of_register, num_of_templates, num_of_readmes, provider, provider_id, handle) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,$11,$12)"
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, user.Id, user.Nickname, user.Login, user.Email, user.Avatar, user.Password, user.TimeOfRegister, user.NumOfTemplates, user.NumOfReadmes, user.Provider, user.ProviderId, user.Handle)
 	if err := qd.InsertWithTx(); err != nil {
 		return err
 	}
@@ -48,7 +57,7 @@ func (ur *userRepo) Create(ctx context.Context, user *models.User) error {
 
 func (ur *userRepo) Get(ctx context.Context, id string) (*models.User, error) {
 	op := "userRepo.Get"
-	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes FROM users WHERE id = $1"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE id = $1"
 	user := &models.User{}
 	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, id)
 	if err := qd.QueryRowWithTx(user); err != nil {
@@ -57,6 +66,17 @@ func (ur *userRepo) Get(ctx context.Context, id string) (*models.User, error) {
 	return user, nil
 }
 
+func (ur *userRepo) GetByHandle(ctx context.Context, handle string) (*models.User, error) {
+	op := "userRepo.GetByHandle"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE LOWER(handle) = LOWER($1)"
+	user := &models.User{}
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, handle)
+	if err := qd.QueryRowWithTx(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (ur *userRepo) GetByIds(ctx context.Context, ids []string) ([]models.User, error) {
 	op := "userRepo.GetByIds"
 	query := "SELECT id, avatar, nickname FROM users WHERE id = ANY($1)"
@@ -95,7 +115,7 @@ func (ur *userRepo) GetAvatar(ctx context.Context, id string) (string, error) {
 
 func (ur *userRepo) GetByLogin(ctx context.Context, login string) (*models.User, error) {
 	op := "userRepo.GetByLogin"
-	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes FROM users WHERE login = $1 AND provider = 'local'"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE login = $1"
 	user := &models.User{}
 	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, login)
 	if err := qd.QueryRowWithTx(user); err != nil {
@@ -104,6 +124,28 @@ func (ur *userRepo) GetByLogin(ctx context.Context, login string) (*models.User,
 	return user, nil
 }
 
+func (ur *userRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	op := "userRepo.GetByEmail"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE email = $1 AND provider = 'local'"
+	user := &models.User{}
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, email)
+	if err := qd.QueryRowWithTx(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (ur *userRepo) GetByAnyEmail(ctx context.Context, email string) (*models.User, error) {
+	op := "userRepo.GetByAnyEmail"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE email = $1"
+	user := &models.User{}
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, email)
+	if err := qd.QueryRowWithTx(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (ur *userRepo) ExistanceCheck(ctx context.Context, login, email string) (bool, error) {
 	op := "userRepo.ExistanceCheck"
 	query := "SELECT 1 FROM users WHERE login = $1 OR email = $2"
@@ -118,9 +160,23 @@ func (ur *userRepo) ExistanceCheck(ctx context.Context, login, email string) (bo
 	return true, nil
 }
 
+func (ur *userRepo) EmailExistanceCheck(ctx context.Context, email string) (bool, error) {
+	op := "userRepo.EmailExistanceCheck"
+	query := "SELECT 1 FROM users WHERE email = $1"
+	var res int
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, email)
+	if err := qd.QueryRowWithTx(&res); err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 func (ur *userRepo) GetByProviderId(ctx context.Context, pid, provider string) (*models.User, error) {
 	op := "userRepo.GetByProviderId"
-	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes FROM users WHERE provider_id = $1 AND provider = $2"
+	query := "SELECT id, nickname, login, email, password, avatar, time_of_register, num_of_templates, num_of_readmes, role, handle FROM users WHERE provider_id = $1 AND provider = $2"
 	user := &models.User{}
 	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, pid, provider)
 	if err := qd.QueryRowWithTx(user); err != nil {
@@ -195,7 +251,7 @@ func (ur *userRepo) Update(ctx context.Context, updates map[string]any, id strin
 
 func (ur *userRepo) ChangePassword(ctx context.Context, id string, password []byte) error {
 	op := "userRepo.UpdatePassword"
-	query := "UPDATE users SET password=$1 WHERE id = $2 AND provider = 'local'"
+	query := "UPDATE users SET password=$1 WHERE id = $2"
 	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, password, id)
 	if err := qd.DeleteOrUpdateWithTx(); err != nil {
 		return err
@@ -203,9 +259,41 @@ func (ur *userRepo) ChangePassword(ctx context.Context, id string, password []by
 	return nil
 }
 
+// SetPassword gives a password to an account created through a provider,
+// along with a login when it has none yet.
+func (ur *userRepo) SetPassword(ctx context.Context, id, login string, password []byte) error {
+	op := "userRepo.SetPassword"
+	query := "UPDATE users SET login = COALESCE(login, NULLIF($1, '')), password = $2 WHERE id = $3 AND password IS NULL"
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, login, password, id)
+	if err := qd.InsertWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ur *userRepo) ChangeEmail(ctx context.Context, id, email string) error {
+	op := "userRepo.ChangeEmail"
+	query := "UPDATE users SET email = $1 WHERE id = $2 AND provider = 'local'"
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, email, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ur *userRepo) ChangeRole(ctx context.Context, id, role string) error {
+	op := "userRepo.ChangeRole"
+	query := "UPDATE users SET role = $1 WHERE id = $2"
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, role, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (ur *userRepo) GetPassword(ctx context.Context, id string) ([]byte, error) {
 	op := "userRepo.GetPassword"
-	query := "SELECT password FROM users WHERE id = $1 AND provider = 'local'"
+	query := "SELECT password FROM users WHERE id = $1"
 	password := []byte{}
 	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, id)
 	if err := qd.QueryRowWithTx(&password); err != nil {
@@ -213,3 +301,26 @@ func (ur *userRepo) GetPassword(ctx context.Context, id string) ([]byte, error)
 	}
 	return password, nil
 }
+
+// HandleCheck reports whether the handle is used by another user, either as
+// their current handle or as one they had before.
+func (ur *userRepo) HandleCheck(ctx context.Context, handle, id string) (bool, error) {
+	op := "userRepo.HandleCheck"
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(handle) = LOWER($1) AND id <> $2) OR EXISTS(SELECT 1 FROM handle_history WHERE LOWER(handle) = LOWER($1) AND user_id <> $2)"
+	var taken bool
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, handle, id)
+	if err := qd.QueryRowWithTx(&taken); err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+func (ur *userRepo) ChangeHandle(ctx context.Context, id, handle string) error {
+	op := "userRepo.ChangeHandle"
+	query := "UPDATE users SET handle = $1 WHERE id = $2"
+	qd := helpers.NewQueryData(ctx, ur.Storage, op, query, handle, id)
+	if err := qd.DeleteOrUpdateWithTx(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -25,8 +25,6 @@ import (
 type WidgetRepo interface {
 	Get(ctx context.Context, id string) (*models.Widget, error)
 	Search(ctx context.Context, amount, page uint, query string, filter map[string][]string, sort map[string]string) ([]models.Widget, error)
-	Like(ctx context.Context, uid, id string) error
-	Dislike(ctx context.Context, uid, id string) error
 	FetchFavorite(ctx context.Context, id string, amount, page uint) ([]models.Widget, error)
 	GetByIds(ctx context.Context, ids []string) ([]models.Widget, error)
 	Update(ctx context.Context, updates map[string]string, id string) error
@@ -83,7 +81,7 @@ func (wr *widgetRepo) Get(ctx context.Context, id string) (*models.Widget, error
 
 func (wr *widgetRepo) FetchFavorite(ctx context.Context, id string, amount, page uint) ([]models.Widget, error) {
 	op := "widgetRepo.FetchFavorite"
-	query := "SELECT w.* FROM widgets w JOIN favorite_widgets fw ON w.id=fw.widget_id WHERE fw.user_id=$1 ORDER BY w.num_of_users DESC OFFSET $2 LIMIT $3"
+	query := "SELECT w.* FROM widgets w JOIN widget_reactions wr ON w.id=wr.widget_id AND wr.reaction='like' WHERE wr.user_id=$1 ORDER BY w.num_of_users DESC OFFSET $2 LIMIT $3"
 	widgets := []models.Widget{}
 	rows, err := wr.Storage.Pool.Query(ctx, query, id, amount*page-amount, amount)
 	if err != nil {
@@ -105,6 +103,7 @@ func (wr *widgetRepo) FetchFavorite(ctx context.Context, id string, amount, page
 			&widget.Link,
 			&widget.Likes,
 			&widget.NumOfUsers,
+			&widget.Reactions,
 		); err != nil {
 			return nil, errs.NewAppError(op, err)
 		}
@@ -116,26 +115,6 @@ func (wr *widgetRepo) FetchFavorite(ctx context.Context, id string, amount, page
 	return widgets, nil
 }
 
-func (wr *widgetRepo) Like(ctx context.Context, uid, id string) error {
-	op := "widgetRepo.Like"
-	query := "INSERT INTO favorite_widgets (widget_id, user_id) VALUES($1,$2)"
-	qd := helpers.NewQueryData(ctx, wr.Storage, op, query, id, uid)
-	if err := qd.InsertWithTx(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (wr *widgetRepo) Dislike(ctx context.Context, uid, id string) error {
-	op := "widgetRepo.Dislike"
-	query := "DELETE FROM favorite_widgets WHERE (widget_id,user_id)=($1,$2)"
-	qd := helpers.NewQueryData(ctx, wr.Storage, op, query, id, uid)
-	if err := qd.DeleteOrUpdateWithTx(); err != nil {
-		return err
-	}
-	return nil
-}
-
 func (wr *widgetRepo) Search(ctx context.Context, amount, page uint, query string, filter map[string][]string, sort map[string]string) ([]models.Widget, error) {
 	op := "widgetRepo.Search"
 	var mainQuery types.Query
@@ -295,6 +274,7 @@ func (wr *widgetRepo) GetByIds(ctx context.Context, ids []string) ([]models.Widg
 				&widget.Link,
 				&widget.Likes,
 				&widget.NumOfUsers,
+				&widget.Reactions,
 			); err != nil {
 				return nil, errs.NewAppError(op, err)
 			}
@@ -319,6 +299,7 @@ func (wr *widgetRepo) GetByIds(ctx context.Context, ids []string) ([]models.Widg
 				&widget.Link,
 				&widget.Likes,
 				&widget.NumOfUsers,
+				&widget.Reactions,
 			); err != nil {
 				return nil, errs.NewAppError(op, err)
 			}
@@ -421,7 +402,6 @@ func (wr *widgetRepo) Update(ctx context.Context, updates map[string]string, id
 	op := "widgetRepo.Update"
 	validFields := map[string]bool{
 		"num_of_users": true,
-		"likes":        true,
 	}
 	validValues := map[string]bool{
 		"+": true,
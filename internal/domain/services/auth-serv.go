@@ -8,63 +8,171 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/url"
 	"readmeow/internal/config"
 	"readmeow/internal/domain/models"
 	"readmeow/internal/domain/repositories"
 	"readmeow/internal/domain/services/utils"
+	"readmeow/internal/dto"
 	em "readmeow/internal/email"
 	"readmeow/pkg/cloudstorage"
 	"readmeow/pkg/errs"
 	"readmeow/pkg/logger"
+	"readmeow/pkg/monitoring"
 	"readmeow/pkg/storage"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthServ interface {
-	Register(ctx context.Context, email, code string) (*loginData, error)
-	Login(ctx context.Context, login, password string) (*loginData, error)
+	Register(ctx context.Context, email, code, ua, ip string) (*loginData, error)
+	Login(ctx context.Context, login, password, ua, ip string) (*loginData, error)
 	SendVerifyCode(ctx context.Context, email, login, nickname, password string) error
 	SendNewCode(ctx context.Context, email string) error
-	OAuthLogin(ctx context.Context, nickname, avatar, email, pid, provider string) (*loginData, error)
+	OAuthLogin(ctx context.Context, profile dto.OAuthProfile, ua, ip string) (*loginData, error)
+	Refresh(ctx context.Context, token, ua, ip string) (*loginData, error)
+	Logout(ctx context.Context, uid, jti string) error
+	FetchSessions(ctx context.Context, uid, jti string) ([]dto.SessionResponse, error)
+	RevokeSession(ctx context.Context, id, uid string) error
+	RevokeAllSessions(ctx context.Context, uid string) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	RequestMagicLink(ctx context.Context, email string) error
+	LoginMagicLink(ctx context.Context, token, ua, ip string) (*loginData, error)
+	RequestEmailChange(ctx context.Context, uid, email string) error
+	ConfirmEmailChange(ctx context.Context, uid, code string) error
+	EnrollTwoFactor(ctx context.Context, uid string) (*dto.TwoFactorEnrollResponse, error)
+	ConfirmTwoFactor(ctx context.Context, uid, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, uid, password string) error
+	RegenerateRecoveryCodes(ctx context.Context, uid, password string) ([]string, error)
+	LoginTwoFactor(ctx context.Context, token, code, ua, ip string) (*loginData, error)
+	CreateAccessToken(ctx context.Context, uid, name string, scopes []string, ttl time.Duration) (*dto.CreateAccessTokenResponse, error)
+	FetchAccessTokens(ctx context.Context, uid string) ([]dto.AccessTokenResponse, error)
+	RevokeAccessToken(ctx context.Context, id, uid string) error
+	AuthenticateAccessToken(ctx context.Context, token string) (string, []string, error)
+	ChangeRole(ctx context.Context, id, role, adminId string) error
+	LinkIdentity(ctx context.Context, uid string, profile dto.OAuthProfile) error
+	FetchIdentities(ctx context.Context, uid string) ([]dto.IdentityResponse, error)
+	UnlinkIdentity(ctx context.Context, uid, provider string) error
+	JWKS() dto.JWKSResponse
+	FetchLoginEvents(ctx context.Context, uid string, amount, page uint) ([]dto.LoginEventResponse, error)
 }
 
 type authServ struct {
-	UserRepo         repositories.UserRepo
-	VerificationRepo repositories.VerificationRepo
-	Transactor       storage.Transactor
-	AuthConfig       config.AuthConfig
-	CloudStorage     cloudstorage.CloudStorage
-	EmailSender      em.EmailSender
-	Logger           *logger.Logger
+	UserRepo          repositories.UserRepo
+	VerificationRepo  repositories.VerificationRepo
+	SessionRepo       repositories.SessionRepo
+	PasswordResetRepo repositories.PasswordResetRepo
+	MagicLinkRepo     repositories.MagicLinkRepo
+	EmailChangeRepo   repositories.EmailChangeRepo
+	TwoFactorRepo     repositories.TwoFactorRepo
+	AccessTokenRepo   repositories.AccessTokenRepo
+	IdentityRepo      repositories.IdentityRepo
+	LoginAttemptRepo  repositories.LoginAttemptRepo
+	LoginEventRepo    repositories.LoginEventRepo
+	ErasureRepo       repositories.ErasureRepo
+	Transactor        storage.Transactor
+	AuthConfig        config.AuthConfig
+	KeySet            *utils.KeySet
+	CloudStorage      cloudstorage.CloudStorage
+	EmailSender       em.EmailSender
+	Metrics           *monitoring.PrometheusSetup
+	Logger            *logger.Logger
 }
 
-func NewAuthServ(ur repositories.UserRepo, vr repositories.VerificationRepo, cs cloudstorage.CloudStorage, t storage.Transactor, es em.EmailSender, l *logger.Logger, cfg config.AuthConfig) AuthServ {
+func NewAuthServ(ur repositories.UserRepo, vr repositories.VerificationRepo, sr repositories.SessionRepo, pr repositories.PasswordResetRepo, mr repositories.MagicLinkRepo, er repositories.EmailChangeRepo, tfr repositories.TwoFactorRepo, atr repositories.AccessTokenRepo, ir repositories.IdentityRepo, lar repositories.LoginAttemptRepo, ler repositories.LoginEventRepo, ear repositories.ErasureRepo, cs cloudstorage.CloudStorage, t storage.Transactor, es em.EmailSender, ps *monitoring.PrometheusSetup, l *logger.Logger, cfg config.AuthConfig, ks *utils.KeySet) AuthServ {
 	return &authServ{
-		UserRepo:         ur,
-		VerificationRepo: vr,
-		Transactor:       t,
-		Logger:           l,
-		EmailSender:      es,
-		CloudStorage:     cs,
-		AuthConfig:       cfg,
+		UserRepo:          ur,
+		VerificationRepo:  vr,
+		SessionRepo:       sr,
+		PasswordResetRepo: pr,
+		MagicLinkRepo:     mr,
+		EmailChangeRepo:   er,
+		TwoFactorRepo:     tfr,
+		AccessTokenRepo:   atr,
+		IdentityRepo:      ir,
+		LoginAttemptRepo:  lar,
+		LoginEventRepo:    ler,
+		ErasureRepo:       ear,
+		Transactor:        t,
+		Logger:            l,
+		EmailSender:       es,
+		Metrics:           ps,
+		CloudStorage:      cs,
+		AuthConfig:        cfg,
+		KeySet:            ks,
 	}
 }
 
 //go:embed assets/default-ava.jpg
 var defaultAvatar []byte
 
+const (
+	emailTimeout       = 30 * time.Second
+	totpIssuer         = "Readmeow"
+	recoveryCodesCount = 10
+	handleAttempts     = 5
+)
+
+// AccessTokenPrefix starts every personal access token.
+const AccessTokenPrefix = "rmw_"
+
 type loginData struct {
-	Id       string
-	Nickname string
-	Avatar   string
-	JWT      string
-	TTL      time.Time
+	Id             string
+	Nickname       string
+	Avatar         string
+	JWT            string
+	TTL            time.Time
+	RefreshToken   string
+	RefreshTTL     time.Time
+	Role           string
+	TwoFactorToken string
+}
+
+// startSession refuses users whose account is waiting to be erased.
+func (as *authServ) startSession(ctx context.Context, ld *loginData, ua, ip string) error {
+	pending, err := as.ErasureRepo.IsPending(ctx, ld.Id)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return errs.ErrForbidden("authServ.startSession")
+	}
+	jti := uuid.New().String()
+	jwtToken, ttl, err := utils.GenerateJWT(as.AuthConfig.TokenTTL, ld.Id, jti, ld.Role, as.KeySet)
+	if err != nil {
+		return err
+	}
+	refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	session := &models.Session{
+		Id:           uuid.New(),
+		UserId:       uuid.MustParse(ld.Id),
+		RefreshToken: refreshHash,
+		Jti:          jti,
+		UserAgent:    ua,
+		Ip:           ip,
+		CreateTime:   now,
+		LastUsedTime: now,
+		ExpiredTime:  now.Add(as.AuthConfig.RefreshTokenTTL),
+	}
+	if err := as.SessionRepo.Create(ctx, session); err != nil {
+		return err
+	}
+	ld.JWT = jwtToken
+	ld.TTL = *ttl
+	ld.RefreshToken = refreshToken
+	ld.RefreshTTL = session.ExpiredTime
+	return nil
 }
 
-func (as *authServ) Register(ctx context.Context, email, code string) (*loginData, error) {
+func (as *authServ) Register(ctx context.Context, email, code, ua, ip string) (*loginData, error) {
 	op := "authServ.Register"
 	log := as.Logger.AddOp(op)
 	log.Info("registering user")
@@ -82,6 +190,10 @@ func (as *authServ) Register(ctx context.Context, email, code string) (*loginDat
 		if err != nil {
 			return nil, err
 		}
+		handle, err := as.newHandle(c, credentials.Nickname)
+		if err != nil {
+			return nil, err
+		}
 		now := time.Now()
 		unow := now.Unix()
 		folder := "avatars"
@@ -90,7 +202,8 @@ func (as *authServ) Register(ctx context.Context, email, code string) (*loginDat
 		file := bytes.NewReader(defaultAvatar)
 		url, pid, err := as.CloudStorage.UploadImage(ctx, file, filename, folder)
 		user := &models.User{
-			Id: id,
+			Id:     id,
+			Handle: handle,
 			Credentials: models.Credentials{
 				Nickname:   credentials.Nickname,
 				Login:      credentials.Login,
@@ -103,6 +216,7 @@ func (as *authServ) Register(ctx context.Context, email, code string) (*loginDat
 			TimeOfRegister: now,
 			NumOfTemplates: 0,
 			NumOfReadmes:   0,
+			Role:           models.RoleUser,
 		}
 		if err := as.VerificationRepo.Delete(c, user.Email); err != nil {
 			return nil, err
@@ -121,57 +235,273 @@ func (as *authServ) Register(ctx context.Context, email, code string) (*loginDat
 		return nil, errs.NewAppError(op, err)
 	}
 	user := res.(*models.User)
-	jwtToken, ttl, err := utils.GenerateJWT(as.AuthConfig.TokenTTL, user.Id.String(), as.AuthConfig.Secret)
-	if err != nil {
-		log.Error("failed to generate jwt token", logger.Err(err))
-		return nil, errs.NewAppError(op, err)
-	}
 	loginData := &loginData{
 		Id:       user.Id.String(),
 		Nickname: *user.Login,
 		Avatar:   user.Avatar,
-		JWT:      jwtToken,
-		TTL:      *ttl,
+		Role:     user.Role,
+	}
+	if err := as.startSession(ctx, loginData, ua, ip); err != nil {
+		log.Error("failed to start session", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
 	}
+	as.recordLogin(ctx, log, loginData.Id, loginMethodRegister, ua, ip, true)
 	log.Info("user registered successfully")
 	return loginData, nil
 }
 
-func (as *authServ) Login(ctx context.Context, login, password string) (*loginData, error) {
+func (as *authServ) Login(ctx context.Context, login, password, ua, ip string) (*loginData, error) {
 	op := "authServ.Login"
 	log := as.Logger.AddOp(op)
 	log.Info("logining user")
 
+	if err := as.checkLoginBlock(ctx, login, ip); err != nil {
+		log.Error("login is blocked", logger.Err(err))
+		as.Metrics.FailedLoginsTotal.WithLabelValues("blocked").Inc()
+		if user, uerr := as.UserRepo.GetByLogin(ctx, login); uerr == nil {
+			as.recordLogin(ctx, log, user.Id.String(), loginMethodPassword, ua, ip, false)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
 	user, err := as.UserRepo.GetByLogin(ctx, login)
 	if err != nil {
 		log.Error("failed to get user by login", logger.Err(err))
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			as.Metrics.FailedLoginsTotal.WithLabelValues("unknown_login").Inc()
+			as.recordFailedLogin(ctx, log, login, ip, nil)
+		}
 		return nil, errs.NewAppError(op, err)
 	}
-	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(password)); err != nil {
+	rehash, err := utils.ComparePassword(user.Password, password)
+	if err != nil {
 		log.Error("invalid credentials", logger.Err(err))
-		return nil, errs.NewAppError(op, err)
+		as.Metrics.FailedLoginsTotal.WithLabelValues("invalid_credentials").Inc()
+		as.recordFailedLogin(ctx, log, login, ip, user)
+		as.recordLogin(ctx, log, user.Id.String(), loginMethodPassword, ua, ip, false)
+		return nil, errs.ErrInvalidCredentials(op)
 	}
-	jwtToken, ttl, err := utils.GenerateJWT(as.AuthConfig.TokenTTL, user.Id.String(), as.AuthConfig.Secret)
-	if err != nil {
-		log.Error("failed to generate jwt token", logger.Err(err))
-		return nil, errs.NewAppError(op, err)
+	if err := as.LoginAttemptRepo.Reset(ctx, loginAttemptUserKey+login); err != nil {
+		log.Error("failed to reset login attempts", logger.Err(err))
+	}
+	if rehash {
+		as.rehashPassword(ctx, log, user.Id.String(), password)
 	}
 	loginData := &loginData{
 		Id:       user.Id.String(),
 		Nickname: *user.Login,
 		Avatar:   user.Avatar,
-		JWT:      jwtToken,
-		TTL:      *ttl,
+		Role:     user.Role,
+	}
+	if err := as.completeLogin(ctx, log, loginData, loginMethodPassword, ua, ip); err != nil {
+		return nil, errs.NewAppError(op, err)
 	}
 
 	log.Info("user loggined successfully")
 	return loginData, nil
 }
 
+// completeLogin finishes the login of a known user, recording it as failed
+// when no session or challenge could be started.
+func (as *authServ) completeLogin(ctx context.Context, log *logger.Logger, ld *loginData, method, ua, ip string) error {
+	fail := func(err error) error {
+		as.recordLogin(ctx, log, ld.Id, method, ua, ip, false)
+		return err
+	}
+	twoFactor, err := as.TwoFactorRepo.Get(ctx, ld.Id)
+	if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		log.Error("failed to get two factor", logger.Err(err))
+		return fail(err)
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		token, tokenHash, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			log.Error("failed to generate two factor token", logger.Err(err))
+			return fail(err)
+		}
+		if err := as.TwoFactorRepo.CreateChallenge(ctx, tokenHash, ld.Id, as.AuthConfig.CodeAttempts, as.AuthConfig.TwoFactorTTL); err != nil {
+			log.Error("failed to create two factor challenge", logger.Err(err))
+			return fail(err)
+		}
+		ld.TwoFactorToken = token
+		log.Info("two factor code required")
+		return nil
+	}
+	if err := as.startSession(ctx, ld, ua, ip); err != nil {
+		log.Error("failed to start session", logger.Err(err))
+		return fail(err)
+	}
+	as.recordLogin(ctx, log, ld.Id, method, ua, ip, true)
+	return nil
+}
+
+const (
+	loginMethodRegister  = "register"
+	loginMethodPassword  = "password"
+	loginMethodMagicLink = "magic_link"
+	loginMethodTwoFactor = "two_factor"
+)
+
+// newHandle derives a free handle from the nickname of a new user, adding a
+// random number when it is taken. Users can change it later.
+func (as *authServ) newHandle(ctx context.Context, nickname string) (string, error) {
+	base := utils.HandleFromNickname(nickname)
+	if utils.IsReservedHandle(base) {
+		base = "user"
+	}
+	handle := base
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for range handleAttempts {
+		taken, err := as.UserRepo.HandleCheck(ctx, handle, uuid.Nil.String())
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return handle, nil
+		}
+		handle = fmt.Sprintf("%s-%04d", base, r.Intn(10000))
+	}
+	return "user-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:utils.MaxHandleLen-5], nil
+}
+
+// recordLogin stores the login event and, for a successful login from a device
+// or ip never seen before, warns the user by email. Failures are only logged so
+// that they never break the login itself.
+func (as *authServ) recordLogin(ctx context.Context, log *logger.Logger, uid, method, ua, ip string, success bool) {
+	device := utils.DeviceFromUserAgent(ua)
+	alert := false
+	if success {
+		seen, known, err := as.LoginEventRepo.CheckDevice(ctx, uid, device, ip)
+		if err != nil {
+			log.Error("failed to check login device", logger.Err(err))
+		}
+		alert = err == nil && seen && !known
+	}
+	now := time.Now()
+	event := &models.LoginEvent{
+		Id:         uuid.New(),
+		UserId:     uuid.MustParse(uid),
+		Method:     method,
+		Success:    success,
+		Ip:         ip,
+		UserAgent:  ua,
+		Device:     device,
+		CreateTime: now,
+	}
+	if err := as.LoginEventRepo.Create(ctx, event); err != nil {
+		log.Error("failed to create login event", logger.Err(err))
+	}
+	if !alert {
+		return
+	}
+	log.Info("login from new device")
+	content, err := em.BuildNewDeviceLetter(device, ip, now.UTC().Format(time.RFC1123), as.AuthConfig.SecurityURL)
+	if err != nil {
+		log.Error("failed to build new device letter", logger.Err(err))
+		return
+	}
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), emailTimeout)
+		defer cancel()
+		user, err := as.UserRepo.Get(c, uid)
+		if err != nil {
+			log.Error("failed to get user", logger.Err(err))
+			return
+		}
+		if err := as.EmailSender.SendMessage(c, "New Sign-in", []byte(content), []string{user.Email}, nil); err != nil {
+			log.Error("failed to send new device letter", logger.Err(err))
+		}
+	}()
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm or parameters.
+// The login itself has already succeeded, so failures are only logged.
+func (as *authServ) rehashPassword(ctx context.Context, log *logger.Logger, uid, password string) {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Error("failed to rehash password", logger.Err(err))
+		return
+	}
+	if err := as.UserRepo.ChangePassword(ctx, uid, passwordHash); err != nil {
+		log.Error("failed to save rehashed password", logger.Err(err))
+		return
+	}
+	log.Info("password rehashed")
+}
+
+const (
+	loginAttemptUserKey = "user:"
+	loginAttemptIPKey   = "ip:"
+)
+
+func (as *authServ) checkLoginBlock(ctx context.Context, login, ip string) error {
+	op := "authServ.checkLoginBlock"
+	for _, key := range []string{loginAttemptUserKey + login, loginAttemptIPKey + ip} {
+		blocked, err := as.LoginAttemptRepo.BlockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+		if blocked > 0 {
+			return errs.ErrTooManyAttempts(op)
+		}
+	}
+	return nil
+}
+
+func (as *authServ) recordFailedLogin(ctx context.Context, log *logger.Logger, login, ip string, user *models.User) {
+	cfg := as.AuthConfig.Lockout
+	if _, err := as.penalizeLogin(ctx, loginAttemptIPKey+ip, cfg.IPMaxAttempts); err != nil {
+		log.Error("failed to record failed login by ip", logger.Err(err))
+	}
+	locked, err := as.penalizeLogin(ctx, loginAttemptUserKey+login, cfg.MaxAttempts)
+	if err != nil {
+		log.Error("failed to record failed login by user", logger.Err(err))
+		return
+	}
+	if !locked || user == nil {
+		return
+	}
+	log.Info("account locked")
+	content, err := em.BuildAccountLockedLetter(ip, cfg.TTL.String())
+	if err != nil {
+		log.Error("failed to build account locked letter", logger.Err(err))
+		return
+	}
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), emailTimeout)
+		defer cancel()
+		if err := as.EmailSender.SendMessage(c, "Account Locked", []byte(content), []string{user.Email}, nil); err != nil {
+			log.Error("failed to send account locked letter", logger.Err(err))
+		}
+	}()
+}
+
+func (as *authServ) penalizeLogin(ctx context.Context, key string, maxAttempts int) (bool, error) {
+	cfg := as.AuthConfig.Lockout
+	attempts, err := as.LoginAttemptRepo.AddFailure(ctx, key, cfg.Window)
+	if err != nil {
+		return false, err
+	}
+	if attempts >= maxAttempts {
+		return as.LoginAttemptRepo.Lock(ctx, key, cfg.TTL)
+	}
+	if attempts > cfg.FreeAttempts {
+		delay := cfg.BaseDelay << min(attempts-cfg.FreeAttempts-1, 30)
+		if delay <= 0 || delay > cfg.MaxDelay {
+			delay = cfg.MaxDelay
+		}
+		return false, as.LoginAttemptRepo.Delay(ctx, key, delay)
+	}
+	return false, nil
+}
+
 func (as *authServ) SendVerifyCode(ctx context.Context, email, login, nickname, password string) error {
 	op := "authServ.SendVerifyCode"
 	log := as.Logger.AddOp(op)
 	log.Info("sending verify code")
+	if utils.IsBreachedPassword(password) {
+		log.Error("password is breached")
+		return errs.ErrBreachedPassword(op)
+	}
 	if _, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
 		exist, err := as.UserRepo.ExistanceCheck(c, login, email)
 		if err != nil {
@@ -183,7 +513,7 @@ func (as *authServ) SendVerifyCode(ctx context.Context, email, login, nickname,
 		r := rand.New(rand.NewSource(time.Now().UnixNano()))
 		code := fmt.Sprintf("%06d", r.Intn(1000000))
 		codeHash := sha256.Sum256([]byte(code))
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+		passwordHash, err := utils.HashPassword(password)
 		if err != nil {
 			return nil, err
 		}
@@ -238,70 +568,995 @@ func (as *authServ) SendNewCode(ctx context.Context, email string) error {
 	return nil
 }
 
-func (as *authServ) OAuthLogin(ctx context.Context, nickname, avatar, email, pid, provider string) (*loginData, error) {
-	op := "authServ.GoogleAuth"
+func (as *authServ) OAuthLogin(ctx context.Context, profile dto.OAuthProfile, ua, ip string) (*loginData, error) {
+	op := "authServ.OAuthLogin"
 	log := as.Logger.AddOp(op)
 	log.Info("user oauth loggining")
 	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		user := &models.User{}
-		var err error
-		user, err = as.UserRepo.GetByProviderId(ctx, pid, provider)
+		return as.oauthUser(c, profile)
+	})
+	if err != nil {
+		log.Error("failed to login user with oauth", logger.Err(err))
+		if identity, ierr := as.IdentityRepo.Get(ctx, profile.Provider, profile.Id); ierr == nil {
+			as.recordLogin(ctx, log, identity.UserId.String(), profile.Provider, ua, ip, false)
+		}
+		return nil, errs.NewAppError(op, err)
+	}
+	user := res.(*models.User)
+	loginData := &loginData{
+		Id:       user.Id.String(),
+		Nickname: user.Nickname,
+		Avatar:   user.Avatar,
+		Role:     user.Role,
+	}
+	if err := as.completeLogin(ctx, log, loginData, profile.Provider, ua, ip); err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+
+	log.Info("user oauth loggined successfully")
+	return loginData, nil
+}
+
+func (as *authServ) oauthUser(ctx context.Context, profile dto.OAuthProfile) (*models.User, error) {
+	identity, err := as.IdentityRepo.Get(ctx, profile.Provider, profile.Id)
+	if err == nil {
+		user, err := as.UserRepo.Get(ctx, identity.UserId.String())
 		if err != nil {
-			if errors.Is(err, errs.ErrNotFoundBase) {
-				user = &models.User{
-					Id: uuid.New(),
-					Credentials: models.Credentials{
-						Nickname:   nickname,
-						Login:      nil,
-						Email:      email,
-						Password:   nil,
-						Provider:   provider,
-						ProviderId: &pid,
-					},
-					Avatar:         avatar,
-					TimeOfRegister: time.Now(),
-					NumOfTemplates: 0,
-					NumOfReadmes:   0,
-				}
-				if err := as.UserRepo.Create(c, user); err != nil {
-					return nil, err
-				}
-			} else {
-				return nil, err
-			}
-		} else {
+			return nil, err
+		}
+		if user.Password == nil && (user.Avatar != profile.Avatar || user.Nickname != profile.Nickname) {
 			updates := map[string]any{}
-			if user.Avatar != avatar || user.Nickname != nickname {
-				if user.Avatar != avatar {
-					updates["avatar"] = avatar
-				}
-				if user.Nickname != nickname {
-					updates["nickname"] = nickname
-				}
-				if err := as.UserRepo.Update(c, updates, user.Id.String()); err != nil {
-					return nil, err
-				}
+			if user.Avatar != profile.Avatar {
+				updates["avatar"] = profile.Avatar
+				user.Avatar = profile.Avatar
 			}
+			if user.Nickname != profile.Nickname {
+				updates["nickname"] = profile.Nickname
+				user.Nickname = profile.Nickname
+			}
+			if err := as.UserRepo.Update(ctx, updates, user.Id.String()); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, errs.ErrNotFoundBase) {
+		return nil, err
+	}
+	var user *models.User
+	if profile.VerifiedEmail && profile.Email != "" {
+		user, err = as.UserRepo.GetByAnyEmail(ctx, profile.Email)
+		if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+			return nil, err
+		}
+	}
+	if user == nil {
+		handle, err := as.newHandle(ctx, profile.Nickname)
+		if err != nil {
+			return nil, err
 		}
+		user = &models.User{
+			Id:     uuid.New(),
+			Handle: handle,
+			Credentials: models.Credentials{
+				Nickname:   profile.Nickname,
+				Login:      nil,
+				Email:      profile.Email,
+				Password:   nil,
+				Provider:   profile.Provider,
+				ProviderId: &profile.Id,
+			},
+			Avatar:         profile.Avatar,
+			TimeOfRegister: time.Now(),
+			NumOfTemplates: 0,
+			NumOfReadmes:   0,
+			Role:           models.RoleUser,
+		}
+		if err := as.UserRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	identity = &models.Identity{
+		Provider:   profile.Provider,
+		ProviderId: profile.Id,
+		UserId:     user.Id,
+		Email:      profile.Email,
+		CreateTime: time.Now(),
+	}
+	if err := as.IdentityRepo.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
-		jwtToken, ttl, err := utils.GenerateJWT(as.AuthConfig.TokenTTL, user.Id.String(), as.AuthConfig.Secret)
+func (as *authServ) Refresh(ctx context.Context, token, ua, ip string) (*loginData, error) {
+	op := "authServ.Refresh"
+	log := as.Logger.AddOp(op)
+	log.Info("refreshing session")
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		session, err := as.SessionRepo.GetByToken(c, utils.HashToken(token))
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				return nil, errs.ErrInvalidToken(op)
+			}
+			return nil, err
+		}
+		if time.Now().After(session.ExpiredTime) {
+			if _, err := as.SessionRepo.Delete(c, session.Id.String(), session.UserId.String()); err != nil {
+				return nil, err
+			}
+			return nil, errs.ErrInvalidToken(op)
+		}
+		user, err := as.UserRepo.Get(c, session.UserId.String())
+		if err != nil {
+			return nil, err
+		}
+		jti := uuid.New().String()
+		jwtToken, ttl, err := utils.GenerateJWT(as.AuthConfig.TokenTTL, user.Id.String(), jti, user.Role, as.KeySet)
 		if err != nil {
 			return nil, err
 		}
+		refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		refreshTTL := now.Add(as.AuthConfig.RefreshTokenTTL)
+		if err := as.SessionRepo.Rotate(c, session.Id.String(), refreshHash, jti, now, refreshTTL); err != nil {
+			return nil, err
+		}
+		if err := as.SessionRepo.Deny(c, session.Jti, as.AuthConfig.TokenTTL); err != nil {
+			return nil, err
+		}
 		loginData := &loginData{
-			Id:       user.Id.String(),
-			Nickname: user.Nickname,
-			Avatar:   user.Avatar,
-			JWT:      jwtToken,
-			TTL:      *ttl,
+			Id:           user.Id.String(),
+			Nickname:     user.Nickname,
+			Avatar:       user.Avatar,
+			Role:         user.Role,
+			JWT:          jwtToken,
+			TTL:          *ttl,
+			RefreshToken: refreshToken,
+			RefreshTTL:   refreshTTL,
 		}
 		return loginData, nil
 	})
 	if err != nil {
-		log.Error("failed to login user with oauth", logger.Err(err))
+		log.Error("failed to refresh session", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
 	}
 
-	log.Info("token generated successfully")
+	log.Info("session refreshed successfully")
 	return res.(*loginData), nil
 }
+
+func (as *authServ) Logout(ctx context.Context, uid, jti string) error {
+	op := "authServ.Logout"
+	log := as.Logger.AddOp(op)
+	log.Info("logging out user")
+	if err := as.SessionRepo.DeleteByJti(ctx, jti, uid); err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		log.Error("failed to delete session", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := as.SessionRepo.Deny(ctx, jti, as.AuthConfig.TokenTTL); err != nil {
+		log.Error("failed to deny access token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("user logged out successfully")
+	return nil
+}
+
+func (as *authServ) FetchSessions(ctx context.Context, uid, jti string) ([]dto.SessionResponse, error) {
+	op := "authServ.FetchSessions"
+	log := as.Logger.AddOp(op)
+	log.Info("fetching user sessions")
+	sessions, err := as.SessionRepo.FetchByUser(ctx, uid)
+	if err != nil {
+		log.Error("failed to fetch user sessions", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	sessionsResp := make([]dto.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		session := dto.SessionResponse{
+			Id:           s.Id.String(),
+			Device:       utils.DeviceFromUserAgent(s.UserAgent),
+			UserAgent:    s.UserAgent,
+			Ip:           s.Ip,
+			CreateTime:   s.CreateTime,
+			LastUsedTime: s.LastUsedTime,
+			ExpiredTime:  s.ExpiredTime,
+			Current:      s.Jti == jti,
+		}
+		sessionsResp = append(sessionsResp, session)
+	}
+	log.Info("user sessions fetched successfully")
+	return sessionsResp, nil
+}
+
+func (as *authServ) FetchLoginEvents(ctx context.Context, uid string, amount, page uint) ([]dto.LoginEventResponse, error) {
+	op := "authServ.FetchLoginEvents"
+	log := as.Logger.AddOp(op)
+	log.Info("fetching login events")
+	events, err := as.LoginEventRepo.FetchByUser(ctx, uid, amount, page)
+	if err != nil {
+		log.Error("failed to fetch login events", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	eventsResp := make([]dto.LoginEventResponse, 0, len(events))
+	for _, e := range events {
+		event := dto.LoginEventResponse{
+			Id:         e.Id.String(),
+			Method:     e.Method,
+			Success:    e.Success,
+			Device:     e.Device,
+			UserAgent:  e.UserAgent,
+			Ip:         e.Ip,
+			CreateTime: e.CreateTime,
+		}
+		eventsResp = append(eventsResp, event)
+	}
+	log.Info("login events fetched successfully")
+	return eventsResp, nil
+}
+
+func (as *authServ) RevokeSession(ctx context.Context, id, uid string) error {
+	op := "authServ.RevokeSession"
+	log := as.Logger.AddOp(op)
+	log.Info("revoking session")
+	jti, err := as.SessionRepo.Delete(ctx, id, uid)
+	if err != nil {
+		log.Error("failed to delete session", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := as.SessionRepo.Deny(ctx, jti, as.AuthConfig.TokenTTL); err != nil {
+		log.Error("failed to deny access token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("session revoked successfully")
+	return nil
+}
+
+func (as *authServ) RevokeAllSessions(ctx context.Context, uid string) error {
+	op := "authServ.RevokeAllSessions"
+	log := as.Logger.AddOp(op)
+	log.Info("revoking all sessions")
+	jtis, err := as.SessionRepo.DeleteAllByUser(ctx, uid)
+	if err != nil {
+		log.Error("failed to delete sessions", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	for _, jti := range jtis {
+		if err := as.SessionRepo.Deny(ctx, jti, as.AuthConfig.TokenTTL); err != nil {
+			log.Error("failed to deny access token", logger.Err(err))
+			return errs.NewAppError(op, err)
+		}
+	}
+	log.Info("all sessions revoked successfully")
+	return nil
+}
+
+func (as *authServ) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	op := "authServ.IsRevoked"
+	denied, err := as.SessionRepo.IsDenied(ctx, jti)
+	if err != nil {
+		return false, errs.NewAppError(op, err)
+	}
+	return denied, nil
+}
+
+func (as *authServ) ForgotPassword(ctx context.Context, email string) error {
+	op := "authServ.ForgotPassword"
+	log := as.Logger.AddOp(op)
+	log.Info("requesting password reset")
+	user, err := as.UserRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			log.Info("password reset requested for unknown email")
+			return nil
+		}
+		log.Error("failed to get user by email", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate reset token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	ttl := time.Now().Add(as.AuthConfig.ResetTokenTTL)
+	if err := as.PasswordResetRepo.Create(ctx, user.Id.String(), tokenHash, ttl); err != nil {
+		log.Error("failed to save reset token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	link := fmt.Sprintf("%s?token=%s", as.AuthConfig.ResetURL, url.QueryEscape(token))
+	content, err := em.BuildResetPasswordLetter(link, as.AuthConfig.ResetTokenTTL.String())
+	if err != nil {
+		log.Error("failed to build reset letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), emailTimeout)
+		defer cancel()
+		if err := as.EmailSender.SendMessage(c, "Password Reset", []byte(content), []string{user.Email}, nil); err != nil {
+			log.Error("failed to send reset letter", logger.Err(err))
+		}
+	}()
+	log.Info("password reset requested successfully")
+	return nil
+}
+
+func (as *authServ) ResetPassword(ctx context.Context, token, password string) error {
+	op := "authServ.ResetPassword"
+	log := as.Logger.AddOp(op)
+	log.Info("resetting password")
+	if utils.IsBreachedPassword(password) {
+		log.Error("password is breached")
+		return errs.ErrBreachedPassword(op)
+	}
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		tokenHash := utils.HashToken(token)
+		uid, ttl, err := as.PasswordResetRepo.Get(c, tokenHash)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				return nil, errs.ErrInvalidToken(op)
+			}
+			return nil, err
+		}
+		if time.Now().After(ttl) {
+			return nil, errs.ErrInvalidToken(op)
+		}
+		if err := as.PasswordResetRepo.Delete(c, tokenHash); err != nil {
+			return nil, err
+		}
+		passwordHash, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		if err := as.UserRepo.ChangePassword(c, uid, passwordHash); err != nil {
+			return nil, err
+		}
+		jtis, err := as.SessionRepo.DeleteAllByUser(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		return jtis, nil
+	})
+	if err != nil {
+		log.Error("failed to reset password", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	for _, jti := range res.([]string) {
+		if err := as.SessionRepo.Deny(ctx, jti, as.AuthConfig.TokenTTL); err != nil {
+			log.Error("failed to deny access token", logger.Err(err))
+			return errs.NewAppError(op, err)
+		}
+	}
+	log.Info("password reset successfully")
+	return nil
+}
+
+func (as *authServ) RequestMagicLink(ctx context.Context, email string) error {
+	op := "authServ.RequestMagicLink"
+	log := as.Logger.AddOp(op)
+	log.Info("requesting magic link")
+	user, err := as.UserRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			log.Info("magic link requested for unknown email")
+			return nil
+		}
+		log.Error("failed to get user by email", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate magic link token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	ttl := time.Now().Add(as.AuthConfig.MagicLinkTTL)
+	if err := as.MagicLinkRepo.Create(ctx, user.Id.String(), tokenHash, ttl); err != nil {
+		log.Error("failed to save magic link token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	link := fmt.Sprintf("%s?token=%s", as.AuthConfig.MagicLinkURL, url.QueryEscape(token))
+	content, err := em.BuildMagicLinkLetter(link, as.AuthConfig.MagicLinkTTL.String())
+	if err != nil {
+		log.Error("failed to build magic link letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), emailTimeout)
+		defer cancel()
+		if err := as.EmailSender.SendMessage(c, "Sign In Link", []byte(content), []string{user.Email}, nil); err != nil {
+			log.Error("failed to send magic link letter", logger.Err(err))
+		}
+	}()
+	log.Info("magic link requested successfully")
+	return nil
+}
+
+func (as *authServ) LoginMagicLink(ctx context.Context, token, ua, ip string) (*loginData, error) {
+	op := "authServ.LoginMagicLink"
+	log := as.Logger.AddOp(op)
+	log.Info("logining user by magic link")
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		tokenHash := utils.HashToken(token)
+		uid, ttl, err := as.MagicLinkRepo.Get(c, tokenHash)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				return nil, errs.ErrInvalidToken(op)
+			}
+			return nil, err
+		}
+		if err := as.MagicLinkRepo.Delete(c, tokenHash); err != nil {
+			return nil, err
+		}
+		if time.Now().After(ttl) {
+			return nil, errs.ErrInvalidToken(op)
+		}
+		return as.UserRepo.Get(c, uid)
+	})
+	if err != nil {
+		log.Error("failed to use magic link", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	user := res.(*models.User)
+	loginData := &loginData{
+		Id:       user.Id.String(),
+		Nickname: user.Nickname,
+		Avatar:   user.Avatar,
+		Role:     user.Role,
+	}
+	if err := as.completeLogin(ctx, log, loginData, loginMethodMagicLink, ua, ip); err != nil {
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("user loggined by magic link successfully")
+	return loginData, nil
+}
+
+func (as *authServ) RequestEmailChange(ctx context.Context, uid, email string) error {
+	op := "authServ.RequestEmailChange"
+	log := as.Logger.AddOp(op)
+	log.Info("requesting email change")
+	user, err := as.UserRepo.Get(ctx, uid)
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if user.Password == nil {
+		log.Error("email of oauth account can not be changed")
+		return errs.ErrInvalidValues(op)
+	}
+	if user.Email == email {
+		log.Error("new email matches the current one")
+		return errs.ErrInvalidValues(op)
+	}
+	exist, err := as.UserRepo.EmailExistanceCheck(ctx, email)
+	if err != nil {
+		log.Error("failed to check email existance", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if exist {
+		log.Error("email already taken")
+		return errs.ErrAlreadyExists(op, nil)
+	}
+	code, err := utils.GenerateCode()
+	if err != nil {
+		log.Error("failed to generate code", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	codeHash := sha256.Sum256([]byte(code))
+	codeTTL := time.Now().Add(as.AuthConfig.CodeTTL)
+	if err := as.EmailChangeRepo.AddCode(ctx, uid, email, codeHash[:], codeTTL, as.AuthConfig.CodeAttempts); err != nil {
+		log.Error("failed to save email change code", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	codeContent, err := em.BuildEmailLetter(code)
+	if err != nil {
+		log.Error("failed to build code letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	noticeContent, err := em.BuildEmailChangeNoticeLetter(email)
+	if err != nil {
+		log.Error("failed to build notice letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := as.EmailSender.SendMessage(ctx, "Email Change Verifying", []byte(codeContent), []string{email}, nil); err != nil {
+		log.Error("failed to send code letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), emailTimeout)
+		defer cancel()
+		if err := as.EmailSender.SendMessage(c, "Email Change Requested", []byte(noticeContent), []string{user.Email}, nil); err != nil {
+			log.Error("failed to send notice letter", logger.Err(err))
+		}
+	}()
+	log.Info("email change requested successfully")
+	return nil
+}
+
+func (as *authServ) ConfirmEmailChange(ctx context.Context, uid, code string) error {
+	op := "authServ.ConfirmEmailChange"
+	log := as.Logger.AddOp(op)
+	log.Info("confirming email change")
+	change, err := as.EmailChangeRepo.Get(ctx, uid)
+	if err != nil {
+		log.Error("failed to get email change", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if time.Now().After(change.ExpiredTime) {
+		log.Error("email change code is expired")
+		return errs.ErrCodeIsExpired(op)
+	}
+	codeHash := sha256.Sum256([]byte(code))
+	if !bytes.Equal(codeHash[:], change.Code) {
+		attempts, err := as.EmailChangeRepo.MinusAttempts(ctx, uid)
+		if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+			log.Error("failed to decrease attempts", logger.Err(err))
+			return errs.NewAppError(op, err)
+		}
+		if attempts == 0 {
+			if err := as.EmailChangeRepo.Delete(ctx, uid); err != nil {
+				log.Error("failed to delete email change", logger.Err(err))
+				return errs.NewAppError(op, err)
+			}
+			log.Error("no attempts left")
+			return errs.ErrZeroAttempts(op)
+		}
+		log.Error("invalid email change code")
+		return errs.ErrInvalidCode(op)
+	}
+	if _, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		exist, err := as.UserRepo.EmailExistanceCheck(c, change.NewEmail)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			return nil, errs.ErrAlreadyExists(op, nil)
+		}
+		if err := as.UserRepo.ChangeEmail(c, uid, change.NewEmail); err != nil {
+			return nil, err
+		}
+		if err := as.EmailChangeRepo.Delete(c, uid); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to change email", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("email changed successfully")
+	return nil
+}
+
+func (as *authServ) EnrollTwoFactor(ctx context.Context, uid string) (*dto.TwoFactorEnrollResponse, error) {
+	op := "authServ.EnrollTwoFactor"
+	log := as.Logger.AddOp(op)
+	log.Info("enrolling two factor")
+	user, err := as.UserRepo.Get(ctx, uid)
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if user.Password == nil {
+		log.Error("two factor is available only for local accounts")
+		return nil, errs.ErrInvalidValues(op)
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Error("failed to generate totp secret", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if err := as.TwoFactorRepo.Create(ctx, uid, secret); err != nil {
+		log.Error("failed to save two factor", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("two factor enrolled successfully")
+	return &dto.TwoFactorEnrollResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+func (as *authServ) ConfirmTwoFactor(ctx context.Context, uid, code string) ([]string, error) {
+	op := "authServ.ConfirmTwoFactor"
+	log := as.Logger.AddOp(op)
+	log.Info("confirming two factor")
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		twoFactor, err := as.TwoFactorRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		if twoFactor.Enabled {
+			return nil, errs.ErrAlreadyExists(op, nil)
+		}
+		step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return nil, errs.ErrInvalidCode(op)
+		}
+		if err := as.TwoFactorRepo.UseStep(c, uid, step); err != nil {
+			return nil, err
+		}
+		if err := as.TwoFactorRepo.Enable(c, uid); err != nil {
+			return nil, err
+		}
+		return as.replaceRecoveryCodes(c, uid)
+	})
+	if err != nil {
+		log.Error("failed to confirm two factor", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("two factor confirmed successfully")
+	return res.([]string), nil
+}
+
+func (as *authServ) DisableTwoFactor(ctx context.Context, uid, password string) error {
+	op := "authServ.DisableTwoFactor"
+	log := as.Logger.AddOp(op)
+	log.Info("disabling two factor")
+	if err := as.checkPassword(ctx, op, uid, password); err != nil {
+		log.Error("failed to check password", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := as.TwoFactorRepo.Delete(ctx, uid); err != nil {
+		log.Error("failed to delete two factor", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("two factor disabled successfully")
+	return nil
+}
+
+func (as *authServ) RegenerateRecoveryCodes(ctx context.Context, uid, password string) ([]string, error) {
+	op := "authServ.RegenerateRecoveryCodes"
+	log := as.Logger.AddOp(op)
+	log.Info("regenerating recovery codes")
+	if err := as.checkPassword(ctx, op, uid, password); err != nil {
+		log.Error("failed to check password", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		twoFactor, err := as.TwoFactorRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !twoFactor.Enabled {
+			return nil, errs.ErrNotFound(op)
+		}
+		return as.replaceRecoveryCodes(c, uid)
+	})
+	if err != nil {
+		log.Error("failed to regenerate recovery codes", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("recovery codes regenerated successfully")
+	return res.([]string), nil
+}
+
+func (as *authServ) LoginTwoFactor(ctx context.Context, token, code, ua, ip string) (*loginData, error) {
+	op := "authServ.LoginTwoFactor"
+	log := as.Logger.AddOp(op)
+	log.Info("checking two factor code")
+	tokenHash := utils.HashToken(token)
+	uid, err := as.TwoFactorRepo.GetChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			log.Error("two factor challenge not found")
+			return nil, errs.ErrInvalidToken(op)
+		}
+		log.Error("failed to get two factor challenge", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	_, err = as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		twoFactor, err := as.TwoFactorRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !twoFactor.Enabled {
+			return nil, errs.ErrInvalidToken(op)
+		}
+		if step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+			if err := as.TwoFactorRepo.UseStep(c, uid, step); err != nil {
+				if errors.Is(err, errs.ErrNotFoundBase) {
+					return nil, errs.ErrInvalidCode(op)
+				}
+				return nil, err
+			}
+			return nil, nil
+		}
+		codeHash := sha256.Sum256([]byte(utils.NormalizeRecoveryCode(code)))
+		if err := as.TwoFactorRepo.UseRecoveryCode(c, uid, codeHash[:]); err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				return nil, errs.ErrInvalidCode(op)
+			}
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		as.recordLogin(ctx, log, uid, loginMethodTwoFactor, ua, ip, false)
+		if errors.Is(err, errs.ErrInvalidCodeBase) {
+			attempts, aerr := as.TwoFactorRepo.MinusChallengeAttempts(ctx, tokenHash)
+			if aerr != nil {
+				log.Error("failed to decrease attempts", logger.Err(aerr))
+				return nil, errs.NewAppError(op, aerr)
+			}
+			if attempts <= 0 {
+				if derr := as.TwoFactorRepo.DeleteChallenge(ctx, tokenHash); derr != nil {
+					log.Error("failed to delete two factor challenge", logger.Err(derr))
+					return nil, errs.NewAppError(op, derr)
+				}
+				log.Error("no attempts left")
+				return nil, errs.ErrZeroAttempts(op)
+			}
+		}
+		log.Error("failed to check two factor code", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if err := as.TwoFactorRepo.DeleteChallenge(ctx, tokenHash); err != nil {
+		log.Error("failed to delete two factor challenge", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	user, err := as.UserRepo.Get(ctx, uid)
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	loginData := &loginData{
+		Id:       user.Id.String(),
+		Nickname: user.Nickname,
+		Avatar:   user.Avatar,
+		Role:     user.Role,
+	}
+	if err := as.startSession(ctx, loginData, ua, ip); err != nil {
+		log.Error("failed to start session", logger.Err(err))
+		as.recordLogin(ctx, log, loginData.Id, loginMethodTwoFactor, ua, ip, false)
+		return nil, errs.NewAppError(op, err)
+	}
+	as.recordLogin(ctx, log, loginData.Id, loginMethodTwoFactor, ua, ip, true)
+	log.Info("user loggined successfully")
+	return loginData, nil
+}
+
+func (as *authServ) checkPassword(ctx context.Context, op, uid, password string) error {
+	passwordHash, err := as.UserRepo.GetPassword(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if _, err := utils.ComparePassword(passwordHash, password); err != nil {
+		return errs.ErrInvalidCredentials(op)
+	}
+	return nil
+}
+
+func (as *authServ) replaceRecoveryCodes(ctx context.Context, uid string) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, 0, len(codes))
+	for _, code := range codes {
+		hash := sha256.Sum256([]byte(code))
+		hashes = append(hashes, hash[:])
+	}
+	if err := as.TwoFactorRepo.ReplaceRecoveryCodes(ctx, uid, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (as *authServ) CreateAccessToken(ctx context.Context, uid, name string, scopes []string, ttl time.Duration) (*dto.CreateAccessTokenResponse, error) {
+	op := "authServ.CreateAccessToken"
+	log := as.Logger.AddOp(op)
+	log.Info("creating access token")
+	token, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate access token", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	token = AccessTokenPrefix + token
+	tokenHash := utils.HashToken(token)
+	uniqueScopes := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			uniqueScopes = append(uniqueScopes, scope)
+		}
+	}
+	now := time.Now()
+	accessToken := &models.AccessToken{
+		Id:          uuid.New(),
+		UserId:      uuid.MustParse(uid),
+		Name:        name,
+		Token:       tokenHash,
+		Scopes:      uniqueScopes,
+		CreateTime:  now,
+		ExpiredTime: now.Add(ttl),
+	}
+	if err := as.AccessTokenRepo.Create(ctx, accessToken); err != nil {
+		log.Error("failed to save access token", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("access token created successfully")
+	return &dto.CreateAccessTokenResponse{
+		AccessTokenResponse: toAccessTokenResponse(accessToken),
+		Token:               token,
+	}, nil
+}
+
+func (as *authServ) FetchAccessTokens(ctx context.Context, uid string) ([]dto.AccessTokenResponse, error) {
+	op := "authServ.FetchAccessTokens"
+	log := as.Logger.AddOp(op)
+	log.Info("fetching access tokens")
+	tokens, err := as.AccessTokenRepo.FetchByUser(ctx, uid)
+	if err != nil {
+		log.Error("failed to fetch access tokens", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	tokensResp := make([]dto.AccessTokenResponse, 0, len(tokens))
+	for i := range tokens {
+		tokensResp = append(tokensResp, toAccessTokenResponse(&tokens[i]))
+	}
+	log.Info("access tokens fetched successfully")
+	return tokensResp, nil
+}
+
+func (as *authServ) RevokeAccessToken(ctx context.Context, id, uid string) error {
+	op := "authServ.RevokeAccessToken"
+	log := as.Logger.AddOp(op)
+	log.Info("revoking access token")
+	if err := as.AccessTokenRepo.Delete(ctx, id, uid); err != nil {
+		log.Error("failed to delete access token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("access token revoked successfully")
+	return nil
+}
+
+func (as *authServ) AuthenticateAccessToken(ctx context.Context, token string) (string, []string, error) {
+	op := "authServ.AuthenticateAccessToken"
+	if !strings.HasPrefix(token, AccessTokenPrefix) {
+		return "", nil, errs.ErrInvalidToken(op)
+	}
+	accessToken, err := as.AccessTokenRepo.GetByToken(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return "", nil, errs.ErrInvalidToken(op)
+		}
+		return "", nil, errs.NewAppError(op, err)
+	}
+	if time.Now().After(accessToken.ExpiredTime) {
+		return "", nil, errs.ErrInvalidToken(op)
+	}
+	if err := as.AccessTokenRepo.Touch(ctx, accessToken.Id.String()); err != nil {
+		return "", nil, errs.NewAppError(op, err)
+	}
+	return accessToken.UserId.String(), accessToken.Scopes, nil
+}
+
+func toAccessTokenResponse(t *models.AccessToken) dto.AccessTokenResponse {
+	return dto.AccessTokenResponse{
+		Id:           t.Id.String(),
+		Name:         t.Name,
+		Scopes:       t.Scopes,
+		CreateTime:   t.CreateTime,
+		LastUsedTime: t.LastUsedTime,
+		ExpiredTime:  t.ExpiredTime,
+	}
+}
+
+func (as *authServ) ChangeRole(ctx context.Context, id, role, adminId string) error {
+	op := "authServ.ChangeRole"
+	log := as.Logger.AddOp(op)
+	log.Info("changing user role")
+	if id == adminId {
+		log.Error("admin can not change own role")
+		return errs.ErrInvalidValues(op)
+	}
+	res, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if err := as.UserRepo.ChangeRole(c, id, role); err != nil {
+			return nil, err
+		}
+		jtis, err := as.SessionRepo.DeleteAllByUser(c, id)
+		if err != nil {
+			return nil, err
+		}
+		return jtis, nil
+	})
+	if err != nil {
+		log.Error("failed to change user role", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	for _, jti := range res.([]string) {
+		if err := as.SessionRepo.Deny(ctx, jti, as.AuthConfig.TokenTTL); err != nil {
+			log.Error("failed to deny access token", logger.Err(err))
+			return errs.NewAppError(op, err)
+		}
+	}
+	log.Info("user role changed successfully")
+	return nil
+}
+
+func (as *authServ) LinkIdentity(ctx context.Context, uid string, profile dto.OAuthProfile) error {
+	op := "authServ.LinkIdentity"
+	log := as.Logger.AddOp(op)
+	log.Info("linking identity")
+	identity, err := as.IdentityRepo.Get(ctx, profile.Provider, profile.Id)
+	if err == nil {
+		if identity.UserId.String() == uid {
+			log.Info("identity already linked")
+			return nil
+		}
+		log.Error("identity linked to another user")
+		return errs.ErrAlreadyExists(op, nil)
+	}
+	if !errors.Is(err, errs.ErrNotFoundBase) {
+		log.Error("failed to get identity", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	identity = &models.Identity{
+		Provider:   profile.Provider,
+		ProviderId: profile.Id,
+		UserId:     uuid.MustParse(uid),
+		Email:      profile.Email,
+		CreateTime: time.Now(),
+	}
+	if err := as.IdentityRepo.Create(ctx, identity); err != nil {
+		log.Error("failed to create identity", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("identity linked successfully")
+	return nil
+}
+
+func (as *authServ) FetchIdentities(ctx context.Context, uid string) ([]dto.IdentityResponse, error) {
+	op := "authServ.FetchIdentities"
+	log := as.Logger.AddOp(op)
+	log.Info("fetching identities")
+	identities, err := as.IdentityRepo.FetchByUser(ctx, uid)
+	if err != nil {
+		log.Error("failed to fetch identities", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	identitiesResp := make([]dto.IdentityResponse, 0, len(identities))
+	for _, i := range identities {
+		identitiesResp = append(identitiesResp, dto.IdentityResponse{
+			Provider:   i.Provider,
+			Email:      i.Email,
+			CreateTime: i.CreateTime,
+		})
+	}
+	log.Info("identities fetched successfully")
+	return identitiesResp, nil
+}
+
+func (as *authServ) UnlinkIdentity(ctx context.Context, uid, provider string) error {
+	op := "authServ.UnlinkIdentity"
+	log := as.Logger.AddOp(op)
+	log.Info("unlinking identity")
+	if _, err := as.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		user, err := as.UserRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		identities, err := as.IdentityRepo.FetchByUser(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		if user.Password == nil && len(identities) <= 1 {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		if err := as.IdentityRepo.Delete(c, uid, provider); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to unlink identity", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("identity unlinked successfully")
+	return nil
+}
+
+func (as *authServ) JWKS() dto.JWKSResponse {
+	return as.KeySet.JWKS()
+}
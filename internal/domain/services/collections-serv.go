@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories"
+	"readmeow/internal/domain/services/utils"
+	"readmeow/internal/dto"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"readmeow/pkg/storage"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CollectionServ interface {
+	Create(ctx context.Context, uid, title, description string, isPublic bool) (*dto.CollectionResponse, error)
+	Get(ctx context.Context, id, uid string) (*dto.CollectionPageResponse, error)
+	GetShared(ctx context.Context, token, uid string) (*dto.CollectionPageResponse, error)
+	Update(ctx context.Context, id, uid, title, description string, isPublic *bool) error
+	Delete(ctx context.Context, id, uid string) error
+	FetchByUser(ctx context.Context, id, uid string, amount, page uint) ([]dto.CollectionResponse, error)
+	FetchFollowed(ctx context.Context, uid string, amount, page uint) ([]dto.CollectionResponse, error)
+	Search(ctx context.Context, uid string, amount, page uint, query string, sort map[string]string) ([]dto.CollectionResponse, error)
+	AddItem(ctx context.Context, id, uid, itemType, itemId string) error
+	RemoveItem(ctx context.Context, id, uid, itemId string) error
+	ReorderItems(ctx context.Context, id, uid string, itemIds []string) error
+	Share(ctx context.Context, id, uid string) (*dto.CollectionShareResponse, error)
+	Unshare(ctx context.Context, id, uid string) error
+	Follow(ctx context.Context, id, uid string) error
+	Unfollow(ctx context.Context, id, uid string) error
+}
+
+type collectionServ struct {
+	CollectionRepo   repositories.CollectionRepo
+	TemplateRepo     repositories.TemplateRepo
+	WidgetRepo       repositories.WidgetRepo
+	OrganizationRepo repositories.OrganizationRepo
+	NotificationServ NotificationServ
+	Transactor       storage.Transactor
+	Logger           *logger.Logger
+}
+
+func NewCollectionServ(cr repositories.CollectionRepo, tr repositories.TemplateRepo, wr repositories.WidgetRepo, or repositories.OrganizationRepo, ns NotificationServ, t storage.Transactor, l *logger.Logger) CollectionServ {
+	return &collectionServ{
+		CollectionRepo:   cr,
+		TemplateRepo:     tr,
+		WidgetRepo:       wr,
+		OrganizationRepo: or,
+		NotificationServ: ns,
+		Transactor:       t,
+		Logger:           l,
+	}
+}
+
+const maxCollectionItems = 200
+
+// owned returns the collection when the user owns it. Others get a not found
+// error for private collections so they can not probe which exist, and a
+// forbidden one for public collections.
+func (cs *collectionServ) owned(ctx context.Context, id, uid string) (*models.CollectionWithOwner, error) {
+	op := "collectionServ.owned"
+	collection, err := cs.CollectionRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if collection.OwnerId.String() != uid {
+		if !collection.IsPublic {
+			return nil, errs.ErrNotFound(op)
+		}
+		return nil, errs.ErrForbidden(op)
+	}
+	return collection, nil
+}
+
+func (cs *collectionServ) Create(ctx context.Context, uid, title, description string, isPublic bool) (*dto.CollectionResponse, error) {
+	op := "collectionServ.Create"
+	log := cs.Logger.AddOp(op)
+	log.Info("creating collection")
+	now := time.Now()
+	collection := &models.Collection{
+		Id:             uuid.New(),
+		OwnerId:        uuid.MustParse(uid),
+		Title:          title,
+		Description:    description,
+		IsPublic:       isPublic,
+		CreateTime:     now,
+		LastUpdateTime: now,
+	}
+	if err := cs.CollectionRepo.Create(ctx, collection); err != nil {
+		log.Error("failed to create collection", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	created, err := cs.CollectionRepo.Get(ctx, collection.Id.String())
+	if err != nil {
+		log.Error("failed to receive collection", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("collection created successfully")
+	resp := toCollectionResponse(created, uid)
+	return &resp, nil
+}
+
+// Get shows public collections to everyone and private ones to their owner
+// only; the rest see them through the share link.
+func (cs *collectionServ) Get(ctx context.Context, id, uid string) (*dto.CollectionPageResponse, error) {
+	op := "collectionServ.Get"
+	log := cs.Logger.AddOp(op)
+	log.Info("receiving collection")
+	collection, err := cs.CollectionRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to receive collection", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if !collection.IsPublic && collection.OwnerId.String() != uid {
+		log.Error("collection is private")
+		return nil, errs.ErrNotFound(op)
+	}
+	page, err := cs.page(ctx, collection, uid)
+	if err != nil {
+		log.Error("failed to receive collection items", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("collection received successfully")
+	return page, nil
+}
+
+// GetShared opens a collection through its share link, private or not.
+func (cs *collectionServ) GetShared(ctx context.Context, token, uid string) (*dto.CollectionPageResponse, error) {
+	op := "collectionServ.GetShared"
+	log := cs.Logger.AddOp(op)
+	log.Info("receiving shared collection")
+	collection, err := cs.CollectionRepo.GetByShareToken(ctx, token)
+	if err != nil {
+		log.Error("failed to receive shared collection", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	page, err := cs.page(ctx, collection, uid)
+	if err != nil {
+		log.Error("failed to receive collection items", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("shared collection received successfully")
+	return page, nil
+}
+
+// page lists the items of the collection in their order. Templates the user
+// can not see are left out.
+func (cs *collectionServ) page(ctx context.Context, collection *models.CollectionWithOwner, uid string) (*dto.CollectionPageResponse, error) {
+	id := collection.Id.String()
+	templates, err := cs.CollectionRepo.FetchTemplateItems(ctx, id, uid)
+	if err != nil {
+		return nil, err
+	}
+	widgets, err := cs.CollectionRepo.FetchWidgetItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	followed, err := cs.CollectionRepo.IsFollowing(ctx, id, uid)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]dto.CollectionItemResponse, 0, len(templates)+len(widgets))
+	for _, i := range templates {
+		t := i.Template
+		items = append(items, dto.CollectionItemResponse{
+			Id:       i.Id.String(),
+			Type:     models.CollectionItemTemplate,
+			Position: i.Position,
+			AddTime:  i.AddTime,
+			Template: &dto.TemplateResponse{
+				TemplateInfo: dto.TemplateInfo{
+					Id:             t.Id.String(),
+					Title:          t.Title,
+					Description:    t.Description,
+					Image:          t.Image,
+					LastUpdateTime: t.LastUpdateTime,
+					NumOfUsers:     t.NumOfUsers,
+					Likes:          t.Likes,
+					Rating:         t.Rating,
+					NumOfRatings:   t.NumOfRatings,
+					Reactions:      t.Reactions,
+					IsPublic:       t.IsPublic,
+				},
+				OwnerInfo: dto.OwnerInfo{
+					OwnerId:       t.OwnerId.String(),
+					OwnerAvatar:   t.OwnerAvatar,
+					OwnerNickname: t.OwnerNickname,
+				},
+			},
+		})
+	}
+	for _, i := range widgets {
+		w := i.Widget
+		items = append(items, dto.CollectionItemResponse{
+			Id:       i.Id.String(),
+			Type:     models.CollectionItemWidget,
+			Position: i.Position,
+			AddTime:  i.AddTime,
+			Widget: &dto.WidgetResponse{
+				Id:          w.Id.String(),
+				Title:       w.Title,
+				Description: w.Description,
+				Image:       w.Image,
+				Likes:       w.Likes,
+				NumOfUsers:  w.NumOfUsers,
+				Reactions:   w.Reactions,
+			},
+		})
+	}
+	slices.SortFunc(items, func(a, b dto.CollectionItemResponse) int {
+		return a.Position - b.Position
+	})
+	return &dto.CollectionPageResponse{
+		CollectionResponse: toCollectionResponse(collection, uid),
+		IsFollowed:         followed,
+		Items:              items,
+	}, nil
+}
+
+func (cs *collectionServ) Update(ctx context.Context, id, uid, title, description string, isPublic *bool) error {
+	op := "collectionServ.Update"
+	log := cs.Logger.AddOp(op)
+	log.Info("updating collection")
+	if _, err := cs.owned(ctx, id, uid); err != nil {
+		log.Error("failed to check collection owner", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := cs.CollectionRepo.Update(ctx, id, title, description, isPublic, time.Now()); err != nil {
+		log.Error("failed to update collection", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection updated successfully")
+	return nil
+}
+
+func (cs *collectionServ) Delete(ctx context.Context, id, uid string) error {
+	op := "collectionServ.Delete"
+	log := cs.Logger.AddOp(op)
+	log.Info("deleting collection")
+	if _, err := cs.owned(ctx, id, uid); err != nil {
+		log.Error("failed to check collection owner", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := cs.CollectionRepo.Delete(ctx, id); err != nil {
+		log.Error("failed to delete collection", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection deleted successfully")
+	return nil
+}
+
+// FetchByUser lists collections of the user, private ones only to the user.
+func (cs *collectionServ) FetchByUser(ctx context.Context, id, uid string, amount, page uint) ([]dto.CollectionResponse, error) {
+	op := "collectionServ.FetchByUser"
+	log := cs.Logger.AddOp(op)
+	log.Info("fetching collections by user")
+	collections, err := cs.CollectionRepo.FetchByUser(ctx, id, id == uid, amount, page)
+	if err != nil {
+		log.Error("failed to fetch collections by user", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("collections fetched successfully")
+	return toCollectionResponses(collections, uid), nil
+}
+
+func (cs *collectionServ) FetchFollowed(ctx context.Context, uid string, amount, page uint) ([]dto.CollectionResponse, error) {
+	op := "collectionServ.FetchFollowed"
+	log := cs.Logger.AddOp(op)
+	log.Info("fetching followed collections")
+	collections, err := cs.CollectionRepo.FetchFollowed(ctx, uid, amount, page)
+	if err != nil {
+		log.Error("failed to fetch followed collections", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("followed collections fetched successfully")
+	return toCollectionResponses(collections, uid), nil
+}
+
+func (cs *collectionServ) Search(ctx context.Context, uid string, amount, page uint, query string, sort map[string]string) ([]dto.CollectionResponse, error) {
+	op := "collectionServ.Search"
+	log := cs.Logger.AddOp(op)
+	log.Info("fetching searched collections")
+	collections, err := cs.CollectionRepo.Search(ctx, amount, page, query, sort)
+	if err != nil {
+		log.Error("failed to fetch searched collections", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("searched collections fetched successfully")
+	return toCollectionResponses(collections, uid), nil
+}
+
+// AddItem appends a template or a widget to the collection. Templates have to
+// be visible to the owner: public, their own or of their organizations.
+func (cs *collectionServ) AddItem(ctx context.Context, id, uid, itemType, itemId string) error {
+	op := "collectionServ.AddItem"
+	log := cs.Logger.AddOp(op)
+	log.Info("adding collection item")
+	if _, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		collection, err := cs.owned(c, id, uid)
+		if err != nil {
+			return nil, err
+		}
+		if collection.NumOfItems >= maxCollectionItems {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		item := &models.CollectionItem{
+			Id:           uuid.New(),
+			CollectionId: collection.Id,
+			AddTime:      time.Now(),
+		}
+		switch itemType {
+		case models.CollectionItemTemplate:
+			template, err := cs.TemplateRepo.Get(c, itemId)
+			if err != nil {
+				return nil, err
+			}
+			if !template.IsPublic && template.OwnerId.String() != uid {
+				if template.OrgId == nil {
+					return nil, errs.ErrNotFound(op)
+				}
+				if _, err := cs.OrganizationRepo.GetMemberRole(c, template.OrgId.String(), uid); err != nil {
+					return nil, err
+				}
+			}
+			item.TemplateId = &template.Id
+		case models.CollectionItemWidget:
+			widget, err := cs.WidgetRepo.Get(c, itemId)
+			if err != nil {
+				return nil, err
+			}
+			item.WidgetId = &widget.Id
+		default:
+			return nil, errs.ErrInvalidFields(op)
+		}
+		if err := cs.CollectionRepo.AddItem(c, item); err != nil {
+			return nil, err
+		}
+		if err := cs.CollectionRepo.Update(c, id, "", "", nil, item.AddTime); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to add collection item", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection item added successfully")
+	return nil
+}
+
+func (cs *collectionServ) RemoveItem(ctx context.Context, id, uid, itemId string) error {
+	op := "collectionServ.RemoveItem"
+	log := cs.Logger.AddOp(op)
+	log.Info("removing collection item")
+	if _, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if _, err := cs.owned(c, id, uid); err != nil {
+			return nil, err
+		}
+		if err := cs.CollectionRepo.RemoveItem(c, id, itemId); err != nil {
+			return nil, err
+		}
+		if err := cs.CollectionRepo.Update(c, id, "", "", nil, time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to remove collection item", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection item removed successfully")
+	return nil
+}
+
+// ReorderItems takes every item of the collection exactly once in the new
+// order.
+func (cs *collectionServ) ReorderItems(ctx context.Context, id, uid string, itemIds []string) error {
+	op := "collectionServ.ReorderItems"
+	log := cs.Logger.AddOp(op)
+	log.Info("reordering collection items")
+	if _, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if _, err := cs.owned(c, id, uid); err != nil {
+			return nil, err
+		}
+		current, err := cs.CollectionRepo.FetchItemIds(c, id)
+		if err != nil {
+			return nil, err
+		}
+		sorted := slices.Clone(itemIds)
+		slices.Sort(sorted)
+		slices.Sort(current)
+		if !slices.Equal(sorted, current) {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		if err := cs.CollectionRepo.Reorder(c, id, itemIds); err != nil {
+			return nil, err
+		}
+		if err := cs.CollectionRepo.Update(c, id, "", "", nil, time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to reorder collection items", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection items reordered successfully")
+	return nil
+}
+
+// Share turns the share link on, replacing the previous one.
+func (cs *collectionServ) Share(ctx context.Context, id, uid string) (*dto.CollectionShareResponse, error) {
+	op := "collectionServ.Share"
+	log := cs.Logger.AddOp(op)
+	log.Info("sharing collection")
+	if _, err := cs.owned(ctx, id, uid); err != nil {
+		log.Error("failed to check collection owner", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	token, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate share token", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if err := cs.CollectionRepo.SetShareToken(ctx, id, &token); err != nil {
+		log.Error("failed to set share token", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("collection shared successfully")
+	return &dto.CollectionShareResponse{ShareToken: token}, nil
+}
+
+func (cs *collectionServ) Unshare(ctx context.Context, id, uid string) error {
+	op := "collectionServ.Unshare"
+	log := cs.Logger.AddOp(op)
+	log.Info("unsharing collection")
+	if _, err := cs.owned(ctx, id, uid); err != nil {
+		log.Error("failed to check collection owner", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := cs.CollectionRepo.SetShareToken(ctx, id, nil); err != nil {
+		log.Error("failed to reset share token", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection unshared successfully")
+	return nil
+}
+
+// Follow subscribes the user to a public collection of someone else.
+func (cs *collectionServ) Follow(ctx context.Context, id, uid string) error {
+	op := "collectionServ.Follow"
+	log := cs.Logger.AddOp(op)
+	log.Info("following collection")
+	res, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		collection, err := cs.CollectionRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if !collection.IsPublic {
+			return nil, errs.ErrNotFound(op)
+		}
+		if collection.OwnerId.String() == uid {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		created, err := cs.CollectionRepo.Follow(c, id, uid, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			return nil, nil
+		}
+		if err := cs.CollectionRepo.AddFollowers(c, id, 1); err != nil {
+			return nil, err
+		}
+		return collection, nil
+	})
+	if err != nil {
+		log.Error("failed to follow collection", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if collection, ok := res.(*models.CollectionWithOwner); ok {
+		if err := cs.NotificationServ.Notify(ctx, collection.OwnerId.String(), uid, models.NotificationCollectionFollowed, id); err != nil {
+			log.Error("failed to notify collection owner", logger.Err(err))
+		}
+	}
+	log.Info("collection followed successfully")
+	return nil
+}
+
+func (cs *collectionServ) Unfollow(ctx context.Context, id, uid string) error {
+	op := "collectionServ.Unfollow"
+	log := cs.Logger.AddOp(op)
+	log.Info("unfollowing collection")
+	if _, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if err := cs.CollectionRepo.Unfollow(c, id, uid); err != nil {
+			return nil, err
+		}
+		if err := cs.CollectionRepo.AddFollowers(c, id, -1); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to unfollow collection", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("collection unfollowed successfully")
+	return nil
+}
+
+func toCollectionResponse(c *models.CollectionWithOwner, uid string) dto.CollectionResponse {
+	resp := dto.CollectionResponse{
+		Id:             c.Id.String(),
+		Title:          c.Title,
+		Description:    c.Description,
+		IsPublic:       c.IsPublic,
+		NumOfFollowers: c.NumOfFollowers,
+		NumOfItems:     c.NumOfItems,
+		CreateTime:     c.CreateTime,
+		LastUpdateTime: c.LastUpdateTime,
+		OwnerInfo: dto.OwnerInfo{
+			OwnerId:       c.OwnerId.String(),
+			OwnerAvatar:   c.OwnerAvatar,
+			OwnerNickname: c.OwnerNickname,
+		},
+	}
+	if c.ShareToken != nil && c.OwnerId.String() == uid {
+		resp.ShareToken = *c.ShareToken
+	}
+	return resp
+}
+
+func toCollectionResponses(collections []models.CollectionWithOwner, uid string) []dto.CollectionResponse {
+	resp := make([]dto.CollectionResponse, 0, len(collections))
+	for _, c := range collections {
+		resp = append(resp, toCollectionResponse(&c, uid))
+	}
+	return resp
+}
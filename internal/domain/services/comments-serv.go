@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories"
+	"readmeow/internal/dto"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"readmeow/pkg/storage"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CommentServ interface {
+	Create(ctx context.Context, target, targetId, uid, parentId, anchorField string, anchorIndex *int, body string) (*dto.CommentResponse, error)
+	Fetch(ctx context.Context, target, targetId, uid string, discussion bool, amount, page uint) ([]dto.CommentThreadResponse, error)
+	Resolve(ctx context.Context, target, targetId, id, uid string) error
+	Unresolve(ctx context.Context, target, targetId, id, uid string) error
+	Delete(ctx context.Context, target, targetId, id, uid, role string) error
+}
+
+type commentServ struct {
+	CommentRepo      repositories.CommentRepo
+	ReadmeRepo       repositories.ReadmeRepo
+	TemplateRepo     repositories.TemplateRepo
+	UserRepo         repositories.UserRepo
+	OrganizationRepo repositories.OrganizationRepo
+	CollaboratorRepo repositories.ReadmeCollaboratorRepo
+	NotificationServ NotificationServ
+	Transactor       storage.Transactor
+	Logger           *logger.Logger
+}
+
+func NewCommentServ(cr repositories.CommentRepo, rr repositories.ReadmeRepo, tr repositories.TemplateRepo, ur repositories.UserRepo, or repositories.OrganizationRepo, rcr repositories.ReadmeCollaboratorRepo, ns NotificationServ, t storage.Transactor, l *logger.Logger) CommentServ {
+	return &commentServ{
+		CommentRepo:      cr,
+		ReadmeRepo:       rr,
+		TemplateRepo:     tr,
+		UserRepo:         ur,
+		OrganizationRepo: or,
+		CollaboratorRepo: rcr,
+		NotificationServ: ns,
+		Transactor:       t,
+		Logger:           l,
+	}
+}
+
+// maxMentions bounds how many users one comment notifies.
+const maxMentions = 10
+
+var mentionRegexp = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@-])@([a-zA-Z0-9](?:[a-zA-Z0-9_-]{1,28}[a-zA-Z0-9]))`)
+
+// commentRights says what a user may do with the comments of one readme or
+// template. Review threads are anchored to blocks, counted in blocks.
+type commentRights struct {
+	review   bool
+	discuss  bool
+	resolve  bool
+	moderate bool
+	blocks   map[string]int
+}
+
+// rights hides readmes and private organization templates from users who can
+// not see them.
+func (cs *commentServ) rights(ctx context.Context, target, targetId, uid string) (*commentRights, error) {
+	op := "commentServ.rights"
+	switch target {
+	case models.CommentTargetReadme:
+		readme, err := cs.ReadmeRepo.Get(ctx, targetId)
+		if err != nil {
+			return nil, err
+		}
+		access, err := readmeAccess(ctx, cs.OrganizationRepo, cs.CollaboratorRepo, readme, uid)
+		if err != nil {
+			return nil, err
+		}
+		if access == "" {
+			return nil, errs.ErrNotFound(op)
+		}
+		return &commentRights{
+			review:   true,
+			resolve:  models.ReadmeAccessAtLeast(access, models.ReadmeAccessEditor),
+			moderate: access == models.ReadmeAccessOwner,
+			blocks:   commentBlocks(readme.Text, readme.Links, readme.Widgets, readme.RenderOrder),
+		}, nil
+	case models.CommentTargetTemplate:
+		template, err := cs.TemplateRepo.Get(ctx, targetId)
+		if err != nil {
+			return nil, err
+		}
+		role := ""
+		if template.OrgId != nil {
+			role, err = cs.OrganizationRepo.GetMemberRole(ctx, template.OrgId.String(), uid)
+			if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+				return nil, err
+			}
+		}
+		owner := template.OwnerId.String() == uid
+		if !template.IsPublic && template.OrgId != nil && role == "" {
+			return nil, errs.ErrNotFound(op)
+		}
+		moderate, err := canManage(ctx, cs.OrganizationRepo, template.OwnerId, template.OrgId, uid)
+		if err != nil {
+			return nil, err
+		}
+		return &commentRights{
+			review:   owner || role != "",
+			discuss:  template.IsPublic,
+			resolve:  owner || models.OrgRoleAtLeast(role, models.OrgRoleEditor),
+			moderate: moderate,
+			blocks:   commentBlocks(template.Text, template.Links, template.Widgets, template.RenderOrder),
+		}, nil
+	}
+	return nil, errs.ErrInvalidValues(op)
+}
+
+func commentBlocks(text, links []string, widgets []map[string]string, order []string) map[string]int {
+	return map[string]int{
+		"title":        1,
+		"text":         len(text),
+		"links":        len(links),
+		"widgets":      len(widgets),
+		"render_order": len(order),
+	}
+}
+
+// commentTargetId returns the readme or template the comment was left on.
+func commentTargetId(comment *models.Comment) string {
+	if comment.ReadmeId != nil {
+		return comment.ReadmeId.String()
+	}
+	return comment.TemplateId.String()
+}
+
+// Create starts a thread or replies to one and notifies the users mentioned
+// by @handle who can see it. Replies to replies join the same thread.
+func (cs *commentServ) Create(ctx context.Context, target, targetId, uid, parentId, anchorField string, anchorIndex *int, body string) (*dto.CommentResponse, error) {
+	op := "commentServ.Create"
+	log := cs.Logger.AddOp(op)
+	log.Info("creating comment")
+	comment := &models.Comment{
+		Id:         uuid.New(),
+		AuthorId:   uuid.MustParse(uid),
+		Body:       body,
+		CreateTime: time.Now(),
+	}
+	tid := uuid.MustParse(targetId)
+	if target == models.CommentTargetReadme {
+		comment.ReadmeId = &tid
+	} else {
+		comment.TemplateId = &tid
+	}
+	type created struct {
+		comment   *models.CommentWithAuthor
+		mentioned []string
+	}
+	res, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		rights, err := cs.rights(c, target, targetId, uid)
+		if err != nil {
+			return nil, err
+		}
+		anchored := anchorField != ""
+		if parentId != "" {
+			if anchored {
+				return nil, errs.ErrInvalidValues(op)
+			}
+			parent, err := cs.CommentRepo.Get(c, parentId)
+			if err != nil {
+				return nil, err
+			}
+			if commentTargetId(parent) != targetId {
+				return nil, errs.ErrNotFound(op)
+			}
+			if parent.ParentId != nil {
+				parent, err = cs.CommentRepo.Get(c, parent.ParentId.String())
+				if err != nil {
+					return nil, err
+				}
+			}
+			comment.ParentId = &parent.Id
+			anchored = parent.AnchorField != nil
+		} else if anchored {
+			if anchorIndex == nil || *anchorIndex >= rights.blocks[anchorField] {
+				return nil, errs.ErrInvalidValues(op)
+			}
+			comment.AnchorField = &anchorField
+			comment.AnchorIndex = anchorIndex
+		}
+		if anchored && !rights.review || !anchored && !rights.discuss {
+			return nil, errs.ErrForbidden(op)
+		}
+		if err := cs.CommentRepo.Create(c, comment); err != nil {
+			return nil, err
+		}
+		author, err := cs.UserRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		mentioned, err := cs.mentioned(c, target, targetId, uid, body, anchored)
+		if err != nil {
+			return nil, err
+		}
+		full := &models.CommentWithAuthor{
+			Comment:        *comment,
+			AuthorNickname: author.Nickname,
+			AuthorHandle:   author.Handle,
+			AuthorAvatar:   author.Avatar,
+		}
+		return created{comment: full, mentioned: mentioned}, nil
+	})
+	if err != nil {
+		log.Error("failed to create comment", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	result := res.(created)
+	notificationType := models.NotificationReadmeMention
+	if target == models.CommentTargetTemplate {
+		notificationType = models.NotificationTemplateMention
+	}
+	for _, id := range result.mentioned {
+		if err := cs.NotificationServ.Notify(ctx, id, uid, notificationType, targetId); err != nil {
+			log.Error("failed to notify mentioned user", logger.Err(err))
+		}
+	}
+	log.Info("comment created successfully")
+	response := toCommentResponse(result.comment)
+	return &response, nil
+}
+
+// mentioned resolves @handles in body to the users who may read the thread.
+// Unknown handles are ignored.
+func (cs *commentServ) mentioned(ctx context.Context, target, targetId, uid, body string, anchored bool) ([]string, error) {
+	seen := make(map[string]bool)
+	ids := []string{}
+	for _, m := range mentionRegexp.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(m[1])
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		if len(seen) > maxMentions {
+			break
+		}
+		user, err := cs.UserRepo.GetByHandle(ctx, handle)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				continue
+			}
+			return nil, err
+		}
+		id := user.Id.String()
+		if id == uid {
+			continue
+		}
+		rights, err := cs.rights(ctx, target, targetId, id)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				continue
+			}
+			return nil, err
+		}
+		if anchored && rights.review || !anchored && rights.discuss {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Fetch lists review threads, or the general discussion of a public template,
+// with their replies.
+func (cs *commentServ) Fetch(ctx context.Context, target, targetId, uid string, discussion bool, amount, page uint) ([]dto.CommentThreadResponse, error) {
+	op := "commentServ.Fetch"
+	log := cs.Logger.AddOp(op)
+	log.Info("receiving comments")
+	rights, err := cs.rights(ctx, target, targetId, uid)
+	if err != nil {
+		log.Error("failed to check comment rights", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if discussion && !rights.discuss || !discussion && !rights.review {
+		log.Error("comments are not available to user")
+		return nil, errs.ErrForbidden(op)
+	}
+	threads, err := commentThreads(ctx, cs.CommentRepo, target, targetId, !discussion, amount, page)
+	if err != nil {
+		log.Error("failed to receive comments", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("comments received successfully")
+	return threads, nil
+}
+
+// commentThreads fetches a page of threads and all replies to them.
+func commentThreads(ctx context.Context, cr repositories.CommentRepo, target, targetId string, anchored bool, amount, page uint) ([]dto.CommentThreadResponse, error) {
+	roots, err := cr.FetchThreads(ctx, target, targetId, anchored, amount, page)
+	if err != nil {
+		return nil, err
+	}
+	threads := make([]dto.CommentThreadResponse, 0, len(roots))
+	if len(roots) == 0 {
+		return threads, nil
+	}
+	ids := make([]string, 0, len(roots))
+	for _, r := range roots {
+		ids = append(ids, r.Id.String())
+	}
+	replies, err := cr.FetchReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byParent := make(map[uuid.UUID][]dto.CommentResponse, len(roots))
+	for _, r := range replies {
+		byParent[*r.ParentId] = append(byParent[*r.ParentId], toCommentResponse(&r))
+	}
+	for _, r := range roots {
+		thread := dto.CommentThreadResponse{
+			CommentResponse: toCommentResponse(&r),
+			Replies:         byParent[r.Id],
+		}
+		if thread.Replies == nil {
+			thread.Replies = []dto.CommentResponse{}
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func (cs *commentServ) Resolve(ctx context.Context, target, targetId, id, uid string) error {
+	op := "commentServ.Resolve"
+	log := cs.Logger.AddOp(op)
+	log.Info("resolving comment thread")
+	if err := cs.setResolved(ctx, target, targetId, id, uid, true); err != nil {
+		log.Error("failed to resolve comment thread", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("comment thread resolved successfully")
+	return nil
+}
+
+func (cs *commentServ) Unresolve(ctx context.Context, target, targetId, id, uid string) error {
+	op := "commentServ.Unresolve"
+	log := cs.Logger.AddOp(op)
+	log.Info("unresolving comment thread")
+	if err := cs.setResolved(ctx, target, targetId, id, uid, false); err != nil {
+		log.Error("failed to unresolve comment thread", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("comment thread unresolved successfully")
+	return nil
+}
+
+// setResolved lets editors and the thread author resolve review threads.
+// Replies and discussion threads can not be resolved.
+func (cs *commentServ) setResolved(ctx context.Context, target, targetId, id, uid string, resolved bool) error {
+	op := "commentServ.setResolved"
+	_, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		comment, err := cs.CommentRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if commentTargetId(comment) != targetId {
+			return nil, errs.ErrNotFound(op)
+		}
+		rights, err := cs.rights(c, target, targetId, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !rights.review {
+			return nil, errs.ErrNotFound(op)
+		}
+		if comment.ParentId != nil || comment.AnchorField == nil {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		if !rights.resolve && comment.AuthorId.String() != uid {
+			return nil, errs.ErrForbidden(op)
+		}
+		if resolved {
+			return nil, cs.CommentRepo.Resolve(c, id, uid, time.Now())
+		}
+		return nil, cs.CommentRepo.Unresolve(c, id)
+	})
+	return err
+}
+
+// Delete lets authors remove their comments, content managers moderate their
+// own readmes and templates and moderators remove anything. Deleting a
+// thread removes its replies.
+func (cs *commentServ) Delete(ctx context.Context, target, targetId, id, uid, role string) error {
+	op := "commentServ.Delete"
+	log := cs.Logger.AddOp(op)
+	log.Info("deleting comment")
+	if _, err := cs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		comment, err := cs.CommentRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if commentTargetId(comment) != targetId {
+			return nil, errs.ErrNotFound(op)
+		}
+		if !models.IsPrivileged(role) {
+			rights, err := cs.rights(c, target, targetId, uid)
+			if err != nil {
+				return nil, err
+			}
+			if !rights.moderate && comment.AuthorId.String() != uid {
+				return nil, errs.ErrForbidden(op)
+			}
+		}
+		return nil, cs.CommentRepo.Delete(c, id)
+	}); err != nil {
+		log.Error("failed to delete comment", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("comment deleted successfully")
+	return nil
+}
+
+func toCommentResponse(c *models.CommentWithAuthor) dto.CommentResponse {
+	res := dto.CommentResponse{
+		Id:             c.Id.String(),
+		AuthorId:       c.AuthorId.String(),
+		AuthorNickname: c.AuthorNickname,
+		AuthorHandle:   c.AuthorHandle,
+		AuthorAvatar:   c.AuthorAvatar,
+		AnchorIndex:    c.AnchorIndex,
+		Body:           c.Body,
+		IsResolved:     c.IsResolved,
+		ResolveTime:    c.ResolveTime,
+		CreateTime:     c.CreateTime,
+	}
+	if c.ParentId != nil {
+		res.ParentId = c.ParentId.String()
+	}
+	if c.AnchorField != nil {
+		res.AnchorField = *c.AnchorField
+	}
+	if c.ResolvedBy != nil {
+		res.ResolvedBy = c.ResolvedBy.String()
+	}
+	return res
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories"
+	"readmeow/internal/dto"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationServ interface {
+	Notify(ctx context.Context, uid, actorId, notificationType, entityId string) error
+	Fetch(ctx context.Context, uid string, amount, page uint) ([]dto.NotificationResponse, error)
+	FetchMissed(ctx context.Context, uid, lastId string) ([]dto.NotificationResponse, error)
+	UnreadCount(ctx context.Context, uid string) (*dto.UnreadCountResponse, error)
+	MarkRead(ctx context.Context, id, uid string) error
+	MarkAllRead(ctx context.Context, uid string) error
+	FetchPreferences(ctx context.Context, uid string) (*dto.NotificationPreferencesResponse, error)
+	UpdatePreferences(ctx context.Context, uid string, preferences map[string]bool) error
+	Subscribe(ctx context.Context, uid string) (<-chan string, func() error)
+}
+
+type notificationServ struct {
+	NotificationRepo repositories.NotificationRepo
+	Logger           *logger.Logger
+}
+
+func NewNotificationServ(nr repositories.NotificationRepo, l *logger.Logger) NotificationServ {
+	return &notificationServ{
+		NotificationRepo: nr,
+		Logger:           l,
+	}
+}
+
+// missedNotificationsLimit bounds how many notifications a reconnecting
+// stream gets replayed; older ones are still available from Fetch.
+const missedNotificationsLimit = 100
+
+// Notify stores a notification for the user and pushes it to their open
+// streams. Nothing is stored when users act on their own content or when the
+// user has turned the type off.
+func (ns *notificationServ) Notify(ctx context.Context, uid, actorId, notificationType, entityId string) error {
+	op := "notificationServ.Notify"
+	log := ns.Logger.AddOp(op)
+	log.Info("notifying user")
+	if uid == actorId {
+		return nil
+	}
+	enabled, err := ns.NotificationRepo.IsEnabled(ctx, uid, notificationType)
+	if err != nil {
+		log.Error("failed to check notification preference", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if !enabled {
+		log.Info("notification type is disabled")
+		return nil
+	}
+	notification := &models.Notification{
+		Id:         uuid.New(),
+		UserId:     uuid.MustParse(uid),
+		ActorId:    uuid.MustParse(actorId),
+		Type:       notificationType,
+		CreateTime: time.Now(),
+	}
+	if entityId != "" {
+		eid := uuid.MustParse(entityId)
+		notification.EntityId = &eid
+	}
+	if err := ns.NotificationRepo.Create(ctx, notification); err != nil {
+		log.Error("failed to create notification", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	full, err := ns.NotificationRepo.Get(ctx, notification.Id.String())
+	if err != nil {
+		log.Error("failed to get notification", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	payload, err := json.Marshal(toNotificationResponse(full))
+	if err != nil {
+		log.Error("failed to marshal notification", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := ns.NotificationRepo.Publish(ctx, uid, payload); err != nil {
+		log.Error("failed to publish notification", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("user notified successfully")
+	return nil
+}
+
+func (ns *notificationServ) Fetch(ctx context.Context, uid string, amount, page uint) ([]dto.NotificationResponse, error) {
+	op := "notificationServ.Fetch"
+	log := ns.Logger.AddOp(op)
+	log.Info("fetching notifications")
+	notifications, err := ns.NotificationRepo.FetchByUser(ctx, uid, amount, page)
+	if err != nil {
+		log.Error("failed to fetch notifications", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	res := make([]dto.NotificationResponse, 0, len(notifications))
+	for _, n := range notifications {
+		res = append(res, toNotificationResponse(&n))
+	}
+	log.Info("notifications fetched successfully")
+	return res, nil
+}
+
+func (ns *notificationServ) FetchMissed(ctx context.Context, uid, lastId string) ([]dto.NotificationResponse, error) {
+	op := "notificationServ.FetchMissed"
+	log := ns.Logger.AddOp(op)
+	log.Info("fetching missed notifications")
+	notifications, err := ns.NotificationRepo.FetchAfter(ctx, uid, lastId, missedNotificationsLimit)
+	if err != nil {
+		log.Error("failed to fetch missed notifications", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	res := make([]dto.NotificationResponse, 0, len(notifications))
+	for _, n := range notifications {
+		res = append(res, toNotificationResponse(&n))
+	}
+	log.Info("missed notifications fetched successfully")
+	return res, nil
+}
+
+func (ns *notificationServ) UnreadCount(ctx context.Context, uid string) (*dto.UnreadCountResponse, error) {
+	op := "notificationServ.UnreadCount"
+	log := ns.Logger.AddOp(op)
+	log.Info("counting unread notifications")
+	count, err := ns.NotificationRepo.CountUnread(ctx, uid)
+	if err != nil {
+		log.Error("failed to count unread notifications", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("unread notifications counted successfully")
+	return &dto.UnreadCountResponse{Count: count}, nil
+}
+
+func (ns *notificationServ) MarkRead(ctx context.Context, id, uid string) error {
+	op := "notificationServ.MarkRead"
+	log := ns.Logger.AddOp(op)
+	log.Info("marking notification as read")
+	if err := ns.NotificationRepo.MarkRead(ctx, id, uid); err != nil {
+		log.Error("failed to mark notification as read", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("notification marked as read successfully")
+	return nil
+}
+
+func (ns *notificationServ) MarkAllRead(ctx context.Context, uid string) error {
+	op := "notificationServ.MarkAllRead"
+	log := ns.Logger.AddOp(op)
+	log.Info("marking all notifications as read")
+	if err := ns.NotificationRepo.MarkAllRead(ctx, uid); err != nil {
+		log.Error("failed to mark all notifications as read", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("all notifications marked as read successfully")
+	return nil
+}
+
+func (ns *notificationServ) FetchPreferences(ctx context.Context, uid string) (*dto.NotificationPreferencesResponse, error) {
+	op := "notificationServ.FetchPreferences"
+	log := ns.Logger.AddOp(op)
+	log.Info("fetching notification preferences")
+	preferences, err := ns.NotificationRepo.FetchPreferences(ctx, uid)
+	if err != nil {
+		log.Error("failed to fetch notification preferences", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("notification preferences fetched successfully")
+	return &dto.NotificationPreferencesResponse{Preferences: preferences}, nil
+}
+
+func (ns *notificationServ) UpdatePreferences(ctx context.Context, uid string, preferences map[string]bool) error {
+	op := "notificationServ.UpdatePreferences"
+	log := ns.Logger.AddOp(op)
+	log.Info("updating notification preferences")
+	for t, enabled := range preferences {
+		if err := ns.NotificationRepo.SetPreference(ctx, uid, t, enabled); err != nil {
+			log.Error("failed to update notification preference", logger.Err(err))
+			return errs.NewAppError(op, err)
+		}
+	}
+	log.Info("notification preferences updated successfully")
+	return nil
+}
+
+func (ns *notificationServ) Subscribe(ctx context.Context, uid string) (<-chan string, func() error) {
+	return ns.NotificationRepo.Subscribe(ctx, uid)
+}
+
+func toNotificationResponse(n *models.NotificationWithActor) dto.NotificationResponse {
+	res := dto.NotificationResponse{
+		Id:            n.Id.String(),
+		Type:          n.Type,
+		ActorId:       n.ActorId.String(),
+		ActorNickname: n.ActorNickname,
+		ActorAvatar:   n.ActorAvatar,
+		IsRead:        n.IsRead,
+		CreateTime:    n.CreateTime,
+	}
+	if n.EntityId != nil {
+		res.EntityId = n.EntityId.String()
+	}
+	return res
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories"
+	"readmeow/internal/dto"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"readmeow/pkg/storage"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrganizationServ interface {
+	Create(ctx context.Context, uid, name, description string) (*dto.OrganizationResponse, error)
+	Get(ctx context.Context, id, uid string) (*dto.OrganizationResponse, error)
+	Update(ctx context.Context, id, uid, name, description string) error
+	Delete(ctx context.Context, id, uid string) error
+	FetchByUser(ctx context.Context, uid string) ([]dto.OrganizationResponse, error)
+	FetchMembers(ctx context.Context, id, uid string, amount, page uint) ([]dto.OrganizationMemberResponse, error)
+	AddMember(ctx context.Context, id, uid, memberId, role string) error
+	UpdateMember(ctx context.Context, id, uid, memberId, role string) error
+	RemoveMember(ctx context.Context, id, uid, memberId string) error
+	FetchTemplates(ctx context.Context, id, uid string, amount, page uint) ([]dto.TemplateResponse, error)
+	FetchReadmes(ctx context.Context, id, uid string, amount, page uint) ([]dto.ReadmeResponse, error)
+}
+
+type organizationServ struct {
+	OrganizationRepo repositories.OrganizationRepo
+	UserRepo         repositories.UserRepo
+	TemplateRepo     repositories.TemplateRepo
+	ReadmeRepo       repositories.ReadmeRepo
+	Transactor       storage.Transactor
+	Logger           *logger.Logger
+}
+
+func NewOrganizationServ(or repositories.OrganizationRepo, ur repositories.UserRepo, tr repositories.TemplateRepo, rr repositories.ReadmeRepo, t storage.Transactor, l *logger.Logger) OrganizationServ {
+	return &organizationServ{
+		OrganizationRepo: or,
+		UserRepo:         ur,
+		TemplateRepo:     tr,
+		ReadmeRepo:       rr,
+		Transactor:       t,
+		Logger:           l,
+	}
+}
+
+// orgRole returns the user's role in the organization. Outsiders get a not
+// found error so they can not probe which organizations exist, members below
+// the minimal role get a forbidden one.
+func orgRole(ctx context.Context, or repositories.OrganizationRepo, orgId, uid, min string) (string, error) {
+	op := "services.orgRole"
+	role, err := or.GetMemberRole(ctx, orgId, uid)
+	if err != nil {
+		return "", err
+	}
+	if !models.OrgRoleAtLeast(role, min) {
+		return "", errs.ErrForbidden(op)
+	}
+	return role, nil
+}
+
+// canManage reports whether the user may delete content. Personal content
+// belongs to its owner alone; in an organization admins manage everything and
+// editors what they created themselves.
+func canManage(ctx context.Context, or repositories.OrganizationRepo, ownerId uuid.UUID, orgId *uuid.UUID, uid string) (bool, error) {
+	if orgId == nil {
+		return ownerId.String() == uid, nil
+	}
+	role, err := or.GetMemberRole(ctx, orgId.String(), uid)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFoundBase) {
+			return false, nil
+		}
+		return false, err
+	}
+	if models.OrgRoleAtLeast(role, models.OrgRoleAdmin) {
+		return true, nil
+	}
+	return ownerId.String() == uid && models.OrgRoleAtLeast(role, models.OrgRoleEditor), nil
+}
+
+func (ors *organizationServ) Create(ctx context.Context, uid, name, description string) (*dto.OrganizationResponse, error) {
+	op := "organizationServ.Create"
+	log := ors.Logger.AddOp(op)
+	log.Info("creating organization")
+	now := time.Now()
+	org := &models.Organization{
+		Id:          uuid.New(),
+		Name:        name,
+		Description: description,
+		CreateTime:  now,
+	}
+	if _, err := ors.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if err := ors.OrganizationRepo.Create(c, org); err != nil {
+			return nil, err
+		}
+		owner := &models.OrganizationMember{
+			OrgId:      org.Id,
+			UserId:     uuid.MustParse(uid),
+			Role:       models.OrgRoleOwner,
+			CreateTime: now,
+		}
+		if err := ors.OrganizationRepo.AddMember(c, owner); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to create organization", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("organization created successfully")
+	return toOrganizationResponse(org, models.OrgRoleOwner), nil
+}
+
+func (ors *organizationServ) Get(ctx context.Context, id, uid string) (*dto.OrganizationResponse, error) {
+	op := "organizationServ.Get"
+	log := ors.Logger.AddOp(op)
+	log.Info("receiving organization")
+	role, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleViewer)
+	if err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	org, err := ors.OrganizationRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to receive organization", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("organization received successfully")
+	return toOrganizationResponse(org, role), nil
+}
+
+func (ors *organizationServ) Update(ctx context.Context, id, uid, name, description string) error {
+	op := "organizationServ.Update"
+	log := ors.Logger.AddOp(op)
+	log.Info("updating organization")
+	if _, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleAdmin); err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := ors.OrganizationRepo.Update(ctx, id, name, description); err != nil {
+		log.Error("failed to update organization", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("organization updated successfully")
+	return nil
+}
+
+func (ors *organizationServ) Delete(ctx context.Context, id, uid string) error {
+	op := "organizationServ.Delete"
+	log := ors.Logger.AddOp(op)
+	log.Info("deleting organization")
+	if _, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleOwner); err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := ors.OrganizationRepo.Delete(ctx, id); err != nil {
+		log.Error("failed to delete organization", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("organization deleted successfully")
+	return nil
+}
+
+func (ors *organizationServ) FetchByUser(ctx context.Context, uid string) ([]dto.OrganizationResponse, error) {
+	op := "organizationServ.FetchByUser"
+	log := ors.Logger.AddOp(op)
+	log.Info("fetching organizations")
+	orgs, err := ors.OrganizationRepo.FetchByUser(ctx, uid)
+	if err != nil {
+		log.Error("failed to fetch organizations", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	res := make([]dto.OrganizationResponse, 0, len(orgs))
+	for _, o := range orgs {
+		res = append(res, *toOrganizationResponse(&o.Organization, o.Role))
+	}
+	log.Info("organizations fetched successfully")
+	return res, nil
+}
+
+func (ors *organizationServ) FetchMembers(ctx context.Context, id, uid string, amount, page uint) ([]dto.OrganizationMemberResponse, error) {
+	op := "organizationServ.FetchMembers"
+	log := ors.Logger.AddOp(op)
+	log.Info("fetching organization members")
+	if _, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleViewer); err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	members, err := ors.OrganizationRepo.FetchMembers(ctx, id, amount, page)
+	if err != nil {
+		log.Error("failed to fetch organization members", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	res := make([]dto.OrganizationMemberResponse, 0, len(members))
+	for _, m := range members {
+		member := dto.OrganizationMemberResponse{
+			UserId:     m.UserId.String(),
+			Nickname:   m.Nickname,
+			Handle:     m.Handle,
+			Avatar:     m.Avatar,
+			Role:       m.Role,
+			CreateTime: m.CreateTime,
+		}
+		res = append(res, member)
+	}
+	log.Info("organization members fetched successfully")
+	return res, nil
+}
+
+// AddMember lets admins invite users with a role no higher than their own.
+func (ors *organizationServ) AddMember(ctx context.Context, id, uid, memberId, role string) error {
+	op := "organizationServ.AddMember"
+	log := ors.Logger.AddOp(op)
+	log.Info("adding organization member")
+	if _, err := ors.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		actorRole, err := orgRole(c, ors.OrganizationRepo, id, uid, models.OrgRoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if !models.OrgRoleAtLeast(actorRole, role) {
+			return nil, errs.ErrForbidden(op)
+		}
+		user, err := ors.UserRepo.Get(c, memberId)
+		if err != nil {
+			return nil, err
+		}
+		member := &models.OrganizationMember{
+			OrgId:      uuid.MustParse(id),
+			UserId:     user.Id,
+			Role:       role,
+			CreateTime: time.Now(),
+		}
+		if err := ors.OrganizationRepo.AddMember(c, member); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to add organization member", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("organization member added successfully")
+	return nil
+}
+
+// UpdateMember changes a member's role. Admins can only touch members whose
+// role, old and new, does not exceed their own, and the last owner can not be
+// demoted.
+func (ors *organizationServ) UpdateMember(ctx context.Context, id, uid, memberId, role string) error {
+	op := "organizationServ.UpdateMember"
+	log := ors.Logger.AddOp(op)
+	log.Info("updating organization member")
+	if _, err := ors.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		actorRole, err := orgRole(c, ors.OrganizationRepo, id, uid, models.OrgRoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		memberRole, err := ors.OrganizationRepo.GetMemberRole(c, id, memberId)
+		if err != nil {
+			return nil, err
+		}
+		if !models.OrgRoleAtLeast(actorRole, memberRole) || !models.OrgRoleAtLeast(actorRole, role) {
+			return nil, errs.ErrForbidden(op)
+		}
+		if err := ors.checkLastOwner(c, id, memberRole, role); err != nil {
+			return nil, err
+		}
+		if err := ors.OrganizationRepo.UpdateMemberRole(c, id, memberId, role); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to update organization member", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("organization member updated successfully")
+	return nil
+}
+
+// RemoveMember lets members leave on their own and admins remove members
+// whose role does not exceed their own.
+func (ors *organizationServ) RemoveMember(ctx context.Context, id, uid, memberId string) error {
+	op := "organizationServ.RemoveMember"
+	log := ors.Logger.AddOp(op)
+	log.Info("removing organization member")
+	if _, err := ors.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		memberRole, err := ors.OrganizationRepo.GetMemberRole(c, id, memberId)
+		if err != nil {
+			return nil, err
+		}
+		if memberId != uid {
+			actorRole, err := orgRole(c, ors.OrganizationRepo, id, uid, models.OrgRoleAdmin)
+			if err != nil {
+				return nil, err
+			}
+			if !models.OrgRoleAtLeast(actorRole, memberRole) {
+				return nil, errs.ErrForbidden(op)
+			}
+		}
+		if err := ors.checkLastOwner(c, id, memberRole, ""); err != nil {
+			return nil, err
+		}
+		if err := ors.OrganizationRepo.RemoveMember(c, id, memberId); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to remove organization member", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("organization member removed successfully")
+	return nil
+}
+
+// checkLastOwner keeps at least one owner in the organization when an owner
+// gets another role or leaves.
+func (ors *organizationServ) checkLastOwner(ctx context.Context, id, oldRole, newRole string) error {
+	op := "organizationServ.checkLastOwner"
+	if oldRole != models.OrgRoleOwner || newRole == models.OrgRoleOwner {
+		return nil
+	}
+	owners, err := ors.OrganizationRepo.CountOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errs.ErrInvalidValues(op)
+	}
+	return nil
+}
+
+func (ors *organizationServ) FetchTemplates(ctx context.Context, id, uid string, amount, page uint) ([]dto.TemplateResponse, error) {
+	op := "organizationServ.FetchTemplates"
+	log := ors.Logger.AddOp(op)
+	log.Info("fetching organization templates")
+	if _, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleViewer); err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	templs, err := ors.TemplateRepo.FetchByOrg(ctx, id, amount, page)
+	if err != nil {
+		log.Error("failed to fetch organization templates", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	templates := make([]dto.TemplateResponse, 0, len(templs))
+	for _, t := range templs {
+		template := dto.TemplateResponse{
+			TemplateInfo: dto.TemplateInfo{
+				Id:             t.Id.String(),
+				Title:          t.Title,
+				Image:          t.Image,
+				Description:    t.Description,
+				LastUpdateTime: t.LastUpdateTime,
+				NumOfUsers:     t.NumOfUsers,
+				Likes:          t.Likes,
+				Rating:         t.Rating,
+				NumOfRatings:   t.NumOfRatings,
+				Reactions:      t.Reactions,
+				IsPublic:       t.IsPublic,
+			},
+			OwnerInfo: dto.OwnerInfo{
+				OwnerId:       t.OwnerId.String(),
+				OwnerAvatar:   t.OwnerAvatar,
+				OwnerNickname: t.OwnerNickname,
+			},
+		}
+		templates = append(templates, template)
+	}
+	log.Info("organization templates fetched successfully")
+	return templates, nil
+}
+
+func (ors *organizationServ) FetchReadmes(ctx context.Context, id, uid string, amount, page uint) ([]dto.ReadmeResponse, error) {
+	op := "organizationServ.FetchReadmes"
+	log := ors.Logger.AddOp(op)
+	log.Info("fetching organization readmes")
+	if _, err := orgRole(ctx, ors.OrganizationRepo, id, uid, models.OrgRoleViewer); err != nil {
+		log.Error("failed to check organization role", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	rdms, err := ors.ReadmeRepo.FetchByOrg(ctx, amount, page, id)
+	if err != nil {
+		log.Error("failed to fetch organization readmes", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	readmes := make([]dto.ReadmeResponse, 0, len(rdms))
+	for _, r := range rdms {
+		readme := dto.ReadmeResponse{
+			Id:             r.Id.String(),
+			Title:          r.Title,
+			Image:          r.Image,
+			LastUpdateTime: r.LastUpdateTime,
+			CreateTime:     r.CreateTime,
+		}
+		readmes = append(readmes, readme)
+	}
+	log.Info("organization readmes fetched successfully")
+	return readmes, nil
+}
+
+func toOrganizationResponse(org *models.Organization, role string) *dto.OrganizationResponse {
+	return &dto.OrganizationResponse{
+		Id:          org.Id.String(),
+		Name:        org.Name,
+		Description: org.Description,
+		Role:        role,
+		CreateTime:  org.CreateTime,
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"readmeow/internal/domain/models"
+	"readmeow/internal/domain/repositories"
+	"readmeow/internal/dto"
+	"readmeow/pkg/errs"
+	"readmeow/pkg/logger"
+	"readmeow/pkg/storage"
+	"time"
+)
+
+type ReactionServ interface {
+	React(ctx context.Context, target, targetId, uid, reaction string) (*dto.ReactionsResponse, error)
+	Unreact(ctx context.Context, target, targetId, uid, reaction string) (*dto.ReactionsResponse, error)
+}
+
+type reactionServ struct {
+	ReactionRepo     repositories.ReactionRepo
+	TemplateRepo     repositories.TemplateRepo
+	WidgetRepo       repositories.WidgetRepo
+	OrganizationRepo repositories.OrganizationRepo
+	NotificationServ NotificationServ
+	Transactor       storage.Transactor
+	Logger           *logger.Logger
+}
+
+func NewReactionServ(rr repositories.ReactionRepo, tr repositories.TemplateRepo, wr repositories.WidgetRepo, or repositories.OrganizationRepo, ns NotificationServ, t storage.Transactor, l *logger.Logger) ReactionServ {
+	return &reactionServ{
+		ReactionRepo:     rr,
+		TemplateRepo:     tr,
+		WidgetRepo:       wr,
+		OrganizationRepo: or,
+		NotificationServ: ns,
+		Transactor:       t,
+		Logger:           l,
+	}
+}
+
+// owner returns who gets notified about likes of the target, empty for
+// widgets. Private templates are hidden from everyone but their owner and the
+// members of their organization.
+func (rs *reactionServ) owner(ctx context.Context, target, targetId, uid string) (string, error) {
+	op := "reactionServ.owner"
+	switch target {
+	case models.ReactionTargetTemplate:
+		template, err := rs.TemplateRepo.Get(ctx, targetId)
+		if err != nil {
+			return "", err
+		}
+		ownerId := template.OwnerId.String()
+		if template.IsPublic || ownerId == uid {
+			return ownerId, nil
+		}
+		if template.OrgId == nil {
+			return "", errs.ErrNotFound(op)
+		}
+		if _, err := rs.OrganizationRepo.GetMemberRole(ctx, template.OrgId.String(), uid); err != nil {
+			if errors.Is(err, errs.ErrNotFoundBase) {
+				return "", errs.ErrNotFound(op)
+			}
+			return "", err
+		}
+		return ownerId, nil
+	case models.ReactionTargetWidget:
+		if _, err := rs.WidgetRepo.Get(ctx, targetId); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+	return "", errs.ErrInvalidValues(op)
+}
+
+// React adds the reaction of the user. Reacting twice the same way keeps a
+// single reaction; the owner of a template hears only about new likes.
+func (rs *reactionServ) React(ctx context.Context, target, targetId, uid, reaction string) (*dto.ReactionsResponse, error) {
+	op := "reactionServ.React"
+	log := rs.Logger.AddOp(op)
+	log.Info("adding reaction")
+	var ownerId string
+	res, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		var err error
+		ownerId, err = rs.owner(c, target, targetId, uid)
+		if err != nil {
+			return nil, err
+		}
+		if err := rs.ReactionRepo.Lock(c, target, targetId); err != nil {
+			return nil, err
+		}
+		created, err := rs.ReactionRepo.Add(c, target, targetId, uid, reaction, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if !created || reaction != models.ReactionLike {
+			ownerId = ""
+		}
+		return rs.reactions(c, target, targetId, uid)
+	})
+	if err != nil {
+		log.Error("failed to add reaction", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if ownerId != "" {
+		if err := rs.NotificationServ.Notify(ctx, ownerId, uid, models.NotificationTemplateLiked, targetId); err != nil {
+			log.Error("failed to notify template owner", logger.Err(err))
+		}
+	}
+	log.Info("reaction added successfully")
+	return res.(*dto.ReactionsResponse), nil
+}
+
+func (rs *reactionServ) Unreact(ctx context.Context, target, targetId, uid, reaction string) (*dto.ReactionsResponse, error) {
+	op := "reactionServ.Unreact"
+	log := rs.Logger.AddOp(op)
+	log.Info("removing reaction")
+	res, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if err := rs.ReactionRepo.Lock(c, target, targetId); err != nil {
+			return nil, err
+		}
+		if err := rs.ReactionRepo.Remove(c, target, targetId, uid, reaction); err != nil {
+			return nil, err
+		}
+		return rs.reactions(c, target, targetId, uid)
+	})
+	if err != nil {
+		log.Error("failed to remove reaction", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("reaction removed successfully")
+	return res.(*dto.ReactionsResponse), nil
+}
+
+// reactions recounts the target within the running transaction, so the
+// counts stay in step with the reaction rows.
+func (rs *reactionServ) reactions(ctx context.Context, target, targetId, uid string) (*dto.ReactionsResponse, error) {
+	counts, err := rs.ReactionRepo.Refresh(ctx, target, targetId)
+	if err != nil {
+		return nil, err
+	}
+	own, err := rs.ReactionRepo.FetchByUser(ctx, target, targetId, uid)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ReactionsResponse{
+		Reactions:     counts,
+		UserReactions: own,
+	}, nil
+}
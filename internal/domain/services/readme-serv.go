@@ -18,44 +18,66 @@ import (
 )
 
 type ReadmeServ interface {
-	Create(ctx context.Context, tid, oid, title string, image *multipart.FileHeader, text, links, order []string, widgets []map[string]string) error
+	Create(ctx context.Context, tid, oid, orgId, title string, image *multipart.FileHeader, text, links, order []string, widgets []map[string]string) error
 	Delete(ctx context.Context, id, uid string) error
-	Update(ctx context.Context, updates map[string]any, id string) error
-	Get(ctx context.Context, id string) (*models.Readme, error)
+	Update(ctx context.Context, updates map[string]any, id, uid string) error
+	Get(ctx context.Context, id, uid string) (*models.Readme, error)
+	GetForEdit(ctx context.Context, id, uid string) (*models.Readme, error)
+	CheckWidgets(ctx context.Context, ids []string) error
 	FetchByUser(ctx context.Context, amount, page uint, uid string) ([]dto.ReadmeResponse, error)
+	FetchByOwner(ctx context.Context, amount, page uint, ownerId, uid string) ([]dto.ReadmeResponse, error)
+	FetchShared(ctx context.Context, amount, page uint, uid string) ([]dto.SharedReadmeResponse, error)
+	FetchCollaborators(ctx context.Context, id, uid string) ([]dto.CollaboratorResponse, error)
+	AddCollaborator(ctx context.Context, id, uid, email, handle, access string) error
+	UpdateCollaborator(ctx context.Context, id, uid, collaboratorId, access string) error
+	RemoveCollaborator(ctx context.Context, id, uid, collaboratorId string) error
 }
 
 type readmeServ struct {
-	ReadmeRepo   repositories.ReadmeRepo
-	UserRepo     repositories.UserRepo
-	TemplateRepo repositories.TemplateRepo
-	WidgetRepo   repositories.WidgetRepo
-	Transactor   storage.Transactor
-	CloudStorage cloudstorage.CloudStorage
-	Logger       *logger.Logger
+	ReadmeRepo       repositories.ReadmeRepo
+	UserRepo         repositories.UserRepo
+	TemplateRepo     repositories.TemplateRepo
+	WidgetRepo       repositories.WidgetRepo
+	OrganizationRepo repositories.OrganizationRepo
+	CollaboratorRepo repositories.ReadmeCollaboratorRepo
+	NotificationServ NotificationServ
+	Transactor       storage.Transactor
+	CloudStorage     cloudstorage.CloudStorage
+	Logger           *logger.Logger
 }
 
-func NewReadmeServ(rr repositories.ReadmeRepo, ur repositories.UserRepo, tr repositories.TemplateRepo, wr repositories.WidgetRepo, t storage.Transactor, cs cloudstorage.CloudStorage, l *logger.Logger) ReadmeServ {
+func NewReadmeServ(rr repositories.ReadmeRepo, ur repositories.UserRepo, tr repositories.TemplateRepo, wr repositories.WidgetRepo, or repositories.OrganizationRepo, rcr repositories.ReadmeCollaboratorRepo, ns NotificationServ, t storage.Transactor, cs cloudstorage.CloudStorage, l *logger.Logger) ReadmeServ {
 	return &readmeServ{
-		ReadmeRepo:   rr,
-		UserRepo:     ur,
-		TemplateRepo: tr,
-		WidgetRepo:   wr,
-		Logger:       l,
-		Transactor:   t,
-		CloudStorage: cs,
+		ReadmeRepo:       rr,
+		UserRepo:         ur,
+		TemplateRepo:     tr,
+		WidgetRepo:       wr,
+		OrganizationRepo: or,
+		CollaboratorRepo: rcr,
+		NotificationServ: ns,
+		Logger:           l,
+		Transactor:       t,
+		CloudStorage:     cs,
 	}
 }
 
-func (rs *readmeServ) Create(ctx context.Context, tid, oid, title string, image *multipart.FileHeader, text, links, order []string, widgets []map[string]string) error {
+func (rs *readmeServ) Create(ctx context.Context, tid, oid, orgId, title string, image *multipart.FileHeader, text, links, order []string, widgets []map[string]string) error {
 	op := "readmeServ.Create"
 	log := rs.Logger.AddOp(op)
 	log.Info("creating readme")
-	_, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+	res, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
 		user, err := rs.UserRepo.Get(c, oid)
 		if err != nil {
 			return nil, err
 		}
+		var org *uuid.UUID
+		if orgId != "" {
+			if _, err := orgRole(c, rs.OrganizationRepo, orgId, oid, models.OrgRoleEditor); err != nil {
+				return nil, err
+			}
+			id := uuid.MustParse(orgId)
+			org = &id
+		}
 		if tid == "" {
 			tid = baseTemplateId.String()
 		}
@@ -63,6 +85,11 @@ func (rs *readmeServ) Create(ctx context.Context, tid, oid, title string, image
 		if err != nil {
 			return nil, err
 		}
+		if !template.IsPublic && template.OrgId != nil {
+			if _, err := rs.OrganizationRepo.GetMemberRole(c, template.OrgId.String(), oid); err != nil {
+				return nil, err
+			}
+		}
 		updateT := map[string]any{
 			"num_of_users": "+",
 		}
@@ -126,6 +153,7 @@ func (rs *readmeServ) Create(ctx context.Context, tid, oid, title string, image
 			RenderOrder:    order,
 			CreateTime:     now,
 			LastUpdateTime: now,
+			OrgId:          org,
 		}
 
 		if err := rs.ReadmeRepo.Create(c, readme); err != nil {
@@ -135,12 +163,18 @@ func (rs *readmeServ) Create(ctx context.Context, tid, oid, title string, image
 			return nil, err
 		}
 
-		return nil, nil
+		return template, nil
 	})
 	if err != nil {
 		log.Error("failed to create new readme", logger.Err(err))
 		return errs.NewAppError(op, err)
 	}
+	template := res.(*models.TemplateWithOwner)
+	if template.Id != baseTemplateId {
+		if err := rs.NotificationServ.Notify(ctx, template.OwnerId.String(), oid, models.NotificationTemplateUsed, template.Id.String()); err != nil {
+			log.Error("failed to notify template owner", logger.Err(err))
+		}
+	}
 	log.Info("new readme created successfully")
 	return nil
 }
@@ -150,17 +184,16 @@ func (rs *readmeServ) Delete(ctx context.Context, id, uid string) error {
 	log := rs.Logger.AddOp(op)
 	log.Info("deleting readme")
 	if _, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		user, err := rs.UserRepo.Get(c, uid)
+		readme, err := rs.ReadmeRepo.Get(c, id)
 		if err != nil {
 			return nil, err
 		}
-		readme, err := rs.ReadmeRepo.Get(c, id)
+		ok, err := canManage(c, rs.OrganizationRepo, readme.OwnerId, readme.OrgId, uid)
 		if err != nil {
 			return nil, err
 		}
-		if readme.OwnerId != user.Id {
-			err := errors.New("readme owner id and user id are not equal")
-			return nil, err
+		if !ok {
+			return nil, errs.ErrForbidden(op)
 		}
 		widgets := make(map[string]struct{})
 		for _, w := range readme.Widgets {
@@ -203,11 +236,18 @@ func (rs *readmeServ) Delete(ctx context.Context, id, uid string) error {
 	return nil
 }
 
-func (rs *readmeServ) Update(ctx context.Context, updates map[string]any, id string) error {
+func (rs *readmeServ) Update(ctx context.Context, updates map[string]any, id, uid string) error {
 	op := "readmeServ.Update"
 	log := rs.Logger.AddOp(op)
 	log.Info("updating readme")
 	if _, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		readme, err := rs.ReadmeRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := rs.checkAccess(c, readme, uid, models.ReadmeAccessEditor); err != nil {
+			return nil, err
+		}
 		fileAnyH, fOk := updates["image"]
 		widgs, wOk := updates["widgets"]
 		var (
@@ -216,11 +256,6 @@ func (rs *readmeServ) Update(ctx context.Context, updates map[string]any, id str
 		)
 		now := time.Now()
 		if fOk || wOk {
-			readme, err := rs.Get(c, id)
-			if err != nil {
-				return nil, err
-			}
-
 			if fOk {
 				oldURL = readme.Image
 				fileH := fileAnyH.(*multipart.FileHeader)
@@ -300,7 +335,7 @@ func (rs *readmeServ) Update(ctx context.Context, updates map[string]any, id str
 	return nil
 }
 
-func (rs *readmeServ) Get(ctx context.Context, id string) (*models.Readme, error) {
+func (rs *readmeServ) Get(ctx context.Context, id, uid string) (*models.Readme, error) {
 	op := "readmeServ.Get"
 	log := rs.Logger.AddOp(op)
 	log.Info("receiving readme")
@@ -309,10 +344,60 @@ func (rs *readmeServ) Get(ctx context.Context, id string) (*models.Readme, error
 		log.Error("failed to receive readme", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
 	}
+	if err := rs.checkAccess(ctx, readme, uid, models.ReadmeAccessViewer); err != nil {
+		log.Error("failed to check readme access", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("readme received successfully")
+	return readme, nil
+}
+
+// GetForEdit returns the readme only if the user may edit it.
+func (rs *readmeServ) GetForEdit(ctx context.Context, id, uid string) (*models.Readme, error) {
+	op := "readmeServ.GetForEdit"
+	log := rs.Logger.AddOp(op)
+	log.Info("receiving readme for editing")
+	readme, err := rs.ReadmeRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to receive readme", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if err := rs.checkAccess(ctx, readme, uid, models.ReadmeAccessEditor); err != nil {
+		log.Error("failed to check readme access", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
 	log.Info("readme received successfully")
 	return readme, nil
 }
 
+// CheckWidgets fails unless every id belongs to an existing widget, so that
+// live edits can't put unknown widgets into a readme.
+func (rs *readmeServ) CheckWidgets(ctx context.Context, ids []string) error {
+	op := "readmeServ.CheckWidgets"
+	log := rs.Logger.AddOp(op)
+	unique := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			log.Error("invalid widget id")
+			return errs.ErrInvalidValues(op)
+		}
+		unique[id] = struct{}{}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	widgets, err := rs.WidgetRepo.GetByIds(ctx, ids)
+	if err != nil {
+		log.Error("failed to receive widgets", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if len(widgets) != len(unique) {
+		log.Error("widgets not found")
+		return errs.ErrNotFound(op)
+	}
+	return nil
+}
+
 func (rs *readmeServ) FetchByUser(ctx context.Context, amount, page uint, uid string) ([]dto.ReadmeResponse, error) {
 	op := "readmeServ.FetchByUser"
 	log := rs.Logger.AddOp(op)
@@ -322,6 +407,29 @@ func (rs *readmeServ) FetchByUser(ctx context.Context, amount, page uint, uid st
 		log.Error("failed to receive readmes", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
 	}
+	log.Info("readmes received successfully")
+	return readmeResponses(rdms), nil
+}
+
+// FetchByOwner lists personal readmes of the owner as the user sees them:
+// all of them for the owner, otherwise only those shared with the user.
+func (rs *readmeServ) FetchByOwner(ctx context.Context, amount, page uint, ownerId, uid string) ([]dto.ReadmeResponse, error) {
+	if ownerId == uid {
+		return rs.FetchByUser(ctx, amount, page, uid)
+	}
+	op := "readmeServ.FetchByOwner"
+	log := rs.Logger.AddOp(op)
+	log.Info("receiving shared readmes by owner")
+	rdms, err := rs.ReadmeRepo.FetchSharedByUser(ctx, amount, page, ownerId, uid)
+	if err != nil {
+		log.Error("failed to receive readmes", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("readmes received successfully")
+	return readmeResponses(rdms), nil
+}
+
+func readmeResponses(rdms []models.Readme) []dto.ReadmeResponse {
 	readmes := make([]dto.ReadmeResponse, 0, len(rdms))
 	for _, r := range rdms {
 		readme := dto.ReadmeResponse{
@@ -333,6 +441,225 @@ func (rs *readmeServ) FetchByUser(ctx context.Context, amount, page uint, uid st
 		}
 		readmes = append(readmes, readme)
 	}
-	log.Info("readmes received successfully")
+	return readmes
+}
+
+func (rs *readmeServ) access(ctx context.Context, readme *models.Readme, uid string) (string, error) {
+	return readmeAccess(ctx, rs.OrganizationRepo, rs.CollaboratorRepo, readme, uid)
+}
+
+// readmeAccess returns the strongest access the user has to the readme: owner
+// for those who may delete it, otherwise what their organization role or
+// collaborator invitation grants. Users without any access get "".
+func readmeAccess(ctx context.Context, or repositories.OrganizationRepo, rcr repositories.ReadmeCollaboratorRepo, readme *models.Readme, uid string) (string, error) {
+	ok, err := canManage(ctx, or, readme.OwnerId, readme.OrgId, uid)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return models.ReadmeAccessOwner, nil
+	}
+	access := ""
+	if readme.OrgId != nil {
+		role, err := or.GetMemberRole(ctx, readme.OrgId.String(), uid)
+		if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+			return "", err
+		}
+		switch {
+		case models.OrgRoleAtLeast(role, models.OrgRoleEditor):
+			return models.ReadmeAccessEditor, nil
+		case role != "":
+			access = models.ReadmeAccessViewer
+		}
+	}
+	shared, err := rcr.GetAccess(ctx, readme.Id.String(), uid)
+	if err != nil && !errors.Is(err, errs.ErrNotFoundBase) {
+		return "", err
+	}
+	if models.ReadmeAccessAtLeast(shared, models.ReadmeAccessViewer) && !models.ReadmeAccessAtLeast(access, shared) {
+		access = shared
+	}
+	return access, nil
+}
+
+// checkAccess hides the readme from users without any access and forbids
+// the rest to do more than their access allows.
+func (rs *readmeServ) checkAccess(ctx context.Context, readme *models.Readme, uid, min string) error {
+	op := "readmeServ.checkAccess"
+	access, err := rs.access(ctx, readme, uid)
+	if err != nil {
+		return err
+	}
+	if access == "" {
+		return errs.ErrNotFound(op)
+	}
+	if !models.ReadmeAccessAtLeast(access, min) {
+		return errs.ErrForbidden(op)
+	}
+	return nil
+}
+
+func (rs *readmeServ) FetchShared(ctx context.Context, amount, page uint, uid string) ([]dto.SharedReadmeResponse, error) {
+	op := "readmeServ.FetchShared"
+	log := rs.Logger.AddOp(op)
+	log.Info("receiving shared readmes")
+	rdms, err := rs.CollaboratorRepo.FetchShared(ctx, uid, amount, page)
+	if err != nil {
+		log.Error("failed to receive shared readmes", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	readmes := make([]dto.SharedReadmeResponse, 0, len(rdms))
+	for _, r := range rdms {
+		readme := dto.SharedReadmeResponse{
+			ReadmeResponse: dto.ReadmeResponse{
+				Id:             r.Id.String(),
+				Title:          r.Title,
+				Image:          r.Image,
+				LastUpdateTime: r.LastUpdateTime,
+				CreateTime:     r.CreateTime,
+			},
+			OwnerId: r.OwnerId.String(),
+			Access:  r.Access,
+		}
+		readmes = append(readmes, readme)
+	}
+	log.Info("shared readmes received successfully")
 	return readmes, nil
 }
+
+func (rs *readmeServ) FetchCollaborators(ctx context.Context, id, uid string) ([]dto.CollaboratorResponse, error) {
+	op := "readmeServ.FetchCollaborators"
+	log := rs.Logger.AddOp(op)
+	log.Info("receiving readme collaborators")
+	readme, err := rs.ReadmeRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to receive readme", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if err := rs.checkAccess(ctx, readme, uid, models.ReadmeAccessViewer); err != nil {
+		log.Error("failed to check readme access", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	collabs, err := rs.CollaboratorRepo.FetchByReadme(ctx, id)
+	if err != nil {
+		log.Error("failed to receive readme collaborators", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	collaborators := make([]dto.CollaboratorResponse, 0, len(collabs))
+	for _, c := range collabs {
+		collaborator := dto.CollaboratorResponse{
+			UserId:     c.UserId.String(),
+			Nickname:   c.Nickname,
+			Handle:     c.Handle,
+			Avatar:     c.Avatar,
+			Access:     c.Access,
+			CreateTime: c.CreateTime,
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	log.Info("readme collaborators received successfully")
+	return collaborators, nil
+}
+
+// AddCollaborator shares the readme with the user found by email or handle
+// and lets them know about it.
+func (rs *readmeServ) AddCollaborator(ctx context.Context, id, uid, email, handle, access string) error {
+	op := "readmeServ.AddCollaborator"
+	log := rs.Logger.AddOp(op)
+	log.Info("adding readme collaborator")
+	res, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		readme, err := rs.ReadmeRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := rs.checkAccess(c, readme, uid, models.ReadmeAccessOwner); err != nil {
+			return nil, err
+		}
+		var user *models.User
+		if email != "" {
+			user, err = rs.UserRepo.GetByEmail(c, email)
+		} else {
+			user, err = rs.UserRepo.GetByHandle(c, handle)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.Id == readme.OwnerId {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		inviter := uuid.MustParse(uid)
+		collaborator := &models.ReadmeCollaborator{
+			ReadmeId:   readme.Id,
+			UserId:     user.Id,
+			Access:     access,
+			InvitedBy:  &inviter,
+			CreateTime: time.Now(),
+		}
+		if err := rs.CollaboratorRepo.Create(c, collaborator); err != nil {
+			return nil, err
+		}
+		return user, nil
+	})
+	if err != nil {
+		log.Error("failed to add readme collaborator", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	user := res.(*models.User)
+	if err := rs.NotificationServ.Notify(ctx, user.Id.String(), uid, models.NotificationReadmeShared, id); err != nil {
+		log.Error("failed to notify collaborator", logger.Err(err))
+	}
+	log.Info("readme collaborator added successfully")
+	return nil
+}
+
+func (rs *readmeServ) UpdateCollaborator(ctx context.Context, id, uid, collaboratorId, access string) error {
+	op := "readmeServ.UpdateCollaborator"
+	log := rs.Logger.AddOp(op)
+	log.Info("updating readme collaborator")
+	if _, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		readme, err := rs.ReadmeRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := rs.checkAccess(c, readme, uid, models.ReadmeAccessOwner); err != nil {
+			return nil, err
+		}
+		if err := rs.CollaboratorRepo.UpdateAccess(c, id, collaboratorId, access); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to update readme collaborator", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("readme collaborator updated successfully")
+	return nil
+}
+
+// RemoveCollaborator stops sharing the readme with the user. Collaborators
+// can remove themselves.
+func (rs *readmeServ) RemoveCollaborator(ctx context.Context, id, uid, collaboratorId string) error {
+	op := "readmeServ.RemoveCollaborator"
+	log := rs.Logger.AddOp(op)
+	log.Info("removing readme collaborator")
+	if _, err := rs.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		if collaboratorId != uid {
+			readme, err := rs.ReadmeRepo.Get(c, id)
+			if err != nil {
+				return nil, err
+			}
+			if err := rs.checkAccess(c, readme, uid, models.ReadmeAccessOwner); err != nil {
+				return nil, err
+			}
+		}
+		if err := rs.CollaboratorRepo.Delete(c, id, collaboratorId); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to remove readme collaborator", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("readme collaborator removed successfully")
+	return nil
+}
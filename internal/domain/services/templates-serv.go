@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"mime/multipart"
 	"readmeow/internal/domain/models"
@@ -18,42 +17,57 @@ import (
 )
 
 type TemplateServ interface {
-	Create(ctx context.Context, oid, title, description string, image *multipart.FileHeader, links, order, text []string, widgets []map[string]string, isPublic bool) error
-	Update(ctx context.Context, updates map[string]any, id string) error
-	Delete(ctx context.Context, id, uid string) error
-	Get(ctx context.Context, id string) (*models.TemplateWithOwner, error)
+	Create(ctx context.Context, oid, orgId, title, description string, image *multipart.FileHeader, links, order, text []string, widgets []map[string]string, isPublic bool) error
+	Update(ctx context.Context, updates map[string]any, id, uid string) error
+	Delete(ctx context.Context, id, uid, role string) error
+	Get(ctx context.Context, id, uid string) (*dto.TemplatePageResponse, error)
 	FetchFavorite(ctx context.Context, id string, amount, page uint) ([]dto.TemplateResponse, error)
 	FetchByUser(ctx context.Context, id string, showPrivate bool, amount, page uint) ([]dto.TemplateInfo, error)
-	Search(ctx context.Context, amount, page uint, query string, filter map[string]bool, sort map[string]string) ([]dto.TemplateResponse, error)
-	Like(ctx context.Context, id, uid string) error
-	Dislike(ctx context.Context, id, uid string) error
+	Search(ctx context.Context, uid string, amount, page uint, query, orgId string, filter map[string]bool, minRating float64, sort map[string]string) ([]dto.TemplateResponse, error)
+	Rate(ctx context.Context, id, uid string, stars int, review string) error
+	DeleteRating(ctx context.Context, id, uid string) error
+	FetchRatings(ctx context.Context, id, uid string, amount, page uint) ([]dto.TemplateRatingResponse, error)
 }
 
 type templateServ struct {
-	TemplateRepo repositories.TemplateRepo
-	UserRepo     repositories.UserRepo
-	WidgetRepo   repositories.WidgetRepo
-	ReadmeRepo   repositories.ReadmeRepo
-	Transactor   storage.Transactor
-	CloudStorage cloudstorage.CloudStorage
-	Logger       *logger.Logger
+	TemplateRepo     repositories.TemplateRepo
+	RatingRepo       repositories.TemplateRatingRepo
+	UserRepo         repositories.UserRepo
+	WidgetRepo       repositories.WidgetRepo
+	ReadmeRepo       repositories.ReadmeRepo
+	OrganizationRepo repositories.OrganizationRepo
+	CommentRepo      repositories.CommentRepo
+	NotificationServ NotificationServ
+	Transactor       storage.Transactor
+	CloudStorage     cloudstorage.CloudStorage
+	Logger           *logger.Logger
 }
 
-func NewTemplateServ(tr repositories.TemplateRepo, rr repositories.ReadmeRepo, ur repositories.UserRepo, wr repositories.WidgetRepo, t storage.Transactor, cs cloudstorage.CloudStorage, l *logger.Logger) TemplateServ {
+func NewTemplateServ(tr repositories.TemplateRepo, trr repositories.TemplateRatingRepo, rr repositories.ReadmeRepo, ur repositories.UserRepo, wr repositories.WidgetRepo, or repositories.OrganizationRepo, cr repositories.CommentRepo, ns NotificationServ, t storage.Transactor, cs cloudstorage.CloudStorage, l *logger.Logger) TemplateServ {
 	return &templateServ{
-		TemplateRepo: tr,
-		ReadmeRepo:   rr,
-		UserRepo:     ur,
-		WidgetRepo:   wr,
-		Transactor:   t,
-		CloudStorage: cs,
-		Logger:       l,
+		TemplateRepo:     tr,
+		RatingRepo:       trr,
+		ReadmeRepo:       rr,
+		UserRepo:         ur,
+		WidgetRepo:       wr,
+		OrganizationRepo: or,
+		CommentRepo:      cr,
+		NotificationServ: ns,
+		Transactor:       t,
+		CloudStorage:     cs,
+		Logger:           l,
 	}
 }
 
 var baseTemplateId = uuid.Nil
 
-func (ts *templateServ) Create(ctx context.Context, oid, title, description string, image *multipart.FileHeader, links, order, text []string, widgets []map[string]string, isPublic bool) error {
+// discussionPreview is how many discussion threads come with a template, the
+// rest are fetched page by page.
+const discussionPreview = 20
+
+// Create stores a personal template, or an organization one when orgId is
+// set; creating in an organization takes at least the editor role.
+func (ts *templateServ) Create(ctx context.Context, oid, orgId, title, description string, image *multipart.FileHeader, links, order, text []string, widgets []map[string]string, isPublic bool) error {
 	op := "templateServ.Create"
 	log := ts.Logger.AddOp(op)
 	log.Info("creating template")
@@ -62,6 +76,14 @@ func (ts *templateServ) Create(ctx context.Context, oid, title, description stri
 		if err != nil {
 			return nil, err
 		}
+		var org *uuid.UUID
+		if orgId != "" {
+			if _, err := orgRole(c, ts.OrganizationRepo, orgId, oid, models.OrgRoleEditor); err != nil {
+				return nil, err
+			}
+			id := uuid.MustParse(orgId)
+			org = &id
+		}
 
 		id := uuid.New()
 
@@ -125,6 +147,7 @@ func (ts *templateServ) Create(ctx context.Context, oid, title, description stri
 			CreateTime:     now,
 			LastUpdateTime: now,
 			IsPublic:       isPublic,
+			OrgId:          org,
 		}
 		if err := ts.TemplateRepo.Create(c, template); err != nil {
 			if cerr := ts.CloudStorage.DeleteImage(c, pid); cerr != nil {
@@ -162,17 +185,33 @@ func (ts *templateServ) FetchByUser(ctx context.Context, id string, showPrivate
 			LastUpdateTime: t.LastUpdateTime,
 			NumOfUsers:     t.NumOfUsers,
 			Likes:          t.Likes,
+			Rating:         t.Rating,
+			NumOfRatings:   t.NumOfRatings,
+			Reactions:      t.Reactions,
 		}
 		templResp = append(templResp, template)
 	}
 	return templResp, nil
 }
 
-func (ts *templateServ) Update(ctx context.Context, updates map[string]any, id string) error {
+// Update is open to those who may delete the template, see canManage.
+// Moderators only get to delete templates, not to edit them.
+func (ts *templateServ) Update(ctx context.Context, updates map[string]any, id, uid string) error {
 	op := "templateServ.Update"
 	log := ts.Logger.AddOp(op)
 	log.Info("updating template")
 	if _, err := ts.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		template, err := ts.TemplateRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := canManage(c, ts.OrganizationRepo, template.OwnerId, template.OrgId, uid)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errs.ErrForbidden(op)
+		}
 		fileAnyH, ok := updates["image"]
 		now := time.Now()
 		var (
@@ -226,22 +265,27 @@ func (ts *templateServ) Update(ctx context.Context, updates map[string]any, id s
 	return nil
 }
 
-func (ts *templateServ) Delete(ctx context.Context, id, uid string) error {
+func (ts *templateServ) Delete(ctx context.Context, id, uid, role string) error {
 	op := "templateServ.Delete"
 	log := ts.Logger.AddOp(op)
 	log.Info("deleting template")
+	if id == baseTemplateId.String() {
+		log.Error("base template can not be deleted")
+		return errs.ErrInvalidValues(op)
+	}
 	if _, err := ts.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		user, err := ts.UserRepo.Get(c, uid)
-		if err != nil {
-			return nil, err
-		}
 		template, err := ts.TemplateRepo.Get(c, id)
 		if err != nil {
 			return nil, err
 		}
-		if template.OwnerId != user.Id {
-			err := errors.New("user is not template owner")
-			return nil, err
+		if !models.IsPrivileged(role) {
+			ok, err := canManage(c, ts.OrganizationRepo, template.OwnerId, template.OrgId, uid)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errs.ErrForbidden(op)
+			}
 		}
 
 		tupd := map[string]any{
@@ -274,7 +318,10 @@ func (ts *templateServ) Delete(ctx context.Context, id, uid string) error {
 	return nil
 }
 
-func (ts *templateServ) Get(ctx context.Context, id string) (*models.TemplateWithOwner, error) {
+// Get hides private organization templates from everyone outside the
+// organization as if they did not exist. Public templates come with the
+// first threads of their discussion.
+func (ts *templateServ) Get(ctx context.Context, id, uid string) (*dto.TemplatePageResponse, error) {
 	op := "templateServ.Get"
 	log := ts.Logger.AddOp(op)
 	log.Info("receiving template")
@@ -284,9 +331,27 @@ func (ts *templateServ) Get(ctx context.Context, id string) (*models.TemplateWit
 		log.Error("failed to receive template", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
 	}
+	if !template.IsPublic && template.OrgId != nil {
+		if _, err := ts.OrganizationRepo.GetMemberRole(ctx, template.OrgId.String(), uid); err != nil {
+			log.Error("failed to check organization membership", logger.Err(err))
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+
+	page := &dto.TemplatePageResponse{
+		TemplateWithOwner: *template,
+		Discussion:        []dto.CommentThreadResponse{},
+	}
+	if template.IsPublic {
+		page.Discussion, err = commentThreads(ctx, ts.CommentRepo, models.CommentTargetTemplate, id, false, discussionPreview, 1)
+		if err != nil {
+			log.Error("failed to receive template discussion", logger.Err(err))
+			return nil, errs.NewAppError(op, err)
+		}
+	}
 
 	log.Info("template received successfully")
-	return template, nil
+	return page, nil
 }
 
 func (ts *templateServ) FetchFavorite(ctx context.Context, id string, amount, page uint) ([]dto.TemplateResponse, error) {
@@ -309,6 +374,9 @@ func (ts *templateServ) FetchFavorite(ctx context.Context, id string, amount, pa
 				LastUpdateTime: t.LastUpdateTime,
 				NumOfUsers:     t.NumOfUsers,
 				Likes:          t.Likes,
+				Rating:         t.Rating,
+				NumOfRatings:   t.NumOfRatings,
+				Reactions:      t.Reactions,
 			},
 			OwnerInfo: dto.OwnerInfo{
 				OwnerId:       t.OwnerId.String(),
@@ -322,11 +390,22 @@ func (ts *templateServ) FetchFavorite(ctx context.Context, id string, amount, pa
 	return templates, nil
 }
 
-func (ts *templateServ) Search(ctx context.Context, amount, page uint, query string, filter map[string]bool, sort map[string]string) ([]dto.TemplateResponse, error) {
+// Search shows anonymous users public templates only; members also find the
+// private templates of their organizations.
+func (ts *templateServ) Search(ctx context.Context, uid string, amount, page uint, query, orgId string, filter map[string]bool, minRating float64, sort map[string]string) ([]dto.TemplateResponse, error) {
 	op := "templateServ.Search"
 	log := ts.Logger.AddOp(op)
 	log.Info("fetching searched templates")
-	templs, err := ts.TemplateRepo.Search(ctx, amount, page, query, filter, sort)
+	orgIds := []string{}
+	if uid != "" {
+		ids, err := ts.OrganizationRepo.FetchIds(ctx, uid)
+		if err != nil {
+			log.Error("failed to fetch user organizations", logger.Err(err))
+			return nil, errs.NewAppError(op, err)
+		}
+		orgIds = ids
+	}
+	templs, err := ts.TemplateRepo.Search(ctx, amount, page, query, orgId, orgIds, filter, minRating, sort)
 	if err != nil {
 		log.Error("failed to fetch searched templates", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
@@ -342,6 +421,9 @@ func (ts *templateServ) Search(ctx context.Context, amount, page uint, query str
 				LastUpdateTime: t.LastUpdateTime,
 				NumOfUsers:     t.NumOfUsers,
 				Likes:          t.Likes,
+				Rating:         t.Rating,
+				NumOfRatings:   t.NumOfRatings,
+				Reactions:      t.Reactions,
 			},
 			OwnerInfo: dto.OwnerInfo{
 				OwnerId:       t.OwnerId.String(),
@@ -355,48 +437,107 @@ func (ts *templateServ) Search(ctx context.Context, amount, page uint, query str
 	return templates, nil
 }
 
-func (ts *templateServ) Like(ctx context.Context, id, uid string) error {
-	op := "templateServ.Like"
+func (ts *templateServ) Rate(ctx context.Context, id, uid string, stars int, review string) error {
+	op := "templateServ.Rate"
 	log := ts.Logger.AddOp(op)
-	log.Info("liking template")
+	log.Info("rating template")
 	if _, err := ts.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		if err := ts.TemplateRepo.Like(c, id, uid); err != nil {
+		template, err := ts.TemplateRepo.Get(c, id)
+		if err != nil {
 			return nil, err
 		}
-		update := map[string]any{
-			"likes": "+",
+		if !template.IsPublic || template.OwnerId.String() == uid {
+			return nil, errs.ErrForbidden(op)
 		}
-		if err := ts.TemplateRepo.Update(c, update, id); err != nil {
+		if err := ts.TemplateRepo.LockRating(c, id); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		rating := &models.TemplateRating{
+			TemplateId: template.Id,
+			UserId:     uuid.MustParse(uid),
+			Stars:      stars,
+			CreateTime: now,
+			UpdateTime: now,
+		}
+		if review != "" {
+			rating.Review = &review
+		}
+		if err := ts.RatingRepo.Upsert(c, rating); err != nil {
+			return nil, err
+		}
+		if err := ts.TemplateRepo.RefreshRating(c, id); err != nil {
 			return nil, err
 		}
 		return nil, nil
 	}); err != nil {
-		log.Error("failed to like template", logger.Err(err))
+		log.Error("failed to rate template", logger.Err(err))
 		return errs.NewAppError(op, err)
 	}
-
-	log.Info("template liked successfully")
+	log.Info("template rated successfully")
 	return nil
 }
 
-func (ts *templateServ) Dislike(ctx context.Context, id, uid string) error {
-	op := "templateServ.Dislike"
+func (ts *templateServ) DeleteRating(ctx context.Context, id, uid string) error {
+	op := "templateServ.DeleteRating"
 	log := ts.Logger.AddOp(op)
-	log.Info("disliking template")
+	log.Info("deleting template rating")
 	if _, err := ts.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		if err := ts.TemplateRepo.Dislike(c, id, uid); err != nil {
+		if err := ts.TemplateRepo.LockRating(c, id); err != nil {
 			return nil, err
 		}
-		update := map[string]any{
-			"likes": "-",
+		if err := ts.RatingRepo.Delete(c, id, uid); err != nil {
+			return nil, err
 		}
-		if err := ts.TemplateRepo.Update(c, update, id); err != nil {
+		if err := ts.TemplateRepo.RefreshRating(c, id); err != nil {
 			return nil, err
 		}
 		return nil, nil
 	}); err != nil {
+		log.Error("failed to delete template rating", logger.Err(err))
 		return errs.NewAppError(op, err)
 	}
-	log.Info("template disliked successfully")
+	log.Info("template rating deleted successfully")
 	return nil
 }
+
+// FetchRatings lists ratings of a template visible to the user, recently
+// updated first.
+func (ts *templateServ) FetchRatings(ctx context.Context, id, uid string, amount, page uint) ([]dto.TemplateRatingResponse, error) {
+	op := "templateServ.FetchRatings"
+	log := ts.Logger.AddOp(op)
+	log.Info("fetching template ratings")
+	template, err := ts.TemplateRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to receive template", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	if !template.IsPublic && template.OrgId != nil {
+		if _, err := ts.OrganizationRepo.GetMemberRole(ctx, template.OrgId.String(), uid); err != nil {
+			log.Error("failed to check organization membership", logger.Err(err))
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+	ratings, err := ts.RatingRepo.FetchByTemplate(ctx, id, amount, page)
+	if err != nil {
+		log.Error("failed to fetch template ratings", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	resp := make([]dto.TemplateRatingResponse, 0, len(ratings))
+	for _, r := range ratings {
+		rating := dto.TemplateRatingResponse{
+			UserId:       r.UserId.String(),
+			UserNickname: r.UserNickname,
+			UserHandle:   r.UserHandle,
+			UserAvatar:   r.UserAvatar,
+			Stars:        r.Stars,
+			UpdateTime:   r.UpdateTime,
+		}
+		if r.Review != nil {
+			rating.Review = *r.Review
+		}
+		resp = append(resp, rating)
+	}
+	log.Info("template ratings fetched successfully")
+	return resp, nil
+}
@@ -1,19 +1,29 @@
 package services
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
+	"net/http"
+	"path"
 	"readmeow/internal/domain/models"
 	"readmeow/internal/domain/repositories"
+	"readmeow/internal/domain/services/utils"
 	"readmeow/internal/dto"
+	em "readmeow/internal/email"
 	"readmeow/pkg/cloudstorage"
 	"readmeow/pkg/errs"
 	"readmeow/pkg/logger"
 	"readmeow/pkg/storage"
+	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 type UserServ interface {
@@ -21,26 +31,63 @@ type UserServ interface {
 	Update(ctx context.Context, updates map[string]any, id string) error
 	Delete(ctx context.Context, id, password string) error
 	ChangePassword(ctx context.Context, id string, oldPassword, newPasswrod string) error
+	SetPassword(ctx context.Context, id, login, password string) error
+	Export(ctx context.Context, id string) ([]byte, error)
+	Erase(ctx context.Context, id string) error
+	ErasePending(ctx context.Context) error
+	Follow(ctx context.Context, uid, target string) error
+	Unfollow(ctx context.Context, uid, target string) error
+	FetchFollowers(ctx context.Context, id string, amount, page uint) ([]dto.FollowUserResponse, error)
+	FetchFollowing(ctx context.Context, id string, amount, page uint) ([]dto.FollowUserResponse, error)
+	FetchFeed(ctx context.Context, uid string, amount, page uint) ([]dto.FeedItemResponse, error)
+	ResolveHandle(ctx context.Context, handle string) (string, string, error)
+	ChangeHandle(ctx context.Context, uid, handle string) error
 }
 
 type userServ struct {
-	UserRepo     repositories.UserRepo
-	TemplateRepo repositories.TemplateRepo
-	CloudStorage cloudstorage.CloudStorage
-	Transactor   storage.Transactor
-	Logger       *logger.Logger
+	UserRepo          repositories.UserRepo
+	TemplateRepo      repositories.TemplateRepo
+	ReadmeRepo        repositories.ReadmeRepo
+	WidgetRepo        repositories.WidgetRepo
+	ErasureRepo       repositories.ErasureRepo
+	FollowRepo        repositories.FollowRepo
+	HandleHistoryRepo repositories.HandleHistoryRepo
+	CollectionRepo    repositories.CollectionRepo
+	AccessTokenRepo   repositories.AccessTokenRepo
+	AuthServ          AuthServ
+	NotificationServ  NotificationServ
+	CloudStorage      cloudstorage.CloudStorage
+	Transactor        storage.Transactor
+	EmailSender       em.EmailSender
+	Logger            *logger.Logger
 }
 
-func NewUserServ(ur repositories.UserRepo, tr repositories.TemplateRepo, cs cloudstorage.CloudStorage, t storage.Transactor, l *logger.Logger) UserServ {
+func NewUserServ(ur repositories.UserRepo, tr repositories.TemplateRepo, rr repositories.ReadmeRepo, wr repositories.WidgetRepo, er repositories.ErasureRepo, fr repositories.FollowRepo, hr repositories.HandleHistoryRepo, clr repositories.CollectionRepo, atr repositories.AccessTokenRepo, as AuthServ, ns NotificationServ, cs cloudstorage.CloudStorage, t storage.Transactor, es em.EmailSender, l *logger.Logger) UserServ {
 	return &userServ{
-		UserRepo:     ur,
-		TemplateRepo: tr,
-		CloudStorage: cs,
-		Transactor:   t,
-		Logger:       l,
+		UserRepo:          ur,
+		TemplateRepo:      tr,
+		ReadmeRepo:        rr,
+		WidgetRepo:        wr,
+		ErasureRepo:       er,
+		FollowRepo:        fr,
+		HandleHistoryRepo: hr,
+		CollectionRepo:    clr,
+		AccessTokenRepo:   atr,
+		AuthServ:          as,
+		NotificationServ:  ns,
+		CloudStorage:      cs,
+		Transactor:        t,
+		EmailSender:       es,
+		Logger:            l,
 	}
 }
 
+const (
+	exportPageSize    = 100
+	erasureBatchSize  = 20
+	exportImageFolder = "images"
+)
+
 func (us *userServ) Update(ctx context.Context, updates map[string]any, id string) error {
 	op := "userServ.Update"
 	log := us.Logger.AddOp(op)
@@ -107,10 +154,16 @@ func (us *userServ) Delete(ctx context.Context, id, password string) error {
 		if err != nil {
 			return nil, err
 		}
-		if err := bcrypt.CompareHashAndPassword(userPassword, []byte(password)); err != nil {
+		if _, err := utils.ComparePassword(userPassword, password); err != nil {
+			return nil, err
+		}
+		if err := us.ErasureRepo.Create(c, id); err != nil {
+			return nil, err
+		}
+		if err := us.AuthServ.RevokeAllSessions(c, id); err != nil {
 			return nil, err
 		}
-		if err := us.UserRepo.Delete(c, id); err != nil {
+		if err := us.AccessTokenRepo.DeleteAllByUser(c, id); err != nil {
 			return nil, err
 		}
 		return nil, nil
@@ -119,7 +172,262 @@ func (us *userServ) Delete(ctx context.Context, id, password string) error {
 		return errs.NewAppError(op, err)
 	}
 
-	log.Info("user deleted successfully")
+	log.Info("user erasure requested successfully")
+	return nil
+}
+
+func (us *userServ) Erase(ctx context.Context, id string) error {
+	op := "userServ.Erase"
+	log := us.Logger.AddOp(op)
+	log.Info("erasing user")
+	user, err := us.UserRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	res, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		return us.ErasureRepo.Erase(c, id, baseTemplateId.String())
+	})
+	if err != nil {
+		log.Error("failed to erase user", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	for _, url := range res.([]string) {
+		pid, err := us.CloudStorage.GetPIdFromURL(url)
+		if err != nil {
+			continue
+		}
+		if err := us.CloudStorage.DeleteImage(ctx, pid); err != nil {
+			log.Error("failed to delete image", logger.Err(err))
+		}
+	}
+	content, err := em.BuildAccountErasedLetter()
+	if err != nil {
+		log.Error("failed to build erasure letter", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if err := us.EmailSender.SendMessage(ctx, "Account Deleted", []byte(content), []string{user.Email}, nil); err != nil {
+		log.Error("failed to send erasure letter", logger.Err(err))
+	}
+	log.Info("user erased successfully")
+	return nil
+}
+
+func (us *userServ) ErasePending(ctx context.Context) error {
+	op := "userServ.ErasePending"
+	log := us.Logger.AddOp(op)
+	log.Info("erasing pending users")
+	uids, err := us.ErasureRepo.FetchPending(ctx, erasureBatchSize)
+	if err != nil {
+		log.Error("failed to fetch pending erasures", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	var errList []error
+	for _, uid := range uids {
+		if err := us.Erase(ctx, uid); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if err := errors.Join(errList...); err != nil {
+		return errs.NewAppError(op, err)
+	}
+	log.Info("pending users erased successfully")
+	return nil
+}
+
+func (us *userServ) Export(ctx context.Context, id string) ([]byte, error) {
+	op := "userServ.Export"
+	log := us.Logger.AddOp(op)
+	log.Info("exporting user data")
+	user, err := us.UserRepo.Get(ctx, id)
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	readmes, err := fetchAll(func(amount, page uint) ([]models.Readme, error) {
+		return us.ReadmeRepo.FetchByUser(ctx, amount, page, id)
+	})
+	if err != nil {
+		log.Error("failed to fetch readmes", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	templates, err := us.TemplateRepo.FetchByUser(ctx, id, true)
+	if err != nil {
+		log.Error("failed to fetch templates", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	favoriteTemplates, err := fetchAll(func(amount, page uint) ([]models.TemplateWithOwner, error) {
+		return us.TemplateRepo.FetchFavorite(ctx, id, amount, page)
+	})
+	if err != nil {
+		log.Error("failed to fetch favorite templates", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	favoriteWidgets, err := fetchAll(func(amount, page uint) ([]models.Widget, error) {
+		return us.WidgetRepo.FetchFavorite(ctx, id, amount, page)
+	})
+	if err != nil {
+		log.Error("failed to fetch favorite widgets", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	favorites := struct {
+		Templates []models.TemplateWithOwner `json:"templates"`
+		Widgets   []models.Widget            `json:"widgets"`
+	}{favoriteTemplates, favoriteWidgets}
+	collections, err := us.exportCollections(ctx, id)
+	if err != nil {
+		log.Error("failed to fetch collections", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"readmes.json", readmes},
+		{"templates.json", templates},
+		{"favorites.json", favorites},
+		{"collections.json", collections},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, errs.NewAppError(op, err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			log.Error("failed to write export file", logger.Err(err))
+			return nil, errs.NewAppError(op, err)
+		}
+	}
+	images := map[string]string{
+		path.Join(exportImageFolder, "avatar"): user.Avatar,
+	}
+	for _, r := range readmes {
+		images[path.Join(exportImageFolder, "readmes", r.Id.String())] = r.Image
+	}
+	for _, t := range templates {
+		images[path.Join(exportImageFolder, "templates", t.Id.String())] = t.Image
+	}
+	for name, url := range images {
+		if err := exportImage(ctx, zw, name, url); err != nil {
+			log.Error("failed to export image", logger.Err(err))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Error("failed to close export archive", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("user data exported successfully")
+	return buf.Bytes(), nil
+}
+
+type exportCollection struct {
+	models.Collection
+	Templates []models.CollectionTemplateItem `json:"templates"`
+	Widgets   []models.CollectionWidgetItem   `json:"widgets"`
+}
+
+func (us *userServ) exportCollections(ctx context.Context, id string) ([]exportCollection, error) {
+	collections, err := fetchAll(func(amount, page uint) ([]models.CollectionWithOwner, error) {
+		return us.CollectionRepo.FetchByUser(ctx, id, true, amount, page)
+	})
+	if err != nil {
+		return nil, err
+	}
+	export := make([]exportCollection, 0, len(collections))
+	for _, c := range collections {
+		templates, err := us.CollectionRepo.FetchTemplateItems(ctx, c.Id.String(), id)
+		if err != nil {
+			return nil, err
+		}
+		widgets, err := us.CollectionRepo.FetchWidgetItems(ctx, c.Id.String())
+		if err != nil {
+			return nil, err
+		}
+		export = append(export, exportCollection{c.Collection, templates, widgets})
+	}
+	return export, nil
+}
+
+func fetchAll[T any](fetch func(amount, page uint) ([]T, error)) ([]T, error) {
+	all := []T{}
+	for page := uint(1); ; page++ {
+		items, err := fetch(exportPageSize, page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < exportPageSize {
+			return all, nil
+		}
+	}
+}
+
+func exportImage(ctx context.Context, zw *zip.Writer, name, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d for %s", resp.StatusCode, url)
+	}
+	ext := path.Ext(req.URL.Path)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	w, err := zw.Create(name + ext)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// SetPassword lets a user who signed up through a provider log in with a
+// password too. Accounts without a login have to pick one.
+func (us *userServ) SetPassword(ctx context.Context, id, login, password string) error {
+	op := "userServ.SetPassword"
+	log := us.Logger.AddOp(op)
+	log.Info("setting user password")
+	if utils.IsBreachedPassword(password) {
+		log.Error("password is breached")
+		return errs.ErrBreachedPassword(op)
+	}
+	if _, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		user, err := us.UserRepo.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if user.Password != nil {
+			return nil, errs.ErrAlreadyExists(op, errors.New("password is already set"))
+		}
+		if user.Login == nil && login == "" {
+			return nil, errs.ErrInvalidValues(op)
+		}
+		hashedPassword, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		if err := us.UserRepo.SetPassword(c, id, login, hashedPassword); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to set user password", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+
+	log.Info("user password set successfully")
 	return nil
 }
 
@@ -127,17 +435,21 @@ func (us *userServ) ChangePassword(ctx context.Context, id string, oldPassword,
 	op := "userServ.UpdatePassword"
 	log := us.Logger.AddOp(op)
 	log.Info("changing user password")
+	if utils.IsBreachedPassword(newPasswrod) {
+		log.Error("new password is breached")
+		return errs.ErrBreachedPassword(op)
+	}
 	if _, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
 		userPassword, err := us.UserRepo.GetPassword(c, id)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := bcrypt.CompareHashAndPassword(userPassword, []byte(oldPassword)); err != nil {
+		if _, err := utils.ComparePassword(userPassword, oldPassword); err != nil {
 			return nil, errs.ErrIncorrectOldPassword(op)
 		}
 
-		newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPasswrod), 12)
+		newHashedPassword, err := utils.HashPassword(newPasswrod)
 		if err != nil {
 			return nil, err
 		}
@@ -167,8 +479,14 @@ func (us *userServ) Get(ctx context.Context, id string, showPrivate bool) (*dto.
 		templs []models.Template
 		err    error
 	}
+	type countsRes struct {
+		followers uint32
+		following uint32
+		err       error
+	}
 	userChan := make(chan userRes, 1)
 	templChan := make(chan templsRes, 1)
+	countsChan := make(chan countsRes, 1)
 	go func() {
 		user, err := us.UserRepo.Get(ctx, id)
 		userChan <- userRes{user: user, err: err}
@@ -177,8 +495,13 @@ func (us *userServ) Get(ctx context.Context, id string, showPrivate bool) (*dto.
 		templates, err := us.TemplateRepo.FetchByUser(ctx, id, showPrivate)
 		templChan <- templsRes{templs: templates, err: err}
 	}()
+	go func() {
+		followers, following, err := us.FollowRepo.Counts(ctx, id)
+		countsChan <- countsRes{followers: followers, following: following, err: err}
+	}()
 	userResponse := <-userChan
 	templateResponse := <-templChan
+	countsResponse := <-countsChan
 	if err := userResponse.err; err != nil {
 		log.Error("failed to get user", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
@@ -187,6 +510,10 @@ func (us *userServ) Get(ctx context.Context, id string, showPrivate bool) (*dto.
 		log.Error("failed to fetch user templates", logger.Err(err))
 		return nil, errs.NewAppError(op, err)
 	}
+	if err := countsResponse.err; err != nil {
+		log.Error("failed to count user follows", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
 	templates := templateResponse.templs
 	templateInfo := make([]dto.TemplateInfo, 0, len(templates))
 	for _, t := range templates {
@@ -198,20 +525,240 @@ func (us *userServ) Get(ctx context.Context, id string, showPrivate bool) (*dto.
 			LastUpdateTime: t.LastUpdateTime,
 			NumOfUsers:     t.NumOfUsers,
 			Likes:          t.Likes,
+			Rating:         t.Rating,
+			NumOfRatings:   t.NumOfRatings,
+			Reactions:      t.Reactions,
 		}
 		templateInfo = append(templateInfo, temlInf)
 	}
 	user := userResponse.user
 	userResp := &dto.UserResponse{
 		Id:             user.Id.String(),
+		Handle:         user.Handle,
 		Nickname:       user.Nickname,
-		Email:          user.Email,
 		Avatar:         user.Avatar,
 		NumOfReadmes:   user.NumOfReadmes,
 		NumOfTemplates: user.NumOfTemplates,
+		NumOfFollowers: countsResponse.followers,
+		NumOfFollowing: countsResponse.following,
 		TimeOfRegister: user.TimeOfRegister,
 		Templates:      templateInfo,
 	}
+	if showPrivate {
+		userResp.Email = user.Email
+	}
 	log.Info("user received successfully")
 	return userResp, nil
 }
+
+func (us *userServ) Follow(ctx context.Context, uid, target string) error {
+	op := "userServ.Follow"
+	log := us.Logger.AddOp(op)
+	log.Info("following user")
+	if uid == target {
+		log.Error("user can not follow themselves")
+		return errs.ErrInvalidValues(op)
+	}
+	if _, err := us.UserRepo.IdCheck(ctx, target); err != nil {
+		log.Error("failed to check user", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	follow := &models.Follow{
+		FollowerId: uuid.MustParse(uid),
+		FolloweeId: uuid.MustParse(target),
+		CreateTime: time.Now(),
+	}
+	created, err := us.FollowRepo.Create(ctx, follow)
+	if err != nil {
+		log.Error("failed to create follow", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	if created {
+		if err := us.NotificationServ.Notify(ctx, target, uid, models.NotificationNewFollower, uid); err != nil {
+			log.Error("failed to notify followed user", logger.Err(err))
+		}
+	}
+	log.Info("user followed successfully")
+	return nil
+}
+
+func (us *userServ) Unfollow(ctx context.Context, uid, target string) error {
+	op := "userServ.Unfollow"
+	log := us.Logger.AddOp(op)
+	log.Info("unfollowing user")
+	if err := us.FollowRepo.Delete(ctx, uid, target); err != nil {
+		log.Error("failed to delete follow", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("user unfollowed successfully")
+	return nil
+}
+
+func (us *userServ) FetchFollowers(ctx context.Context, id string, amount, page uint) ([]dto.FollowUserResponse, error) {
+	op := "userServ.FetchFollowers"
+	log := us.Logger.AddOp(op)
+	log.Info("fetching followers")
+	users, err := us.FollowRepo.FetchFollowers(ctx, id, amount, page)
+	if err != nil {
+		log.Error("failed to fetch followers", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("followers fetched successfully")
+	return toFollowUserResponses(users), nil
+}
+
+func (us *userServ) FetchFollowing(ctx context.Context, id string, amount, page uint) ([]dto.FollowUserResponse, error) {
+	op := "userServ.FetchFollowing"
+	log := us.Logger.AddOp(op)
+	log.Info("fetching followed users")
+	users, err := us.FollowRepo.FetchFollowing(ctx, id, amount, page)
+	if err != nil {
+		log.Error("failed to fetch followed users", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	log.Info("followed users fetched successfully")
+	return toFollowUserResponses(users), nil
+}
+
+func toFollowUserResponses(users []models.FollowUser) []dto.FollowUserResponse {
+	res := make([]dto.FollowUserResponse, 0, len(users))
+	for _, u := range users {
+		res = append(res, dto.FollowUserResponse{
+			Id:         u.Id.String(),
+			Nickname:   u.Nickname,
+			Avatar:     u.Avatar,
+			FollowTime: u.FollowTime,
+		})
+	}
+	return res
+}
+
+const (
+	feedTemplateCreated = "template_created"
+	feedTemplateUpdated = "template_updated"
+)
+
+func (us *userServ) FetchFeed(ctx context.Context, uid string, amount, page uint) ([]dto.FeedItemResponse, error) {
+	op := "userServ.FetchFeed"
+	log := us.Logger.AddOp(op)
+	log.Info("fetching feed")
+	templates, err := us.TemplateRepo.FetchFeed(ctx, uid, amount, page)
+	if err != nil {
+		log.Error("failed to fetch feed", logger.Err(err))
+		return nil, errs.NewAppError(op, err)
+	}
+	feed := make([]dto.FeedItemResponse, 0, len(templates))
+	for _, t := range templates {
+		itemType := feedTemplateUpdated
+		if t.LastUpdateTime.Equal(t.CreateTime) {
+			itemType = feedTemplateCreated
+		}
+		item := dto.FeedItemResponse{
+			Type: itemType,
+			Time: t.LastUpdateTime,
+			TemplateResponse: dto.TemplateResponse{
+				TemplateInfo: dto.TemplateInfo{
+					Id:             t.Id.String(),
+					Title:          t.Title,
+					Image:          t.Image,
+					Description:    t.Description,
+					LastUpdateTime: t.LastUpdateTime,
+					NumOfUsers:     t.NumOfUsers,
+					Likes:          t.Likes,
+					Rating:         t.Rating,
+					NumOfRatings:   t.NumOfRatings,
+					Reactions:      t.Reactions,
+					IsPublic:       t.IsPublic,
+				},
+				OwnerInfo: dto.OwnerInfo{
+					OwnerId:       t.OwnerId.String(),
+					OwnerAvatar:   t.OwnerAvatar,
+					OwnerNickname: t.OwnerNickname,
+				},
+			},
+		}
+		feed = append(feed, item)
+	}
+	log.Info("feed fetched successfully")
+	return feed, nil
+}
+
+// ResolveHandle finds the user by their current or any of their previous
+// handles and returns the user id with the current handle, so callers can
+// redirect old links.
+func (us *userServ) ResolveHandle(ctx context.Context, handle string) (string, string, error) {
+	op := "userServ.ResolveHandle"
+	log := us.Logger.AddOp(op)
+	log.Info("resolving handle")
+	user, err := us.UserRepo.GetByHandle(ctx, handle)
+	if err == nil {
+		log.Info("handle resolved successfully")
+		return user.Id.String(), user.Handle, nil
+	}
+	if !errors.Is(err, errs.ErrNotFoundBase) {
+		log.Error("failed to get user by handle", logger.Err(err))
+		return "", "", errs.NewAppError(op, err)
+	}
+	history, err := us.HandleHistoryRepo.GetByHandle(ctx, handle)
+	if err != nil {
+		log.Error("failed to get handle history", logger.Err(err))
+		return "", "", errs.NewAppError(op, err)
+	}
+	user, err = us.UserRepo.Get(ctx, history.UserId.String())
+	if err != nil {
+		log.Error("failed to get user", logger.Err(err))
+		return "", "", errs.NewAppError(op, err)
+	}
+	log.Info("old handle resolved successfully")
+	return user.Id.String(), user.Handle, nil
+}
+
+// ChangeHandle keeps the old handle in the history, so links with it still
+// redirect and nobody else can take it over.
+func (us *userServ) ChangeHandle(ctx context.Context, uid, handle string) error {
+	op := "userServ.ChangeHandle"
+	log := us.Logger.AddOp(op)
+	log.Info("changing handle")
+	if utils.IsReservedHandle(handle) {
+		log.Error("handle is reserved")
+		return errs.ErrReservedHandle(op)
+	}
+	if _, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		user, err := us.UserRepo.Get(c, uid)
+		if err != nil {
+			return nil, err
+		}
+		if user.Handle == handle {
+			return nil, nil
+		}
+		taken, err := us.UserRepo.HandleCheck(c, handle, uid)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, errs.ErrAlreadyExists(op, errors.New("handle is taken"))
+		}
+		if err := us.HandleHistoryRepo.Delete(c, handle, uid); err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(user.Handle, handle) {
+			history := &models.HandleHistory{
+				Handle:     user.Handle,
+				UserId:     user.Id,
+				ChangeTime: time.Now(),
+			}
+			if err := us.HandleHistoryRepo.Create(c, history); err != nil {
+				return nil, err
+			}
+		}
+		if err := us.UserRepo.ChangeHandle(c, uid, handle); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}); err != nil {
+		log.Error("failed to change handle", logger.Err(err))
+		return errs.NewAppError(op, err)
+	}
+	log.Info("handle changed successfully")
+	return nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// breachedPasswords holds upper-case SHA-1 hashes of known leaked passwords,
+// one per line, so the list itself never contains a plain password.
+//
+//go:embed assets/breached-passwords.txt
+var breachedPasswords string
+
+const breachPrefixLen = 5
+
+var (
+	breachRanges     map[string]map[string]struct{}
+	breachRangesOnce sync.Once
+)
+
+// loadBreachRanges groups the hashes by their 5 character prefix the same way
+// the Have I Been Pwned range API does.
+func loadBreachRanges() {
+	breachRanges = map[string]map[string]struct{}{}
+	for _, line := range strings.Split(breachedPasswords, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) <= breachPrefixLen {
+			continue
+		}
+		prefix, suffix := line[:breachPrefixLen], line[breachPrefixLen:]
+		if breachRanges[prefix] == nil {
+			breachRanges[prefix] = map[string]struct{}{}
+		}
+		breachRanges[prefix][suffix] = struct{}{}
+	}
+}
+
+// IsBreachedPassword looks the password up k-anonymity style: only the range
+// of its hash prefix is searched, and the lookup never leaves the process.
+func IsBreachedPassword(password string) bool {
+	breachRangesOnce.Do(loadBreachRanges)
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, ok := breachRanges[hash[:breachPrefixLen]][hash[breachPrefixLen:]]
+	return ok
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "strings"
+
+func DeviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	l := strings.ToLower(ua)
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(l, "curl"):
+		browser = "curl"
+	case strings.Contains(l, "postman"):
+		browser = "Postman"
+	case strings.Contains(l, "edg/"):
+		browser = "Edge"
+	case strings.Contains(l, "opr/") || strings.Contains(l, "opera"):
+		browser = "Opera"
+	case strings.Contains(l, "firefox"):
+		browser = "Firefox"
+	case strings.Contains(l, "chrome"):
+		browser = "Chrome"
+	case strings.Contains(l, "safari"):
+		browser = "Safari"
+	}
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(l, "android"):
+		os = "Android"
+	case strings.Contains(l, "iphone") || strings.Contains(l, "ipad"):
+		os = "iOS"
+	case strings.Contains(l, "windows"):
+		os = "Windows"
+	case strings.Contains(l, "mac os"):
+		os = "macOS"
+	case strings.Contains(l, "linux"):
+		os = "Linux"
+	}
+	return browser + " on " + os
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"strings"
+)
+
+const (
+	MinHandleLen = 3
+	MaxHandleLen = 30
+)
+
+// reservedHandles can not be taken by users: they collide with routes or
+// could pass for the staff of the service.
+var reservedHandles = map[string]struct{}{
+	"about":         {},
+	"admin":         {},
+	"administrator": {},
+	"api":           {},
+	"assets":        {},
+	"auth":          {},
+	"feed":          {},
+	"help":          {},
+	"login":         {},
+	"logout":        {},
+	"me":            {},
+	"moderator":     {},
+	"notifications": {},
+	"null":          {},
+	"official":      {},
+	"readmeow":      {},
+	"readmes":       {},
+	"register":      {},
+	"root":          {},
+	"security":      {},
+	"settings":      {},
+	"signin":        {},
+	"signup":        {},
+	"staff":         {},
+	"static":        {},
+	"support":       {},
+	"system":        {},
+	"templates":     {},
+	"undefined":     {},
+	"users":         {},
+	"widgets":       {},
+}
+
+func IsReservedHandle(handle string) bool {
+	_, ok := reservedHandles[strings.ToLower(handle)]
+	return ok
+}
+
+// HandleFromNickname makes a valid handle out of the nickname by keeping its
+// latin letters and digits and joining the rest with dashes.
+func HandleFromNickname(nickname string) string {
+	b := strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(nickname) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	handle := b.String()
+	// room for the suffix added when the handle is taken
+	if len(handle) > MaxHandleLen-5 {
+		handle = strings.TrimRight(handle[:MaxHandleLen-5], "-")
+	}
+	if len(handle) < MinHandleLen {
+		handle = "user"
+	}
+	return handle
+}
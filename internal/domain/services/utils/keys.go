@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"readmeow/internal/config"
+	"readmeow/internal/dto"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type SigningKey struct {
+	Kid         string
+	Method      jwt.SigningMethod
+	PrivateKey  any
+	PublicKey   any
+	VerifyUntil time.Time
+}
+
+func (k *SigningKey) expired(now time.Time) bool {
+	return !k.VerifyUntil.IsZero() && now.After(k.VerifyUntil)
+}
+
+// KeySet signs tokens with its active key and verifies them with any key that
+// is still in its grace period.
+type KeySet struct {
+	Active *SigningKey
+	Keys   map[string]*SigningKey
+}
+
+// MustNewKeySet loads the configured signing keys. Without them the legacy
+// HS256 secret keeps signing; with them it only verifies tokens issued before
+// the switch, which live no longer than one token TTL.
+func MustNewKeySet(cfg config.AuthConfig) *KeySet {
+	ks := &KeySet{Keys: map[string]*SigningKey{}}
+	for i, kc := range cfg.SigningKeys {
+		key, err := loadSigningKey(kc)
+		if err != nil {
+			panic(fmt.Errorf("failed to load signing key %q: %w", kc.Kid, err))
+		}
+		if _, ok := ks.Keys[key.Kid]; ok {
+			panic(fmt.Errorf("duplicate signing key %q", key.Kid))
+		}
+		if i == 0 {
+			if !key.VerifyUntil.IsZero() {
+				panic(fmt.Errorf("active signing key %q must not have verifyUntil", key.Kid))
+			}
+			ks.Active = key
+		}
+		ks.Keys[key.Kid] = key
+	}
+	if cfg.Secret == "" {
+		if ks.Active == nil {
+			panic("no jwt signing keys configured")
+		}
+		return ks
+	}
+	legacy := &SigningKey{
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: []byte(cfg.Secret),
+		PublicKey:  []byte(cfg.Secret),
+	}
+	if ks.Active == nil {
+		ks.Active = legacy
+	} else {
+		legacy.VerifyUntil = time.Now().Add(cfg.TokenTTL)
+	}
+	ks.Keys[legacy.Kid] = legacy
+	return ks
+}
+
+func loadSigningKey(kc config.SigningKeyConfig) (*SigningKey, error) {
+	if kc.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &SigningKey{Kid: kc.Kid}
+	if kc.VerifyUntil != "" {
+		t, err := time.Parse(time.RFC3339, kc.VerifyUntil)
+		if err != nil {
+			return nil, err
+		}
+		key.VerifyUntil = t
+	}
+	data, err := os.ReadFile(kc.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	switch kc.Algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("RS256 requires an RSA key")
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.PrivateKey = private
+		key.PublicKey = &private.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 key")
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.PrivateKey = private
+		key.PublicKey = private.Public()
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	return key, nil
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.Active.Method, claims)
+	if ks.Active.Kid != "" {
+		token.Header["kid"] = ks.Active.Kid
+	}
+	return token.SignedString(ks.Active.PrivateKey)
+}
+
+// Keyfunc picks the verification key by the kid header and rejects tokens
+// whose algorithm differs from the one the key was configured with.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := ks.Keys[kid]
+	if !ok || key.expired(time.Now()) {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+	}
+	return key.PublicKey, nil
+}
+
+// JWKS lists the public halves of the asymmetric keys that still verify.
+// The legacy HS256 secret is never published.
+func (ks *KeySet) JWKS() dto.JWKSResponse {
+	now := time.Now()
+	b64 := base64.RawURLEncoding
+	res := dto.JWKSResponse{Keys: []dto.JWKResponse{}}
+	for _, key := range ks.Keys {
+		if key.expired(now) {
+			continue
+		}
+		jwk := dto.JWKResponse{
+			Kid: key.Kid,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64.EncodeToString(pub.N.Bytes())
+			jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64.EncodeToString(pub)
+		default:
+			continue
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	slices.SortFunc(res.Keys, func(a, b dto.JWKResponse) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+	return res
+}
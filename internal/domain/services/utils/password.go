@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordMismatch         = errors.New("password mismatch")
+	ErrUnknownPasswordAlgorithm = errors.New("unknown password algorithm")
+)
+
+// PasswordHasher is one password hashing algorithm. Hashes are self-describing:
+// every algorithm recognises its own hashes by their prefix.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Compare(hash []byte, password string) error
+	Owns(hash []byte) bool
+	NeedsRehash(hash []byte) bool
+}
+
+type bcryptHasher struct {
+	Cost int
+}
+
+func (bh bcryptHasher) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bh.Cost)
+}
+
+func (bh bcryptHasher) Compare(hash []byte, password string) error {
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	}
+	return nil
+}
+
+func (bh bcryptHasher) Owns(hash []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(hash, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (bh bcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != bh.Cost
+}
+
+const argon2idPrefix = "$argon2id$"
+
+type argon2idHasher struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen int
+	KeyLen  uint32
+}
+
+// Hash encodes the result in the PHC string format:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func (ah argon2idHasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, ah.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, ah.Time, ah.Memory, ah.Threads, ah.KeyLen)
+	b64 := base64.RawStdEncoding
+	hash := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, ah.Memory, ah.Time, ah.Threads, b64.EncodeToString(salt), b64.EncodeToString(key))
+	return []byte(hash), nil
+}
+
+func (ah argon2idHasher) Compare(hash []byte, password string) error {
+	params, salt, key, err := ah.decode(hash)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (ah argon2idHasher) Owns(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+func (ah argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, salt, key, err := ah.decode(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != ah.Memory || params.Time != ah.Time || params.Threads != ah.Threads ||
+		len(salt) != ah.SaltLen || uint32(len(key)) != ah.KeyLen
+}
+
+func (ah argon2idHasher) decode(hash []byte) (argon2idHasher, []byte, []byte, error) {
+	params := argon2idHasher{}
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownPasswordAlgorithm
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordAlgorithm
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+// defaultHasher is used for every new hash. Hashes made by any other hasher
+// are still accepted and get replaced on the next successful login.
+var (
+	defaultHasher PasswordHasher = argon2idHasher{
+		Memory:  19 * 1024,
+		Time:    2,
+		Threads: 1,
+		SaltLen: 16,
+		KeyLen:  32,
+	}
+	passwordHashers = []PasswordHasher{
+		defaultHasher,
+		bcryptHasher{Cost: 12},
+	}
+)
+
+func HashPassword(password string) ([]byte, error) {
+	return defaultHasher.Hash(password)
+}
+
+// ComparePassword checks the password against a hash of any supported
+// algorithm and reports whether the hash should be replaced by a new one.
+func ComparePassword(hash []byte, password string) (bool, error) {
+	for _, hasher := range passwordHashers {
+		if !hasher.Owns(hash) {
+			continue
+		}
+		if err := hasher.Compare(hash, password); err != nil {
+			return false, err
+		}
+		return hasher != defaultHasher || hasher.NeedsRehash(hash), nil
+	}
+	return false, ErrUnknownPasswordAlgorithm
+}
@@ -13,8 +13,6 @@ import (
 type WidgetServ interface {
 	Get(ctx context.Context, id string) (*models.Widget, error)
 	Search(ctx context.Context, amount, page uint, query string, filter map[string][]string, sort map[string]string) ([]dto.WidgetResponse, error)
-	Like(ctx context.Context, id, uid string) error
-	Dislike(ctx context.Context, id, uid string) error
 	FetchFavorite(ctx context.Context, id string, amount, page uint) ([]dto.WidgetResponse, error)
 }
 
@@ -65,6 +63,7 @@ func (ws *widgetServ) Search(ctx context.Context, amount, page uint, query strin
 			Image:       w.Image,
 			Likes:       w.Likes,
 			NumOfUsers:  w.NumOfUsers,
+			Reactions:   w.Reactions,
 		}
 		widgets = append(widgets, widget)
 	}
@@ -72,53 +71,6 @@ func (ws *widgetServ) Search(ctx context.Context, amount, page uint, query strin
 	return widgets, nil
 }
 
-func (ws *widgetServ) Like(ctx context.Context, id, uid string) error {
-	op := "widgetServ.Like"
-	log := ws.Logger.AddOp(op)
-	log.Info("liking widget")
-	if _, err := ws.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		if err := ws.WidgetRepo.Like(c, uid, id); err != nil {
-			return nil, err
-		}
-		update := map[string]string{
-			"likes": "+",
-		}
-		if err := ws.WidgetRepo.Update(c, update, id); err != nil {
-			return nil, err
-		}
-		return nil, nil
-	}); err != nil {
-		log.Error("failed to like widget", logger.Err(err))
-		return errs.NewAppError(op, err)
-	}
-
-	log.Info("widget liked successfully")
-	return nil
-}
-
-func (ws *widgetServ) Dislike(ctx context.Context, id, uid string) error {
-	op := "widgetServ.Dislike"
-	log := ws.Logger.AddOp(op)
-	log.Info("disliking widget")
-	if _, err := ws.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
-		if err := ws.WidgetRepo.Dislike(c, uid, id); err != nil {
-			return nil, err
-		}
-		update := map[string]string{
-			"likes": "-",
-		}
-		if err := ws.WidgetRepo.Update(c, update, id); err != nil {
-			return nil, err
-		}
-		return nil, nil
-	}); err != nil {
-		log.Error("failed to dislike widget", logger.Err(err))
-		return errs.NewAppError(op, err)
-	}
-	log.Info("widget disliked successfully")
-	return nil
-}
-
 func (ws *widgetServ) FetchFavorite(ctx context.Context, id string, amount, page uint) ([]dto.WidgetResponse, error) {
 	op := "widgetServ.FetchFavorite"
 	log := ws.Logger.AddOp(op)
@@ -137,6 +89,7 @@ func (ws *widgetServ) FetchFavorite(ctx context.Context, id string, amount, page
 			Image:       w.Image,
 			Likes:       w.Likes,
 			NumOfUsers:  w.NumOfUsers,
+			Reactions:   w.Reactions,
 		}
 		widgets = append(widgets, widget)
 	}
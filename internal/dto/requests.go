@@ -55,6 +55,8 @@ type sortTemplatesFields struct {
 	Likes          string     `json:"Likes,omitempty" example:"desc/asc"`
 	NumOfUsers     string     `json:"NumOfUsers,omitempty" example:"desc/asc"`
 	LastUpdateTime *time.Time `json:"LastUpdateTime,omitempty" example:"desc/asc"`
+	Rating         string     `json:"Rating,omitempty" example:"desc/asc"`
+	NumOfRatings   string     `json:"NumOfRatings,omitempty" example:"desc/asc"`
 }
 
 type filterTemplatesFields struct {
@@ -63,9 +65,11 @@ type filterTemplatesFields struct {
 
 type SearchTemplateRequest struct {
 	PaginationRequest
-	Query  string            `json:"query" validate:"omitempty"`
-	Sort   map[string]string `json:"sort" validate:"omitempty,dive,keys,oneof=Likes NumOfUsers LastUpdateTime,endkeys"`
-	Filter map[string]bool   `json:"filter" validate:"omitempty,dive,keys,oneof=isOfficial,endkeys"`
+	Query     string            `json:"query" validate:"omitempty"`
+	Sort      map[string]string `json:"sort" validate:"omitempty,dive,keys,oneof=Likes NumOfUsers LastUpdateTime Rating NumOfRatings,endkeys"`
+	Filter    map[string]bool   `json:"filter" validate:"omitempty,dive,keys,oneof=isOfficial,endkeys"`
+	MinRating float64           `json:"min_rating" validate:"omitempty,min=1,max=5"`
+	OrgId     string            `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type SearchTemplateRequestDoc struct {
@@ -73,6 +77,8 @@ type SearchTemplateRequestDoc struct {
 	Query                 string `json:"query" validate:"omitempty"`
 	sortTemplatesFields   `json:"sort" validate:"omitempty"`
 	filterTemplatesFields `json:"filter" validate:"omitempty"`
+	MinRating             float64 `json:"min_rating" validate:"omitempty,min=1,max=5"`
+	OrgId                 string  `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type UpdateUserRequest struct {
@@ -90,11 +96,34 @@ type DeleteUserRequest struct {
 	Password string `json:"password" validate:"required,min=8"`
 }
 
+type ChangeHandleRequest struct {
+	Handle string `json:"handle" validate:"required,min=3,max=30,handle"`
+}
+
 type ChangePasswordRequest struct {
 	OldPasswrod string `json:"old_password" validate:"required,min=8"`
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
+// SetPasswordRequest gives a password to an account created through a
+// provider. Login is required when the account has none.
+type SetPasswordRequest struct {
+	Login    string `json:"login" validate:"omitempty,min=2,max=80"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Code string `json:"code" validate:"required,min=6"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
 type CreateTemplateRequest struct {
 	Title       string                `json:"title" validate:"required,min=1,max=255"`
 	Image       *multipart.FileHeader `json:"image" validate:"required"`
@@ -104,6 +133,7 @@ type CreateTemplateRequest struct {
 	Links       []string              `json:"links" validate:"omitempty,dive,url"`
 	Widgets     []map[string]string   `json:"widgets" validate:"omitempty,dive,dive,keys,uuid,endkeys,required,min=1"`
 	IsPublic    bool                  `json:"is_public" validate:"required"`
+	OrgId       string                `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type CreateTemplateRequestDoc struct {
@@ -115,6 +145,7 @@ type CreateTemplateRequestDoc struct {
 	Links       []string            `json:"links" validate:"omitempty,dive,url"`
 	Widgets     []map[string]string `json:"widgets" validate:"omitempty,dive,dive,keys,uuid,endkeys,required,min=1"`
 	IsPublic    bool                `json:"is_public" validate:"required"`
+	OrgId       string              `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type UpdateTemplateRequest struct {
@@ -142,6 +173,7 @@ type CreateReadmeRequest struct {
 	Text        []string              `json:"text" validate:"omitempty"`
 	Links       []string              `json:"links" validate:"omitempty,dive,url"`
 	Widgets     []map[string]string   `json:"widgets" validate:"omitempty,dive,dive,keys,uuid,endkeys,required,min=1"`
+	OrgId       string                `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type CreateReadmeRequestDoc struct {
@@ -152,6 +184,7 @@ type CreateReadmeRequestDoc struct {
 	Text        []string            `json:"text" validate:"omitempty"`
 	Links       []string            `json:"links" validate:"omitempty,dive,url"`
 	Widgets     []map[string]string `json:"widgets" validate:"omitempty,dive,dive,keys,uuid,endkeys,required,min=1"`
+	OrgId       string              `json:"org_id" validate:"omitempty,uuid"`
 }
 
 type UpdateReadmeRequest struct {
@@ -169,6 +202,143 @@ type UpdateReadmeRequestDoc struct {
 	Widgets     []map[string]string `json:"widgets" validate:"omitempty,dive,dive,keys,uuid,endkeys,required,min=1"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,min=1,max=80"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=readmes:read readmes:write templates:read templates:write widgets:read widgets:write users:read users:write notifications:read notifications:write orgs:read orgs:write collections:read collections:write"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1,max=365"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences map[string]bool `json:"preferences" validate:"required,min=1,dive,keys,oneof=template_liked template_used new_follower readme_shared readme_comment_mention template_comment_mention collection_followed,endkeys"`
+}
+
+type AddCollaboratorRequest struct {
+	Email  string `json:"email" validate:"required_without=Handle,omitempty,email"`
+	Handle string `json:"handle" validate:"required_without=Email,omitempty,min=3,max=30,handle"`
+	Access string `json:"access" validate:"required,oneof=viewer editor"`
+}
+
+type UpdateCollaboratorRequest struct {
+	Access string `json:"access" validate:"required,oneof=viewer editor"`
+}
+
+// CreateCommentRequest starts a thread anchored to a block, or a general
+// discussion one on public templates when the anchor is left out. Replies
+// only set ParentId.
+type CreateCommentRequest struct {
+	Body        string `json:"body" validate:"required,min=1,max=5000"`
+	ParentId    string `json:"parent_id" validate:"omitempty,uuid"`
+	AnchorField string `json:"anchor_field" validate:"required_with=AnchorIndex,omitempty,oneof=title text links widgets render_order"`
+	AnchorIndex *int   `json:"anchor_index" validate:"required_with=AnchorField,omitempty,min=0"`
+}
+
+type FetchCommentsRequest struct {
+	PaginationRequest
+	Discussion bool `json:"discussion"`
+}
+
+type sortCollectionsFields struct {
+	NumOfFollowers string     `json:"NumOfFollowers,omitempty" example:"desc/asc"`
+	NumOfItems     string     `json:"NumOfItems,omitempty" example:"desc/asc"`
+	LastUpdateTime *time.Time `json:"LastUpdateTime,omitempty" example:"desc/asc"`
+}
+
+type SearchCollectionRequest struct {
+	PaginationRequest
+	Query string            `json:"query" validate:"omitempty"`
+	Sort  map[string]string `json:"sort" validate:"omitempty,dive,keys,oneof=NumOfFollowers NumOfItems LastUpdateTime,endkeys"`
+}
+
+type SearchCollectionRequestDoc struct {
+	PaginationRequest
+	Query                 string `json:"query" validate:"omitempty"`
+	sortCollectionsFields `json:"sort" validate:"omitempty"`
+}
+
+type CreateCollectionRequest struct {
+	Title       string `json:"title" validate:"required,min=1,max=80"`
+	Description string `json:"description" validate:"omitempty,max=1000"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type UpdateCollectionRequest struct {
+	Title       string `json:"title" validate:"omitempty,min=1,max=80"`
+	Description string `json:"description" validate:"omitempty,max=1000"`
+	IsPublic    *bool  `json:"is_public"`
+}
+
+type AddCollectionItemRequest struct {
+	Type   string `json:"type" validate:"required,oneof=template widget"`
+	ItemId string `json:"item_id" validate:"required,uuid"`
+}
+
+// ReorderCollectionItemsRequest lists every item of the collection in the new
+// order.
+type ReorderCollectionItemsRequest struct {
+	ItemIds []string `json:"item_ids" validate:"required,min=1,dive,uuid"`
+}
+
+// RateTemplateRequest rates a template or replaces the previous rating of the
+// user.
+type RateTemplateRequest struct {
+	Stars  int    `json:"stars" validate:"required,min=1,max=5"`
+	Review string `json:"review" validate:"omitempty,max=5000"`
+}
+
+// ReactRequest leaves one of the fixed reactions on a template or widget.
+type ReactRequest struct {
+	Reaction string `json:"reaction" validate:"required,oneof=like love laugh hooray rocket eyes"`
+}
+
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=80"`
+	Description string `json:"description" validate:"omitempty,max=1000"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name        string `json:"name" validate:"omitempty,min=1,max=80"`
+	Description string `json:"description" validate:"omitempty,max=1000"`
+}
+
+type AddOrganizationMemberRequest struct {
+	UserId string `json:"user_id" validate:"required,uuid"`
+	Role   string `json:"role" validate:"required,oneof=owner admin editor viewer"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin editor viewer"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6"`
+}
+
+type TwoFactorLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+	Code  string `json:"code" validate:"required,min=6"`
+}
+
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=8"`
+}
+
 type SendNewCodeRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
@@ -184,6 +354,15 @@ type GoogleOAuthRequest struct {
 	Locale        string `json:"locale"`
 }
 
+type OAuthProfile struct {
+	Id            string
+	Provider      string
+	Nickname      string
+	Avatar        string
+	Email         string
+	VerifiedEmail bool
+}
+
 type GitHubOAuthRequest struct {
 	Id     int64  `json:"id"`
 	Login  string `json:"login"`
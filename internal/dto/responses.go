@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"readmeow/internal/domain/models"
 	"time"
 )
 
@@ -10,13 +11,52 @@ type LoginResponse struct {
 	Avatar   string `json:"avatar" validate:"required"`
 }
 
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Token             string `json:"token" validate:"required"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret" validate:"required"`
+	URI    string `json:"uri" validate:"required"`
+}
+
+type CSRFTokenResponse struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes" validate:"required"`
+}
+
+type AccessTokenResponse struct {
+	Id           string     `json:"id" validate:"required,uuid"`
+	Name         string     `json:"name" validate:"required"`
+	Scopes       []string   `json:"scopes" validate:"required"`
+	CreateTime   time.Time  `json:"create_time" validate:"required"`
+	LastUsedTime *time.Time `json:"last_used_time"`
+	ExpiredTime  time.Time  `json:"expired_time" validate:"required"`
+}
+
+type CreateAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token" validate:"required"`
+}
+
+type IdentityResponse struct {
+	Provider   string    `json:"provider" validate:"required"`
+	Email      string    `json:"email"`
+	CreateTime time.Time `json:"create_time" validate:"required"`
+}
+
 type WidgetResponse struct {
-	Id          string `json:"id" validate:"required,uuid"`
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Image       string `json:"image" validate:"required"`
-	Likes       uint32 `json:"likes" validate:"required,min=0"`
-	NumOfUsers  uint32 `json:"num_of_users" validate:"required,min=0"`
+	Id          string            `json:"id" validate:"required,uuid"`
+	Title       string            `json:"title" validate:"required"`
+	Description string            `json:"description" validate:"required"`
+	Image       string            `json:"image" validate:"required"`
+	Likes       uint32            `json:"likes" validate:"required,min=0"`
+	NumOfUsers  uint32            `json:"num_of_users" validate:"required,min=0"`
+	Reactions   map[string]uint32 `json:"reactions" validate:"required"`
 }
 
 type OwnerInfo struct {
@@ -26,14 +66,17 @@ type OwnerInfo struct {
 }
 
 type TemplateInfo struct {
-	Id             string    `json:"id" validate:"required,uuid"`
-	Title          string    `json:"title" validate:"required,min=1"`
-	Description    string    `json:"description" validate:"required,min=1"`
-	Image          string    `json:"image" validate:"required"`
-	LastUpdateTime time.Time `json:"last_update_time" validate:"required"`
-	NumOfUsers     uint32    `json:"num_of_users" validate:"required,min=0"`
-	Likes          uint32    `json:"likes" validate:"required,min=0"`
-	IsPublic       bool      `json:"is_public" validate:"required"`
+	Id             string            `json:"id" validate:"required,uuid"`
+	Title          string            `json:"title" validate:"required,min=1"`
+	Description    string            `json:"description" validate:"required,min=1"`
+	Image          string            `json:"image" validate:"required"`
+	LastUpdateTime time.Time         `json:"last_update_time" validate:"required"`
+	NumOfUsers     uint32            `json:"num_of_users" validate:"required,min=0"`
+	Likes          uint32            `json:"likes" validate:"required,min=0"`
+	Rating         float64           `json:"rating" validate:"min=0,max=5"`
+	NumOfRatings   uint32            `json:"num_of_ratings" validate:"min=0"`
+	Reactions      map[string]uint32 `json:"reactions" validate:"required"`
+	IsPublic       bool              `json:"is_public" validate:"required"`
 }
 
 type TemplateResponse struct {
@@ -49,18 +92,201 @@ type ReadmeResponse struct {
 	CreateTime     time.Time `json:"create_time" validate:"required"`
 }
 
+type SharedReadmeResponse struct {
+	ReadmeResponse
+	OwnerId string `json:"owner_id" validate:"required,uuid"`
+	Access  string `json:"access" validate:"required,oneof=viewer editor"`
+}
+
+type CollaboratorResponse struct {
+	UserId     string    `json:"user_id" validate:"required,uuid"`
+	Nickname   string    `json:"nickname" validate:"required"`
+	Handle     string    `json:"handle" validate:"required"`
+	Avatar     string    `json:"avatar" validate:"required"`
+	Access     string    `json:"access" validate:"required,oneof=viewer editor"`
+	CreateTime time.Time `json:"create_time" validate:"required"`
+}
+
+type CommentResponse struct {
+	Id             string     `json:"id" validate:"required,uuid"`
+	ParentId       string     `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+	AuthorId       string     `json:"author_id" validate:"required,uuid"`
+	AuthorNickname string     `json:"author_nickname" validate:"required"`
+	AuthorHandle   string     `json:"author_handle" validate:"required"`
+	AuthorAvatar   string     `json:"author_avatar" validate:"required"`
+	AnchorField    string     `json:"anchor_field,omitempty" validate:"omitempty,oneof=title text links widgets render_order"`
+	AnchorIndex    *int       `json:"anchor_index,omitempty" validate:"omitempty,min=0"`
+	Body           string     `json:"body" validate:"required"`
+	IsResolved     bool       `json:"is_resolved"`
+	ResolvedBy     string     `json:"resolved_by,omitempty" validate:"omitempty,uuid"`
+	ResolveTime    *time.Time `json:"resolve_time,omitempty"`
+	CreateTime     time.Time  `json:"create_time" validate:"required"`
+}
+
+type CommentThreadResponse struct {
+	CommentResponse
+	Replies []CommentResponse `json:"replies" validate:"required"`
+}
+
+type TemplateRatingResponse struct {
+	UserId       string    `json:"user_id" validate:"required,uuid"`
+	UserNickname string    `json:"user_nickname" validate:"required"`
+	UserHandle   string    `json:"user_handle" validate:"required"`
+	UserAvatar   string    `json:"user_avatar" validate:"required"`
+	Stars        int       `json:"stars" validate:"required,min=1,max=5"`
+	Review       string    `json:"review,omitempty"`
+	UpdateTime   time.Time `json:"update_time" validate:"required"`
+}
+
+// ReactionsResponse holds reaction counts of a template or widget together
+// with the reactions the current user left on it.
+type ReactionsResponse struct {
+	Reactions     map[string]uint32 `json:"reactions" validate:"required"`
+	UserReactions []string          `json:"user_reactions" validate:"required"`
+}
+
+// TemplatePageResponse is a template with the first page of its general
+// discussion, which only public templates have.
+type TemplatePageResponse struct {
+	models.TemplateWithOwner
+	Discussion []CommentThreadResponse `json:"discussion" validate:"required"`
+}
+
 type UserResponse struct {
 	Id             string         `json:"id" validate:"required,uuid"`
+	Handle         string         `json:"handle" validate:"required"`
 	Nickname       string         `json:"nickname" validate:"required,min=1"`
-	Email          string         `json:"email" validate:"required,email"`
+	Email          string         `json:"email,omitempty" validate:"omitempty,email"`
 	Avatar         string         `json:"avatar" validate:"required"`
 	NumOfReadmes   uint32         `json:"num_of_readmes" validate:"required,min=0"`
 	NumOfTemplates uint32         `json:"num_of_templates" validate:"required,min=0"`
+	NumOfFollowers uint32         `json:"num_of_followers" validate:"required,min=0"`
+	NumOfFollowing uint32         `json:"num_of_following" validate:"required,min=0"`
 	Templates      []TemplateInfo `json:"templates" validate:"required,min=0"`
 	TimeOfRegister time.Time      `json:"time_of_register" validate:"required"`
 }
 
+type FollowUserResponse struct {
+	Id         string    `json:"id" validate:"required,uuid"`
+	Nickname   string    `json:"nickname" validate:"required"`
+	Avatar     string    `json:"avatar" validate:"required"`
+	FollowTime time.Time `json:"follow_time" validate:"required"`
+}
+
+type FeedItemResponse struct {
+	Type string    `json:"type" validate:"required,oneof=template_created template_updated"`
+	Time time.Time `json:"time" validate:"required"`
+	TemplateResponse
+}
+
 type SuccessResponse struct {
 	Code    int    `json:"code" example:"200"`
 	Message string `json:"message" example:"success"`
 }
+
+type SessionResponse struct {
+	Id           string    `json:"id" validate:"required,uuid"`
+	Device       string    `json:"device" validate:"required"`
+	UserAgent    string    `json:"user_agent" validate:"required"`
+	Ip           string    `json:"ip" validate:"required"`
+	CreateTime   time.Time `json:"create_time" validate:"required"`
+	LastUsedTime time.Time `json:"last_used_time" validate:"required"`
+	ExpiredTime  time.Time `json:"expired_time" validate:"required"`
+	Current      bool      `json:"current"`
+}
+
+type JWKResponse struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSResponse struct {
+	Keys []JWKResponse `json:"keys"`
+}
+
+type LoginEventResponse struct {
+	Id         string    `json:"id" validate:"required,uuid"`
+	Method     string    `json:"method" validate:"required"`
+	Success    bool      `json:"success"`
+	Device     string    `json:"device" validate:"required"`
+	UserAgent  string    `json:"user_agent" validate:"required"`
+	Ip         string    `json:"ip" validate:"required"`
+	CreateTime time.Time `json:"create_time" validate:"required"`
+}
+
+type NotificationResponse struct {
+	Id            string    `json:"id" validate:"required,uuid"`
+	Type          string    `json:"type" validate:"required,oneof=template_liked template_used new_follower readme_shared readme_comment_mention template_comment_mention collection_followed"`
+	EntityId      string    `json:"entity_id,omitempty" validate:"omitempty,uuid"`
+	ActorId       string    `json:"actor_id" validate:"required,uuid"`
+	ActorNickname string    `json:"actor_nickname" validate:"required"`
+	ActorAvatar   string    `json:"actor_avatar" validate:"required"`
+	IsRead        bool      `json:"is_read"`
+	CreateTime    time.Time `json:"create_time" validate:"required"`
+}
+
+type UnreadCountResponse struct {
+	Count uint32 `json:"count"`
+}
+
+type NotificationPreferencesResponse struct {
+	Preferences map[string]bool `json:"preferences" validate:"required"`
+}
+
+type OrganizationResponse struct {
+	Id          string    `json:"id" validate:"required,uuid"`
+	Name        string    `json:"name" validate:"required"`
+	Description string    `json:"description"`
+	Role        string    `json:"role" validate:"required,oneof=owner admin editor viewer"`
+	CreateTime  time.Time `json:"create_time" validate:"required"`
+}
+
+type OrganizationMemberResponse struct {
+	UserId     string    `json:"user_id" validate:"required,uuid"`
+	Nickname   string    `json:"nickname" validate:"required"`
+	Handle     string    `json:"handle" validate:"required"`
+	Avatar     string    `json:"avatar" validate:"required"`
+	Role       string    `json:"role" validate:"required,oneof=owner admin editor viewer"`
+	CreateTime time.Time `json:"create_time" validate:"required"`
+}
+
+type CollectionResponse struct {
+	Id             string    `json:"id" validate:"required,uuid"`
+	Title          string    `json:"title" validate:"required"`
+	Description    string    `json:"description"`
+	IsPublic       bool      `json:"is_public"`
+	NumOfFollowers uint32    `json:"num_of_followers" validate:"min=0"`
+	NumOfItems     uint32    `json:"num_of_items" validate:"min=0"`
+	CreateTime     time.Time `json:"create_time" validate:"required"`
+	LastUpdateTime time.Time `json:"last_update_time" validate:"required"`
+	OwnerInfo
+	// ShareToken is only shown to the owner while the share link is on.
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+// CollectionItemResponse carries either a template or a widget, as told by
+// Type.
+type CollectionItemResponse struct {
+	Id       string            `json:"id" validate:"required,uuid"`
+	Type     string            `json:"type" validate:"required,oneof=template widget"`
+	Position int               `json:"position" validate:"min=0"`
+	AddTime  time.Time         `json:"add_time" validate:"required"`
+	Template *TemplateResponse `json:"template,omitempty"`
+	Widget   *WidgetResponse   `json:"widget,omitempty"`
+}
+
+type CollectionPageResponse struct {
+	CollectionResponse
+	IsFollowed bool                     `json:"is_followed"`
+	Items      []CollectionItemResponse `json:"items" validate:"required"`
+}
+
+type CollectionShareResponse struct {
+	ShareToken string `json:"share_token" validate:"required"`
+}
@@ -9,18 +9,85 @@ import (
 //go:embed templates/email.html
 var verifyEmailHTML string
 
+//go:embed templates/reset-password.html
+var resetPasswordHTML string
+
+//go:embed templates/email-change-notice.html
+var emailChangeNoticeHTML string
+
+//go:embed templates/magic-link.html
+var magicLinkHTML string
+
+//go:embed templates/account-erased.html
+var accountErasedHTML string
+
+//go:embed templates/account-locked.html
+var accountLockedHTML string
+
+//go:embed templates/new-device.html
+var newDeviceHTML string
+
 type VerifyEmailCode struct {
 	Code string
 }
 
-func BuildEmailLetter(code string) (string, error) {
-	tmpl, err := template.New("email").Parse(verifyEmailHTML)
+type ResetPasswordLink struct {
+	Link string
+	TTL  string
+}
+
+type EmailChangeNotice struct {
+	NewEmail string
+}
+
+type AccountLockedNotice struct {
+	IP  string
+	TTL string
+}
+
+type NewDeviceNotice struct {
+	Device string
+	IP     string
+	Time   string
+	Link   string
+}
+
+func render(name, html string, data any) (string, error) {
+	tmpl, err := template.New(name).Parse(html)
 	if err != nil {
 		return "", err
 	}
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, VerifyEmailCode{Code: code}); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
+
+func BuildEmailLetter(code string) (string, error) {
+	return render("email", verifyEmailHTML, VerifyEmailCode{Code: code})
+}
+
+func BuildResetPasswordLetter(link, ttl string) (string, error) {
+	return render("reset-password", resetPasswordHTML, ResetPasswordLink{Link: link, TTL: ttl})
+}
+
+func BuildEmailChangeNoticeLetter(newEmail string) (string, error) {
+	return render("email-change-notice", emailChangeNoticeHTML, EmailChangeNotice{NewEmail: newEmail})
+}
+
+func BuildAccountLockedLetter(ip, ttl string) (string, error) {
+	return render("account-locked", accountLockedHTML, AccountLockedNotice{IP: ip, TTL: ttl})
+}
+
+func BuildNewDeviceLetter(device, ip, time, link string) (string, error) {
+	return render("new-device", newDeviceHTML, NewDeviceNotice{Device: device, IP: ip, Time: time, Link: link})
+}
+
+func BuildMagicLinkLetter(link, ttl string) (string, error) {
+	return render("magic-link", magicLinkHTML, ResetPasswordLink{Link: link, TTL: ttl})
+}
+
+func BuildAccountErasedLetter() (string, error) {
+	return render("account-erased", accountErasedHTML, nil)
+}
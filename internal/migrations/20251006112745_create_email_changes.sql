@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS email_changes(
+    user_id UUID PRIMARY KEY NOT NULL,
+    new_email VARCHAR(256) NOT NULL UNIQUE,
+    code BYTEA NOT NULL,
+    attempts INTEGER NOT NULL CHECK (attempts >= 0) DEFAULT 5,
+    expired_time TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS email_changes
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_access_tokens(
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    name VARCHAR(80) NOT NULL,
+    token BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    create_time TIMESTAMP NOT NULL,
+    last_used_time TIMESTAMP,
+    expired_time TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON personal_access_tokens(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS personal_access_tokens
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS identities(
+    provider VARCHAR(80) NOT NULL,
+    provider_id TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    email VARCHAR(256) NOT NULL DEFAULT '',
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, provider_id),
+    UNIQUE (user_id, provider),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO identities (provider, provider_id, user_id, email, create_time)
+SELECT provider, provider_id, id, email, time_of_register FROM users
+WHERE provider <> 'local' AND provider_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS identities
+-- +goose StatementEnd
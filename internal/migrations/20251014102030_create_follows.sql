@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS follows(
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_id_idx ON follows(followee_id, create_time DESC);
+
+CREATE INDEX IF NOT EXISTS templates_owner_id_last_update_time_idx ON templates(owner_id, last_update_time DESC) WHERE is_public = TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS templates_owner_id_last_update_time_idx;
+
+DROP INDEX IF EXISTS follows_followee_id_idx;
+
+DROP TABLE IF EXISTS follows;
+-- +goose StatementEnd
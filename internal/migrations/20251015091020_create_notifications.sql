@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notifications(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    entity_id UUID,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_create_time_idx ON notifications(user_id, create_time DESC);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_unread_idx ON notifications(user_id) WHERE is_read = FALSE;
+
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    user_id UUID NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_preferences;
+
+DROP INDEX IF EXISTS notifications_user_id_unread_idx;
+
+DROP INDEX IF EXISTS notifications_user_id_create_time_idx;
+
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
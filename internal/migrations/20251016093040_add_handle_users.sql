@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS handle VARCHAR(30);
+
+UPDATE users SET handle = 'user-' || LEFT(REPLACE(id::text, '-', ''), 25) WHERE handle IS NULL;
+
+ALTER TABLE IF EXISTS users
+ALTER COLUMN handle SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_handle_key ON users(LOWER(handle));
+
+CREATE TABLE IF NOT EXISTS handle_history(
+    handle VARCHAR(30) NOT NULL,
+    user_id UUID NOT NULL,
+    change_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS handle_history_handle_key ON handle_history(LOWER(handle));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS handle_history_handle_key;
+
+DROP TABLE IF EXISTS handle_history;
+
+DROP INDEX IF EXISTS users_handle_key;
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS handle;
+-- +goose StatementEnd
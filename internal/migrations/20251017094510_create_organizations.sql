@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS organizations(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(80) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members(
+    org_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS organization_members_user_id_idx ON organization_members(user_id);
+
+ALTER TABLE IF EXISTS templates
+ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS templates_org_id_idx ON templates(org_id) WHERE org_id IS NOT NULL;
+
+ALTER TABLE IF EXISTS readmes
+ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS readmes_org_id_idx ON readmes(org_id) WHERE org_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS readmes_org_id_idx;
+
+ALTER TABLE IF EXISTS readmes
+DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS templates_org_id_idx;
+
+ALTER TABLE IF EXISTS templates
+DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS organization_members_user_id_idx;
+
+DROP TABLE IF EXISTS organization_members;
+
+DROP TABLE IF EXISTS organizations;
+-- +goose StatementEnd
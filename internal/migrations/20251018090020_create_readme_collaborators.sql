@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS readme_collaborators(
+    readme_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    access VARCHAR(16) NOT NULL CHECK (access IN ('viewer', 'editor')),
+    invited_by UUID,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (readme_id, user_id),
+    FOREIGN KEY (readme_id) REFERENCES readmes(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS readme_collaborators_user_id_idx ON readme_collaborators(user_id, create_time DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS readme_collaborators_user_id_idx;
+
+DROP TABLE IF EXISTS readme_collaborators;
+-- +goose StatementEnd
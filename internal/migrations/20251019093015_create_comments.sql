@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS comments(
+    id UUID PRIMARY KEY,
+    readme_id UUID,
+    template_id UUID,
+    parent_id UUID,
+    author_id UUID NOT NULL,
+    anchor_field VARCHAR(16) CHECK (anchor_field IN ('title', 'text', 'links', 'widgets', 'render_order')),
+    anchor_index INT CHECK (anchor_index >= 0),
+    body TEXT NOT NULL,
+    is_resolved BOOLEAN NOT NULL DEFAULT FALSE,
+    resolved_by UUID,
+    resolve_time TIMESTAMP,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((readme_id IS NULL) <> (template_id IS NULL)),
+    CHECK ((anchor_field IS NULL) = (anchor_index IS NULL)),
+    FOREIGN KEY (readme_id) REFERENCES readmes(id) ON DELETE CASCADE,
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS comments_readme_id_idx ON comments(readme_id, create_time) WHERE parent_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS comments_template_id_idx ON comments(template_id, create_time) WHERE parent_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments(parent_id, create_time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS comments_parent_id_idx;
+
+DROP INDEX IF EXISTS comments_template_id_idx;
+
+DROP INDEX IF EXISTS comments_readme_id_idx;
+
+DROP TABLE IF EXISTS comments;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE IF EXISTS templates
+ADD COLUMN IF NOT EXISTS rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS num_of_ratings INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS template_ratings(
+    template_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    stars SMALLINT NOT NULL CHECK (stars BETWEEN 1 AND 5),
+    review TEXT,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (template_id, user_id),
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS template_ratings_template_id_idx ON template_ratings(template_id, update_time DESC);
+
+CREATE INDEX IF NOT EXISTS template_ratings_user_id_idx ON template_ratings(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS template_ratings_user_id_idx;
+
+DROP INDEX IF EXISTS template_ratings_template_id_idx;
+
+DROP TABLE IF EXISTS template_ratings;
+
+ALTER TABLE IF EXISTS templates
+DROP COLUMN IF EXISTS num_of_ratings,
+DROP COLUMN IF EXISTS rating;
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS collections(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL,
+    title VARCHAR(80) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    share_token VARCHAR(64) UNIQUE,
+    num_of_followers INT NOT NULL DEFAULT 0,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS collections_owner_id_idx ON collections(owner_id, last_update_time DESC);
+
+CREATE TABLE IF NOT EXISTS collection_items(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    collection_id UUID NOT NULL,
+    template_id UUID,
+    widget_id UUID,
+    position INT NOT NULL,
+    add_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((template_id IS NULL) <> (widget_id IS NULL)),
+    UNIQUE (collection_id, template_id),
+    UNIQUE (collection_id, widget_id),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (widget_id) REFERENCES widgets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS collection_items_collection_id_idx ON collection_items(collection_id, position);
+
+CREATE TABLE IF NOT EXISTS collection_follows(
+    collection_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, user_id),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS collection_follows_user_id_idx ON collection_follows(user_id, create_time DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS collection_follows_user_id_idx;
+
+DROP TABLE IF EXISTS collection_follows;
+
+DROP INDEX IF EXISTS collection_items_collection_id_idx;
+
+DROP TABLE IF EXISTS collection_items;
+
+DROP INDEX IF EXISTS collections_owner_id_idx;
+
+DROP TABLE IF EXISTS collections;
+-- +goose StatementEnd
@@ -0,0 +1,91 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS template_reactions(
+    template_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    reaction VARCHAR(16) NOT NULL CHECK (reaction IN ('like', 'love', 'laugh', 'hooray', 'rocket', 'eyes')),
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (template_id, user_id, reaction),
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS template_reactions_user_id_idx ON template_reactions(user_id, reaction);
+
+CREATE TABLE IF NOT EXISTS widget_reactions(
+    widget_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    reaction VARCHAR(16) NOT NULL CHECK (reaction IN ('like', 'love', 'laugh', 'hooray', 'rocket', 'eyes')),
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (widget_id, user_id, reaction),
+    FOREIGN KEY (widget_id) REFERENCES widgets(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS widget_reactions_user_id_idx ON widget_reactions(user_id, reaction);
+
+INSERT INTO template_reactions (template_id, user_id, reaction)
+SELECT template_id, user_id, 'like' FROM favorite_templates
+ON CONFLICT DO NOTHING;
+
+INSERT INTO widget_reactions (widget_id, user_id, reaction)
+SELECT widget_id, user_id, 'like' FROM favorite_widgets
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE IF EXISTS templates
+ADD COLUMN IF NOT EXISTS reactions JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE IF EXISTS widgets
+ADD COLUMN IF NOT EXISTS reactions JSONB NOT NULL DEFAULT '{}';
+
+UPDATE templates t SET
+    reactions = COALESCE((SELECT jsonb_object_agg(r.reaction, r.cnt) FROM (SELECT reaction, COUNT(*) AS cnt FROM template_reactions WHERE template_id = t.id GROUP BY reaction) r), '{}'),
+    likes = (SELECT COUNT(*) FROM template_reactions WHERE template_id = t.id AND reaction = 'like');
+
+UPDATE widgets w SET
+    reactions = COALESCE((SELECT jsonb_object_agg(r.reaction, r.cnt) FROM (SELECT reaction, COUNT(*) AS cnt FROM widget_reactions WHERE widget_id = w.id GROUP BY reaction) r), '{}'),
+    likes = (SELECT COUNT(*) FROM widget_reactions WHERE widget_id = w.id AND reaction = 'like');
+
+DROP TABLE IF EXISTS favorite_templates;
+
+DROP TABLE IF EXISTS favorite_widgets;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS favorite_templates(
+    template_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (template_id, user_id),
+    FOREIGN KEY (template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS favorite_widgets(
+    widget_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (widget_id, user_id),
+    FOREIGN KEY (widget_id) REFERENCES widgets(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO favorite_templates (template_id, user_id)
+SELECT template_id, user_id FROM template_reactions WHERE reaction = 'like';
+
+INSERT INTO favorite_widgets (widget_id, user_id)
+SELECT widget_id, user_id FROM widget_reactions WHERE reaction = 'like';
+
+ALTER TABLE IF EXISTS widgets
+DROP COLUMN IF EXISTS reactions;
+
+ALTER TABLE IF EXISTS templates
+DROP COLUMN IF EXISTS reactions;
+
+DROP INDEX IF EXISTS widget_reactions_user_id_idx;
+
+DROP TABLE IF EXISTS widget_reactions;
+
+DROP INDEX IF EXISTS template_reactions_user_id_idx;
+
+DROP TABLE IF EXISTS template_reactions;
+-- +goose StatementEnd
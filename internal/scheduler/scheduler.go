@@ -5,33 +5,48 @@ import (
 	"fmt"
 	"readmeow/internal/config"
 	"readmeow/internal/domain/repositories"
+	"readmeow/internal/domain/services"
 	"readmeow/pkg/logger"
 
 	"github.com/robfig/cron/v3"
 )
 
 type Scheduler struct {
-	Cron             *cron.Cron
-	WidgetRepo       repositories.WidgetRepo
-	TemplateRepo     repositories.TemplateRepo
-	VerificationRepo repositories.VerificationRepo
-	ShedulerConfig   config.ShedulerConfig
-	SearchConfig     config.SearchConfig
-	Logger           *logger.Logger
+	Cron              *cron.Cron
+	WidgetRepo        repositories.WidgetRepo
+	TemplateRepo      repositories.TemplateRepo
+	CollectionRepo    repositories.CollectionRepo
+	VerificationRepo  repositories.VerificationRepo
+	SessionRepo       repositories.SessionRepo
+	PasswordResetRepo repositories.PasswordResetRepo
+	MagicLinkRepo     repositories.MagicLinkRepo
+	EmailChangeRepo   repositories.EmailChangeRepo
+	AccessTokenRepo   repositories.AccessTokenRepo
+	UserServ          services.UserServ
+	ShedulerConfig    config.ShedulerConfig
+	SearchConfig      config.SearchConfig
+	Logger            *logger.Logger
 }
 
-func NewScheduler(wr repositories.WidgetRepo, tr repositories.TemplateRepo, vr repositories.VerificationRepo, shcfg config.ShedulerConfig, scfg config.SearchConfig, l *logger.Logger) *Scheduler {
+func NewScheduler(wr repositories.WidgetRepo, tr repositories.TemplateRepo, clr repositories.CollectionRepo, vr repositories.VerificationRepo, sr repositories.SessionRepo, pr repositories.PasswordResetRepo, mr repositories.MagicLinkRepo, er repositories.EmailChangeRepo, atr repositories.AccessTokenRepo, us services.UserServ, shcfg config.ShedulerConfig, scfg config.SearchConfig, l *logger.Logger) *Scheduler {
 	cr := cron.New(cron.WithChain(
 		cron.SkipIfStillRunning(cron.DefaultLogger),
 	))
 	return &Scheduler{
-		Cron:             cr,
-		WidgetRepo:       wr,
-		TemplateRepo:     tr,
-		VerificationRepo: vr,
-		ShedulerConfig:   shcfg,
-		SearchConfig:     scfg,
-		Logger:           l,
+		Cron:              cr,
+		WidgetRepo:        wr,
+		TemplateRepo:      tr,
+		CollectionRepo:    clr,
+		VerificationRepo:  vr,
+		SessionRepo:       sr,
+		PasswordResetRepo: pr,
+		MagicLinkRepo:     mr,
+		EmailChangeRepo:   er,
+		AccessTokenRepo:   atr,
+		UserServ:          us,
+		ShedulerConfig:    shcfg,
+		SearchConfig:      scfg,
+		Logger:            l,
 	}
 }
 
@@ -50,6 +65,90 @@ func (s *Scheduler) Start() {
 	}); err != nil {
 		panic(fmt.Errorf("failed to start CleanExpiredVerifyCodes sheduler: %w", err))
 	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CleanCodesTime), func() {
+		op := "sheduler.CleanExpiredResetTokens"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CleanCodesTimeout)
+		defer cancel()
+		log.Info("cleaning expired reset tokens")
+		if err := s.PasswordResetRepo.DeleteExpired(ctx); err != nil {
+			log.Error("failed to delete expired reset tokens", logger.Err(err))
+		} else {
+			log.Info("expired reset tokens cleaned successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start CleanExpiredResetTokens sheduler: %w", err))
+	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CleanCodesTime), func() {
+		op := "sheduler.CleanExpiredMagicLinks"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CleanCodesTimeout)
+		defer cancel()
+		log.Info("cleaning expired magic links")
+		if err := s.MagicLinkRepo.DeleteExpired(ctx); err != nil {
+			log.Error("failed to delete expired magic links", logger.Err(err))
+		} else {
+			log.Info("expired magic links cleaned successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start CleanExpiredMagicLinks sheduler: %w", err))
+	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CleanCodesTime), func() {
+		op := "sheduler.CleanExpiredEmailChanges"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CleanCodesTimeout)
+		defer cancel()
+		log.Info("cleaning expired email changes")
+		if err := s.EmailChangeRepo.DeleteExpired(ctx); err != nil {
+			log.Error("failed to delete expired email changes", logger.Err(err))
+		} else {
+			log.Info("expired email changes cleaned successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start CleanExpiredEmailChanges sheduler: %w", err))
+	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CleanSessionsTime), func() {
+		op := "sheduler.CleanExpiredSessions"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CleanSessionsTimeout)
+		defer cancel()
+		log.Info("cleaning expired sessions")
+		if err := s.SessionRepo.DeleteExpired(ctx); err != nil {
+			log.Error("failed to delete expired sessions", logger.Err(err))
+		} else {
+			log.Info("expired sessions cleaned successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start CleanExpiredSessions sheduler: %w", err))
+	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CleanSessionsTime), func() {
+		op := "sheduler.CleanExpiredAccessTokens"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CleanSessionsTimeout)
+		defer cancel()
+		log.Info("cleaning expired access tokens")
+		if err := s.AccessTokenRepo.DeleteExpired(ctx); err != nil {
+			log.Error("failed to delete expired access tokens", logger.Err(err))
+		} else {
+			log.Info("expired access tokens cleaned successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start CleanExpiredAccessTokens sheduler: %w", err))
+	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.EraseUsersTime), func() {
+		op := "sheduler.EraseUsers"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.EraseUsersTimeout)
+		defer cancel()
+		log.Info("erasing users")
+		if err := s.UserServ.ErasePending(ctx); err != nil {
+			log.Error("failed to erase users", logger.Err(err))
+		} else {
+			log.Info("users erased successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start EraseUsers sheduler: %w", err))
+	}
 	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.WidgetBulkTime), func() {
 		op := "sheduler.BulkWidgetsData"
 		log := s.Logger.AddOp(op)
@@ -78,6 +177,20 @@ func (s *Scheduler) Start() {
 	}); err != nil {
 		panic(fmt.Errorf("failed to start BulkWidgetsTemplates: %w", err))
 	}
+	if _, err := s.Cron.AddFunc(fmt.Sprintf("@every %s", s.ShedulerConfig.CollectionBulkTime), func() {
+		op := "sheduler.BulkCollectionsData"
+		log := s.Logger.AddOp(op)
+		ctx, cancel := context.WithTimeout(context.Background(), s.ShedulerConfig.CollectionBulkTimeout)
+		defer cancel()
+		log.Info("bulking collections data")
+		if err := s.CollectionRepo.MustBulk(ctx, s.SearchConfig); err != nil {
+			log.Error("failed to bulk collections", logger.Err(err))
+		} else {
+			log.Info("collections data bulked successfully")
+		}
+	}); err != nil {
+		panic(fmt.Errorf("failed to start BulkCollectionsData sheduler: %w", err))
+	}
 	s.Cron.Start()
 }
 
@@ -14,6 +14,12 @@ var (
 	ErrZeroAttemptsBase         = errors.New("zero attempts")
 	ErrCodeIsExpiredBase        = errors.New("code is expired")
 	ErrIncorrectOldPasswordBase = errors.New("old password is incorrect")
+	ErrInvalidTokenBase         = errors.New("invalid token")
+	ErrInvalidCredentialsBase   = errors.New("invalid credentials")
+	ErrTooManyAttemptsBase      = errors.New("too many attempts")
+	ErrBreachedPasswordBase     = errors.New("password is too common")
+	ErrReservedHandleBase       = errors.New("handle is reserved")
+	ErrForbiddenBase            = errors.New("access denied")
 )
 
 type AppError struct {
@@ -67,3 +73,27 @@ func ErrCodeIsExpired(op string) AppError {
 func ErrIncorrectOldPassword(op string) AppError {
 	return NewAppError(op, fmt.Errorf("%w", ErrIncorrectOldPasswordBase))
 }
+
+func ErrInvalidToken(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrInvalidTokenBase))
+}
+
+func ErrInvalidCredentials(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrInvalidCredentialsBase))
+}
+
+func ErrTooManyAttempts(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrTooManyAttemptsBase))
+}
+
+func ErrBreachedPassword(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrBreachedPasswordBase))
+}
+
+func ErrReservedHandle(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrReservedHandleBase))
+}
+
+func ErrForbidden(op string) AppError {
+	return NewAppError(op, fmt.Errorf("%w", ErrForbiddenBase))
+}
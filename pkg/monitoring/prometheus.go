@@ -8,6 +8,7 @@ type PrometheusSetup struct {
 	HTTPRequestsTotal   *prometheus.CounterVec
 	HTTPErrorTotal      *prometheus.CounterVec
 	HTTPRequestDuration *prometheus.HistogramVec
+	FailedLoginsTotal   *prometheus.CounterVec
 }
 
 func NewPrometheusSetup() *PrometheusSetup {
@@ -39,9 +40,19 @@ func NewPrometheusSetup() *PrometheusSetup {
 		[]string{"path", "method", "status"},
 	)
 	prometheus.MustRegister(httpErrorTotal)
+	failedLoginsTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "readmeow",
+			Name:      "failed_logins_total",
+			Help:      "Total number of failed login attempts",
+		},
+		[]string{"reason"},
+	)
+	prometheus.MustRegister(failedLoginsTotal)
 	return &PrometheusSetup{
 		HTTPRequestsTotal:   httpRequestsTotal,
 		HTTPRequestDuration: httpRequestsDuration,
 		HTTPErrorTotal:      httpErrorTotal,
+		FailedLoginsTotal:   failedLoginsTotal,
 	}
 }
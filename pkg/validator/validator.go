@@ -3,11 +3,16 @@ package validator
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// handleRegexp allows latin letters, digits, dashes and underscores, with a
+// letter or digit at both ends.
+var handleRegexp = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9_-]*[a-zA-Z0-9])?$`)
+
 type Validator struct {
 	Validate *validator.Validate
 }
@@ -21,6 +26,9 @@ func NewValidator() *Validator {
 		}
 		return name
 	})
+	v.RegisterValidation("handle", func(fl validator.FieldLevel) bool {
+		return handleRegexp.MatchString(fl.Field().String())
+	})
 	return &Validator{
 		Validate: v,
 	}
@@ -49,6 +57,8 @@ func (v *Validator) translate(e validator.FieldError) string {
 		return fmt.Sprintf("must be at least %s characters", e.Param())
 	case "email":
 		return "must be a valid email"
+	case "handle":
+		return "must contain only latin letters, digits, '-' and '_' and start and end with a letter or digit"
 	case "oneof":
 		return fmt.Sprintf("must be one of [%s]", e.Param())
 	default: